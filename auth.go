@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"golang.org/x/oauth2"
+)
+
+const (
+	authBasic  = "basic"
+	authBearer = "bearer"
+	authOAuth  = "oauth"
+)
+
+// The credentials can be given in the environment instead of the config
+// file, the environment takes precedence.
+var jiraAuthEnv = []struct {
+	name  string
+	field func(j *Jira) *string
+}{
+	{"WORK_REPORTER_JIRA_USER", func(j *Jira) *string { return &j.User }},
+	{"WORK_REPORTER_JIRA_PASSWORD", func(j *Jira) *string { return &j.Password }},
+	{"WORK_REPORTER_JIRA_TOKEN", func(j *Jira) *string { return &j.Auth.Token }},
+	{"WORK_REPORTER_JIRA_CLIENT_SECRET", func(j *Jira) *string { return &j.Auth.ClientSecret }},
+	{"WORK_REPORTER_JIRA_REFRESH_TOKEN", func(j *Jira) *string { return &j.Auth.RefreshToken }},
+}
+
+func applyJiraAuthEnv(j *Jira) {
+	for _, env := range jiraAuthEnv {
+		if value, ok := os.LookupEnv(env.name); ok {
+			*env.field(j) = value
+		}
+	}
+}
+
+// jiraAuthType returns the configured auth type. Without one, a token
+// means bearer auth and basic auth is used otherwise.
+func jiraAuthType(cfg Jira) string {
+	if len(cfg.Auth.Type) > 0 {
+		return cfg.Auth.Type
+	}
+	if len(cfg.Auth.Token) > 0 {
+		return authBearer
+	}
+	return authBasic
+}
+
+// jiraAuthProblems returns what is missing for the configured auth type.
+func jiraAuthProblems(cfg Jira) []string {
+	var problems []string
+	switch auth := cfg.Auth; jiraAuthType(cfg) {
+	case authBasic:
+		if len(cfg.User) == 0 || len(cfg.Password) == 0 {
+			problems = append(problems, "jira user and password are required for basic auth")
+		}
+	case authBearer:
+		if len(auth.Token) == 0 {
+			problems = append(problems, "jira auth token is required for bearer auth")
+		}
+	case authOAuth:
+		if len(auth.ClientID) == 0 || len(auth.TokenURL) == 0 {
+			problems = append(problems, "jira auth client-id and token-url are required for oauth")
+		}
+		if len(auth.Token) == 0 && len(auth.RefreshToken) == 0 {
+			problems = append(problems, "jira auth token or refresh-token is required for oauth")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("jira auth type must be %q, %q or %q, got %q", authBasic, authBearer, authOAuth, auth.Type))
+	}
+	return problems
+}
+
+// initJiraClient creates jiraClient for the config and checks that Jira is
+// reachable with the credentials, so a wrong setup fails at startup with a
+// clear message instead of in the middle of a report.
+func initJiraClient(cfg *Config) error {
+	if len(cfg.Jira.Endpoint) == 0 {
+		return fmt.Errorf("jira endpoint is not configured")
+	}
+	if problems := jiraAuthProblems(cfg.Jira); len(problems) > 0 {
+		return fmt.Errorf("jira credentials are not configured: %s", strings.Join(problems, "; "))
+	}
+
+	httpClient, err := newJiraHTTPClient(cfg.Jira)
+	if err != nil {
+		return err
+	}
+	client, err := jira.NewClient(httpClient, cfg.Jira.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid jira endpoint %q: %v", cfg.Jira.Endpoint, err)
+	}
+
+	// myself is cheap and, unlike serverInfo, requires valid credentials.
+	req, err := client.NewRequest("GET", "rest/api/2/myself", nil)
+	if err != nil {
+		return err
+	}
+	ctx := globalCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req = req.WithContext(ctx)
+	if resp, err := client.Do(req, nil); err != nil {
+		err = wrapJiraError(req, resp, err)
+		return fmt.Errorf("can not reach jira at %s: %v", cfg.Jira.Endpoint, err)
+	}
+
+	if ttl := cfg.Jira.CacheTTL.Duration; ttl > 0 {
+		jiraClient = newCachingJira(client, ttl)
+	} else {
+		jiraClient = client
+	}
+	return nil
+}
+
+// newJiraHTTPClient creates the authenticated HTTP client for Jira:
+//   - basic uses the user and password, for Cloud the account email and an
+//     API token.
+//   - bearer sends a personal access token, which Data Center requires in
+//     some companies.
+//   - oauth uses an OAuth 2.0 access token, which is refreshed with the
+//     refresh token when it expires.
+func newJiraHTTPClient(cfg Jira) (*http.Client, error) {
+	switch jiraAuthType(cfg) {
+	case authBasic:
+		transport := jira.BasicAuthTransport{
+			Username: cfg.User,
+			Password: cfg.Password,
+		}
+		return transport.Client(), nil
+	case authBearer:
+		return &http.Client{Transport: &bearerAuthTransport{Token: cfg.Auth.Token}}, nil
+	case authOAuth:
+		oauthConfig := &oauth2.Config{
+			ClientID:     cfg.Auth.ClientID,
+			ClientSecret: cfg.Auth.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: cfg.Auth.TokenURL},
+		}
+		token := &oauth2.Token{
+			AccessToken:  cfg.Auth.Token,
+			RefreshToken: cfg.Auth.RefreshToken,
+		}
+		return oauthConfig.Client(context.Background(), token), nil
+	default:
+		return nil, fmt.Errorf("unknown jira auth type %q", cfg.Auth.Type)
+	}
+}
+
+// bearerAuthTransport adds the token as bearer authorization to requests.
+type bearerAuthTransport struct {
+	Token     string
+	Transport http.RoundTripper
+}
+
+func (t *bearerAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrip must not modify the request.
+	req2 := req.Clone(req.Context())
+	req2.Header.Set("Authorization", "Bearer "+t.Token)
+
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return transport.RoundTrip(req2)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewJiraHTTPClientBearer(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	client, err := newJiraHTTPClient(Jira{Auth: JiraAuth{Type: authBearer, Token: "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if auth != "Bearer secret" {
+		t.Fatalf("expect bearer token, got %q", auth)
+	}
+}
+
+func TestNewJiraHTTPClientOAuth(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	cfg := Jira{Auth: JiraAuth{Type: authOAuth, ClientID: "id", TokenURL: server.URL + "/token", Token: "access"}}
+	client, err := newJiraHTTPClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if auth != "Bearer access" {
+		t.Fatalf("expect the access token, got %q", auth)
+	}
+
+	cfg.Auth.Type = "kerberos"
+	if _, err = newJiraHTTPClient(cfg); err == nil {
+		t.Fatal("expect error for unknown auth type")
+	}
+}
+
+func TestJiraAuthType(t *testing.T) {
+	cases := []struct {
+		cfg    Jira
+		expect string
+	}{
+		{Jira{User: "user", Password: "password"}, authBasic},
+		{Jira{Auth: JiraAuth{Token: "token"}}, authBearer},
+		{Jira{Deployment: deploymentServer, User: "user", Password: "password"}, authBasic},
+		{Jira{User: "user", Auth: JiraAuth{Type: authOAuth}}, authOAuth},
+	}
+
+	for _, c := range cases {
+		if got := jiraAuthType(c.cfg); got != c.expect {
+			t.Errorf("%+v: expect %s, got %s", c.cfg, c.expect, got)
+		}
+	}
+}
+
+func TestApplyJiraAuthEnv(t *testing.T) {
+	os.Setenv("WORK_REPORTER_JIRA_TOKEN", "from-env")
+	defer os.Unsetenv("WORK_REPORTER_JIRA_TOKEN")
+
+	cfg := Jira{User: "user", Auth: JiraAuth{Token: "from-file"}}
+	applyJiraAuthEnv(&cfg)
+	if cfg.Auth.Token != "from-env" || cfg.User != "user" {
+		t.Fatalf("expect the token from the environment, got %+v", cfg)
+	}
+}
+
+func TestInitJiraClient(t *testing.T) {
+	oldClient := jiraClient
+	defer func() { jiraClient = oldClient }()
+
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/myself" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	cfg := &Config{Jira: Jira{Endpoint: server.URL, User: "user", Password: "secret"}}
+
+	status = http.StatusOK
+	jiraClient = nil
+	if err := initJiraClient(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if jiraClient == nil {
+		t.Fatal("expect jira client to be set")
+	}
+
+	status = http.StatusUnauthorized
+	jiraClient = nil
+	if err := initJiraClient(cfg); err == nil {
+		t.Fatal("expect error for rejected credentials")
+	}
+	if jiraClient != nil {
+		t.Fatal("expect no jira client after a failed check")
+	}
+
+	cfg.Jira.Password = ""
+	if err := initJiraClient(cfg); err == nil || !strings.Contains(err.Error(), "credentials") {
+		t.Fatalf("expect missing credentials error, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// isBlocked reports whether the issue is flagged in the configured jira
+// flagged-field, or in one of the jira blocked-statuses. Done issues are not
+// blocked anymore.
+func isBlocked(issue jira.Issue) bool {
+	if issue.Fields == nil || isDone(issue) {
+		return false
+	}
+	if field := config.Jira.FlaggedField; len(field) > 0 {
+		// Jira has a list of the flags, like [{"value": "Impediment"}], or
+		// null if the issue isn't flagged.
+		switch value := issue.Fields.Unknowns[field].(type) {
+		case []interface{}:
+			if len(value) > 0 {
+				return true
+			}
+		case string:
+			if len(value) > 0 {
+				return true
+			}
+		}
+	}
+	if status := issue.Fields.Status; status != nil {
+		for _, name := range config.Jira.BlockedStatuses {
+			if strings.EqualFold(name, status.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// blockedIssues returns the blocked issues, see isBlocked.
+func blockedIssues(issues []jira.Issue) []jira.Issue {
+	var blocked []jira.Issue
+	for _, issue := range issues {
+		if isBlocked(issue) {
+			blocked = append(blocked, issue)
+		}
+	}
+	return blocked
+}
+
+// renderBlocked renders the "Blocked" section with a line per issue, or
+// returns "" if there are none.
+func renderBlocked(issues []jira.Issue) string {
+	if len(issues) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Blocked (%d):\n", len(issues))
+	for _, issue := range issues {
+		fmt.Fprintf(&b, "- %s %s (%s)\n", issue.Key, issueSummary(issue), issueAssignee(issue))
+	}
+	return b.String()
+}
+
+func issueSummary(issue jira.Issue) string {
+	if issue.Fields == nil {
+		return ""
+	}
+	return issue.Fields.Summary
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestBlockedIssues(t *testing.T) {
+	config = &Config{}
+	config.Jira.FlaggedField = "customfield_10021"
+	config.Jira.BlockedStatuses = []string{"Waiting"}
+	defer func() { config = nil }()
+
+	flagged := newReportIssue("TEST-1", "alice", "In Progress", -1)
+	flagged.Fields.Summary = "Fix the build"
+	flagged.Fields.Unknowns["customfield_10021"] = []interface{}{map[string]interface{}{"value": "Impediment"}}
+	waiting := newReportIssue("TEST-2", "", "In Progress", -1)
+	waiting.Fields.Summary = "Upgrade the cluster"
+	waiting.Fields.Status.Name = "waiting"
+	unflagged := newReportIssue("TEST-3", "bob", "In Progress", -1)
+	unflagged.Fields.Unknowns["customfield_10021"] = nil
+	done := newReportIssue("TEST-4", "bob", "Done", -1)
+	done.Fields.Unknowns["customfield_10021"] = []interface{}{map[string]interface{}{"value": "Impediment"}}
+
+	blocked := blockedIssues([]jira.Issue{flagged, waiting, unflagged, done})
+	if len(blocked) != 2 || blocked[0].Key != "TEST-1" || blocked[1].Key != "TEST-2" {
+		t.Fatalf("expect the flagged and the waiting issue, got %v", blocked)
+	}
+
+	expect := "Blocked (2):\n- TEST-1 Fix the build (alice)\n- TEST-2 Upgrade the cluster (Unassigned)\n"
+	if out := renderBlocked(blocked); out != expect {
+		t.Fatalf("expect %q, got %q", expect, out)
+	}
+	if out := renderBlocked(nil); out != "" {
+		t.Fatalf("expect nothing without blocked issues, got %q", out)
+	}
+}
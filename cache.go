@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// cachingJira caches the responses of GET requests for ttl, a report reads
+// the same sprints and boards several times. Any other request may change
+// what was read, so it clears the cache.
+type cachingJira struct {
+	jiraAPI
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	body    json.RawMessage
+	expires time.Time
+}
+
+func newCachingJira(client jiraAPI, ttl time.Duration) *cachingJira {
+	return &cachingJira{
+		jiraAPI: client,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+func (c *cachingJira) Do(req *http.Request, v interface{}) (*jira.Response, error) {
+	if req.Method != "GET" {
+		c.mu.Lock()
+		c.entries = make(map[string]cacheEntry)
+		c.mu.Unlock()
+		return c.jiraAPI.Do(req, v)
+	}
+	if v == nil {
+		return c.jiraAPI.Do(req, v)
+	}
+
+	key := req.URL.String()
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		logger.Debug("jira cache hit", "url", key)
+		return cachedResponse(req), json.Unmarshal(entry.body, v)
+	}
+
+	var body json.RawMessage
+	resp, err := c.jiraAPI.Do(req, &body)
+	if err != nil {
+		return resp, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{body: body, expires: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return resp, json.Unmarshal(body, v)
+}
+
+func cachedResponse(req *http.Request) *jira.Response {
+	return &jira.Response{Response: &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+		Request:    req,
+	}}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestCachingJira(t *testing.T) {
+	f, cleanup := newInProcessFakeJira(t)
+	defer cleanup()
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST 1", State: "active"})
+
+	now := time.Now()
+	cache := newCachingJira(jiraClient, time.Minute)
+	cache.now = func() time.Time { return now }
+	jiraClient = cache
+
+	getSprint := func() jira.Sprint {
+		sprint, err := getSprintByID(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sprint
+	}
+
+	getSprint()
+	if sprint := getSprint(); sprint.Name != "TEST 1" {
+		t.Fatalf("expect the cached sprint, got %q", sprint.Name)
+	}
+	if n := f.countRequests("GET /rest/agile/1.0/sprint/1"); n != 1 {
+		t.Fatalf("expect 1 request within the ttl, got %d", n)
+	}
+
+	if _, err := updateSprintState(jira.Sprint{ID: 1, State: "active"}, "closed"); err != nil {
+		t.Fatal(err)
+	}
+	if sprint := getSprint(); sprint.State != "closed" {
+		t.Fatalf("expect the cache to be cleared by the update, got state %q", sprint.State)
+	}
+
+	now = now.Add(2 * time.Minute)
+	getSprint()
+	if n := f.countRequests("GET /rest/agile/1.0/sprint/1"); n != 3 {
+		t.Fatalf("expect the expired entry to be fetched again, got %d requests", n)
+	}
+}
+
+func TestCachingJiraSkipsErrors(t *testing.T) {
+	f, cleanup := newInProcessFakeJira(t)
+	defer cleanup()
+	jiraClient = newCachingJira(jiraClient, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if _, err := getSprintByID(1); err == nil {
+			t.Fatal("expect error for a missing sprint")
+		}
+	}
+	if n := f.countRequests("GET /rest/agile/1.0/sprint/1"); n != 2 {
+		t.Fatalf("expect failed reads not to be cached, got %d requests", n)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// assigneeCapacity returns the story points the user can take on in a
+// sprint, from report capacity by user name, key or display name, or else
+// report default-capacity. 0 means the capacity is unknown.
+func assigneeCapacity(user *jira.User) float64 {
+	if user == nil {
+		return 0
+	}
+	for _, id := range []string{user.Name, user.Key, user.DisplayName} {
+		if capacity, ok := config.Report.Capacity[id]; ok && len(id) > 0 {
+			return capacity
+		}
+	}
+	return config.Report.DefaultCapacity
+}
+
+// Overload returns how many story points the assignee has over capacity, 0
+// if none or without a capacity.
+func (s AssigneeSummary) Overload() float64 {
+	if s.Capacity <= 0 || s.StoryPoints <= s.Capacity {
+		return 0
+	}
+	return s.StoryPoints - s.Capacity
+}
+
+// overloadedAssignees returns the assignees over capacity, the most
+// overloaded first.
+func overloadedAssignees(report map[string]AssigneeSummary) []AssigneeSummary {
+	var overloaded []AssigneeSummary
+	for _, assignee := range sortedAssignees(report) {
+		if summary := report[assignee]; summary.Overload() > 0 {
+			overloaded = append(overloaded, summary)
+		}
+	}
+	sort.SliceStable(overloaded, func(i, j int) bool { return overloaded[i].Overload() > overloaded[j].Overload() })
+	return overloaded
+}
+
+// renderOverloaded lists the assignees over capacity on one line, like
+// "1 over capacity: alice 13/10 points (+3)", or returns "" if there are
+// none.
+func renderOverloaded(report map[string]AssigneeSummary) string {
+	overloaded := overloadedAssignees(report)
+	if len(overloaded) == 0 {
+		return ""
+	}
+	items := make([]string, 0, len(overloaded))
+	for _, summary := range overloaded {
+		items = append(items, fmt.Sprintf("%s %s/%s points (+%s)", summary.Assignee,
+			formatPoints(summary.StoryPoints), formatPoints(summary.Capacity), formatPoints(summary.Overload())))
+	}
+	return fmt.Sprintf("%d over capacity: %s", len(overloaded), strings.Join(items, ", "))
+}
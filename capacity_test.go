@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestAssigneeReportCapacity(t *testing.T) {
+	config = &Config{}
+	config.Jira.StoryPointField = "customfield_10001"
+	config.Report.Capacity = map[string]float64{"alice": 5, "Bob Smith": 10}
+	config.Report.DefaultCapacity = 2
+	defer func() { config = nil }()
+
+	bob := newReportIssue("TEST-3", "bob", "To Do", 8)
+	bob.Fields.Assignee.DisplayName = "Bob Smith"
+	report := buildAssigneeReport([]jira.Issue{
+		newReportIssue("TEST-1", "alice", "Done", 5),
+		newReportIssue("TEST-2", "alice", "To Do", 3),
+		bob,
+		newReportIssue("TEST-4", "carol", "To Do", 3),
+		newReportIssue("TEST-5", "", "To Do", 8),
+	})
+
+	if alice := report["alice"]; alice.Capacity != 5 || alice.Overload() != 3 {
+		t.Fatalf("expect alice 3 points over capacity, got %+v", alice)
+	}
+	if bob := report["Bob Smith"]; bob.Capacity != 10 || bob.Overload() != 0 {
+		t.Fatalf("expect bob under capacity by display name, got %+v", bob)
+	}
+	if carol := report["carol"]; carol.Capacity != 2 || carol.Overload() != 1 {
+		t.Fatalf("expect carol over the default capacity, got %+v", carol)
+	}
+	if unassigned := report[defaultUnassignedLabel]; unassigned.Capacity != 0 || unassigned.Overload() != 0 {
+		t.Fatalf("expect no capacity for the unassigned issues, got %+v", unassigned)
+	}
+
+	expect := "2 over capacity: alice 8/5 points (+3), carol 3/2 points (+1)"
+	if out := renderOverloaded(report); out != expect {
+		t.Fatalf("expect %q, got %q", expect, out)
+	}
+	if out := renderOverloaded(map[string]AssigneeSummary{}); out != "" {
+		t.Fatalf("expect nothing without overloads, got %q", out)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// CarryoverSummary tells how many issues of a sprint were carried over
+// from the previous sprint.
+type CarryoverSummary struct {
+	Sprint jira.Sprint
+	// PreviousSprint is nil if the sprint is the first one of the project.
+	PreviousSprint *jira.Sprint
+	Issues         int
+	Carryover      int
+
+	// The keys of the counted issues, so that the summaries of the sprints
+	// of several boards can be combined, see combineCarryover.
+	issueKeys     []string
+	carryoverKeys []string
+}
+
+// Percentage returns the carried over issues in percent of all issues.
+func (s CarryoverSummary) Percentage() float64 {
+	if s.Issues == 0 {
+		return 0
+	}
+	return float64(s.Carryover) * 100 / float64(s.Issues)
+}
+
+func (s CarryoverSummary) String() string {
+	return fmt.Sprintf("%d of %d issues carried over (%.0f%%)", s.Carryover, s.Issues, s.Percentage())
+}
+
+// sprintCarryover counts the issues of the sprint which were in the previous
+// sprint of the project too. An issue which was added to the sprint after
+// it started is not a carryover, even if it was in the previous sprint.
+func sprintCarryover(project string, boardID int, sprint jira.Sprint) (CarryoverSummary, error) {
+	summary := CarryoverSummary{Sprint: sprint}
+
+	issues, err := queryJiraIssuesWithOptions(NewJQL().Sprint(sprint.ID).String(), &jira.SearchOptions{
+		Fields: []string{"key"},
+	})
+	if err != nil {
+		return summary, err
+	}
+	summary.Issues = len(issues)
+	for _, issue := range issues {
+		summary.issueKeys = append(summary.issueKeys, issue.Key)
+	}
+
+	sprints, err := getSprints(boardID, jira.GetAllSprintsOptions{State: "closed"})
+	if err != nil {
+		return summary, err
+	}
+	summary.PreviousSprint = previousSprint(project, sprints, sprint)
+	if summary.PreviousSprint == nil {
+		return summary, nil
+	}
+
+	carried, err := queryJiraIssuesWithOptions(NewJQL().Sprint(sprint.ID).Sprint(summary.PreviousSprint.ID).String(),
+		&jira.SearchOptions{Fields: []string{"key"}, Expand: "changelog"})
+	if err != nil {
+		return summary, err
+	}
+	for _, issue := range carried {
+		if added, ok := addedToSprintAt(issue, sprint.ID); ok && sprint.StartDate != nil && added.After(*sprint.StartDate) {
+			continue
+		}
+		summary.Carryover++
+		summary.carryoverKeys = append(summary.carryoverKeys, issue.Key)
+	}
+
+	return summary, nil
+}
+
+// combineCarryover adds the issues of the other summaries to the first one,
+// an issue in the sprints of several boards is counted once.
+func combineCarryover(summaries []CarryoverSummary) CarryoverSummary {
+	if len(summaries) == 0 {
+		return CarryoverSummary{}
+	}
+	combined := summaries[0]
+	issues := make(map[string]bool)
+	carried := make(map[string]bool)
+	for _, s := range summaries {
+		for _, key := range s.issueKeys {
+			issues[key] = true
+		}
+		for _, key := range s.carryoverKeys {
+			carried[key] = true
+		}
+	}
+	combined.Issues, combined.Carryover = len(issues), len(carried)
+	return combined
+}
+
+// previousSprint returns the sprint of the project which ended last before
+// the sprint started.
+func previousSprint(project string, sprints []jira.Sprint, sprint jira.Sprint) *jira.Sprint {
+	if sprint.StartDate == nil {
+		return nil
+	}
+
+	var previous *jira.Sprint
+	for idx, s := range sprints {
+		if s.ID == sprint.ID || s.EndDate == nil || !sprintBelongsToProject(s, project) {
+			continue
+		}
+		if s.EndDate.After(*sprint.StartDate) {
+			continue
+		}
+		if previous == nil || s.EndDate.After(*previous.EndDate) {
+			previous = &sprints[idx]
+		}
+	}
+	return previous
+}
+
+// addedToSprintAt returns when the issue was last added to the sprint
+// according to its changelog, ok is false if the changelog doesn't say.
+func addedToSprintAt(issue jira.Issue, sprintID int) (added time.Time, ok bool) {
+	if issue.Changelog == nil {
+		return added, false
+	}
+
+	id := strconv.Itoa(sprintID)
+	for _, history := range issue.Changelog.Histories {
+		for _, item := range history.Items {
+			if item.Field != "Sprint" || !containsSprintID(item.To, id) || containsSprintID(item.From, id) {
+				continue
+			}
+			created, err := history.CreatedTime()
+			if err != nil {
+				continue
+			}
+			if !ok || created.After(added) {
+				added, ok = created, true
+			}
+		}
+	}
+	return added, ok
+}
+
+// containsSprintID checks the comma separated sprint IDs of a changelog item.
+func containsSprintID(ids interface{}, id string) bool {
+	s, _ := ids.(string)
+	for _, field := range strings.Split(s, ",") {
+		if strings.TrimSpace(field) == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestSprintCarryover(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST 1", State: "closed", StartDate: day(9, 28), EndDate: day(10, 5)})
+	f.addSprint(jira.Sprint{ID: 2, Name: "TEST 2", State: "active", StartDate: day(10, 5), EndDate: day(10, 12)})
+
+	sprintHistory := func(created, from, to string) *jira.Changelog {
+		return &jira.Changelog{Histories: []jira.ChangelogHistory{{
+			Created: created,
+			Items:   []jira.ChangelogItems{{Field: "Sprint", From: from, To: to}},
+		}}}
+	}
+	rolled := newFakeIssue(1, jira.StatusCategoryToDo)
+	rolled.Changelog = sprintHistory("2018-10-04T23:00:00.000+0000", "1", "1, 2")
+	midSprint := newFakeIssue(2, jira.StatusCategoryToDo)
+	midSprint.Changelog = sprintHistory("2018-10-08T10:00:00.000+0000", "1", "1, 2")
+
+	f.search = func(jql string) []jira.Issue {
+		if jql == "sprint = 2 AND sprint = 1" {
+			return []jira.Issue{rolled, midSprint}
+		}
+		return []jira.Issue{rolled, midSprint, newFakeIssue(3, jira.StatusCategoryToDo), newFakeIssue(4, jira.StatusCategoryToDo)}
+	}
+
+	summary, err := sprintCarryover("TEST", 1, *f.sprint(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.PreviousSprint == nil || summary.PreviousSprint.ID != 1 {
+		t.Fatalf("expect sprint 1 as previous, got %v", summary.PreviousSprint)
+	}
+	if summary.Issues != 4 || summary.Carryover != 1 || summary.Percentage() != 25 {
+		t.Fatalf("expect 1 of 4 issues carried over, got %s", summary)
+	}
+}
+
+func TestCombineCarryover(t *testing.T) {
+	sprint := jira.Sprint{ID: 2, Name: "TEST 2"}
+	// TEST-1 is in the sprints of both boards.
+	combined := combineCarryover([]CarryoverSummary{
+		{Sprint: sprint, Issues: 2, Carryover: 1, issueKeys: []string{"TEST-1", "TEST-2"}, carryoverKeys: []string{"TEST-1"}},
+		{Sprint: jira.Sprint{ID: 5, Name: "TEST web 2"}, Issues: 2, Carryover: 1, issueKeys: []string{"TEST-1", "TEST-3"}, carryoverKeys: []string{"TEST-1"}},
+	})
+	if combined.Sprint.ID != 2 {
+		t.Fatalf("expect the summary of sprint 2, got %d", combined.Sprint.ID)
+	}
+	if combined.Issues != 3 || combined.Carryover != 1 {
+		t.Fatalf("expect 1 of 3 issues carried over, got %s", combined)
+	}
+}
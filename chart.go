@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+// The velocity chart is a bar per sprint, small enough to sit next to the
+// report. There is no text, the numbers are in the velocity table.
+const (
+	chartBarWidth = 24
+	chartBarGap   = 8
+	chartHeight   = 80
+	chartPadding  = 8
+)
+
+var (
+	chartBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	chartBar        = color.RGBA{0x4a, 0x90, 0xd9, 0xff}
+	// The last sprint stands out.
+	chartLastBar = color.RGBA{0x2e, 0xb8, 0x86, 0xff}
+	chartAverage = color.RGBA{0x99, 0x99, 0x99, 0xff}
+)
+
+// renderVelocityChart renders the story points of the sprints as a PNG bar
+// chart, oldest first, with a line at the average.
+func renderVelocityChart(velocities []SprintVelocity) ([]byte, error) {
+	width := 2*chartPadding + len(velocities)*chartBarWidth + (len(velocities)-1)*chartBarGap
+	if len(velocities) == 0 {
+		width = 2 * chartPadding
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, chartHeight+2*chartPadding))
+	draw.Draw(img, img.Bounds(), &image.Uniform{chartBackground}, image.Point{}, draw.Src)
+
+	max := 0.0
+	for _, v := range velocities {
+		if v.Points > max {
+			max = v.Points
+		}
+	}
+	// The bottom of the chart, y grows downwards.
+	bottom := chartPadding + chartHeight
+	barHeight := func(points float64) int {
+		if max == 0 {
+			return 0
+		}
+		return int(points / max * chartHeight)
+	}
+
+	for i, v := range velocities {
+		x := chartPadding + i*(chartBarWidth+chartBarGap)
+		c := chartBar
+		if i == len(velocities)-1 {
+			c = chartLastBar
+		}
+		bar := image.Rect(x, bottom-barHeight(v.Points), x+chartBarWidth, bottom)
+		draw.Draw(img, bar, &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+
+	if average, _ := velocityTrend(velocities); average > 0 {
+		y := bottom - barHeight(average)
+		for x := chartPadding; x < width-chartPadding; x++ {
+			// Dashed, so it doesn't look like the top of a bar.
+			if x%6 < 4 {
+				img.Set(x, y, chartAverage)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
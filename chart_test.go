@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestRenderVelocityChart(t *testing.T) {
+	velocities := []SprintVelocity{
+		{Sprint: jira.Sprint{Name: "TEST 1"}, Points: 10},
+		{Sprint: jira.Sprint{Name: "TEST 2"}, Points: 5},
+		{Sprint: jira.Sprint{Name: "TEST 3"}, Points: 0},
+	}
+	data, err := renderVelocityChart(velocities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width := img.Bounds().Dx(); width != 2*chartPadding+3*chartBarWidth+2*chartBarGap {
+		t.Fatalf("expect a bar per sprint, got width %d", width)
+	}
+
+	bottom := chartPadding + chartHeight - 1
+	bar := func(i int) int { return chartPadding + i*(chartBarWidth+chartBarGap) + 1 }
+	for _, c := range []struct {
+		x, y  int
+		color color.Color
+	}{
+		// The highest bar goes to the top.
+		{bar(0), chartPadding, chartBar},
+		// Half as high.
+		{bar(1), chartPadding + chartHeight/2 + 1, chartBar},
+		{bar(1), chartPadding + chartHeight/2 - 1, chartBackground},
+		// No points, no bar.
+		{bar(2), bottom, chartBackground},
+	} {
+		r, g, b, _ := img.At(c.x, c.y).RGBA()
+		er, eg, eb, _ := c.color.RGBA()
+		if r != er || g != eg || b != eb {
+			t.Errorf("expect %v at %d,%d, got %v", c.color, c.x, c.y, img.At(c.x, c.y))
+		}
+	}
+
+	if _, err := renderVelocityChart(nil); err != nil {
+		t.Fatalf("expect an empty chart without sprints, got %v", err)
+	}
+}
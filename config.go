@@ -1,7 +1,15 @@
 package main
 
 import (
+	"fmt"
 	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
@@ -10,16 +18,138 @@ type Slack struct {
 	Token   string `toml:"token"`
 	Channel string `toml:"channel"`
 	User    string `toml:"user"`
+	Webhook string `toml:"webhook"`
+	// The sprint report is green with at least HealthyPercent of the issues
+	// done, yellow with at least AtRiskPercent and red otherwise.
+	HealthyPercent int `toml:"healthy-percent"`
+	AtRiskPercent  int `toml:"at-risk-percent"`
 }
 
 type Jira struct {
-	User     string `toml:"user"`
-	Password string `toml:"password"`
-	Endpoint string `toml:"endpoint"`
-	ServerID string `toml:"server-id"`
-	Server   string `toml:"server"`
-	Project  string `toml:"project"`
-	OnCall   string `toml:"oncall"`
+	User                 string   `toml:"user"`
+	Password             string   `toml:"password"`
+	Endpoint             string   `toml:"endpoint"`
+	ServerID             string   `toml:"server-id"`
+	Server               string   `toml:"server"`
+	Project              string   `toml:"project"`
+	Projects             []string `toml:"projects"`
+	OnCall               string   `toml:"oncall"`
+	BoardName            string   `toml:"board-name"`
+	BoardID              int      `toml:"board-id"`
+	BoardType            string   `toml:"board-type"`
+	Retry                Retry    `toml:"retry"`
+	SprintDuration       Duration `toml:"sprint-duration"`
+	SprintNameTemplate   string   `toml:"sprint-name-template"`
+	SprintGoalTemplate   string   `toml:"sprint-goal-template"`
+	SprintNameIgnoreCase bool     `toml:"sprint-name-ignore-case"`
+	SprintMatch          string   `toml:"sprint-match"`
+	Timezone             string   `toml:"timezone"`
+	SprintStartTimeOfDay string   `toml:"sprint-start-time-of-day"`
+	MoveBatchSize        int      `toml:"move-batch-size"`
+	SprintPageSize       int      `toml:"sprint-page-size"`
+	LabelCarryover       bool     `toml:"label-carryover"`
+	CarryoverLabel       string   `toml:"carryover-label"`
+	StoryPointField      string   `toml:"story-point-field"`
+	EpicLinkField        string   `toml:"epic-link-field"`
+	DoneStatuses         []string `toml:"done-statuses"`
+	FlaggedField         string   `toml:"flagged-field"`
+	BlockedStatuses      []string `toml:"blocked-statuses"`
+	DisableBoardCache    bool     `toml:"disable-board-cache"`
+	PruneAfter           Duration `toml:"prune-after"`
+	Deployment           string   `toml:"deployment"`
+	RequestsPerSecond    float64  `toml:"requests-per-second"`
+	Workers              int      `toml:"workers"`
+	CacheTTL             Duration `toml:"cache-ttl"`
+	Auth                 JiraAuth `toml:"auth"`
+	// Environments are other Jira instances, like a sandbox, selected with
+	// --env.
+	Environments map[string]JiraEnvironment `toml:"environments"`
+}
+
+// JiraEnvironment is a named Jira instance. Its empty options keep the ones
+// of the jira section.
+type JiraEnvironment struct {
+	Endpoint   string   `toml:"endpoint"`
+	ServerID   string   `toml:"server-id"`
+	Server     string   `toml:"server"`
+	Deployment string   `toml:"deployment"`
+	User       string   `toml:"user"`
+	Password   string   `toml:"password"`
+	Auth       JiraAuth `toml:"auth"`
+}
+
+type JiraAuth struct {
+	Type         string `toml:"type"`
+	Token        string `toml:"token"`
+	ClientID     string `toml:"client-id"`
+	ClientSecret string `toml:"client-secret"`
+	TokenURL     string `toml:"token-url"`
+	RefreshToken string `toml:"refresh-token"`
+}
+
+type Retry struct {
+	MaxRetries int      `toml:"max-retries"`
+	BaseDelay  Duration `toml:"base-delay"`
+}
+
+// Duration is a time.Duration which can be decoded from a string like "1s".
+// A day count like "14d" is accepted too.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(text []byte) error {
+	s := string(text)
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return fmt.Errorf("invalid day count %q", s)
+		}
+		d.Duration = time.Duration(days) * 24 * time.Hour
+		return nil
+	}
+
+	var err error
+	d.Duration, err = time.ParseDuration(s)
+	return err
+}
+
+type Report struct {
+	UnassignedLabel  string   `toml:"unassigned-label"`
+	Components       []string `toml:"components"`
+	Labels           []string `toml:"labels"`
+	GroupByComponent bool     `toml:"group-by-component"`
+	GroupByEpic      bool     `toml:"group-by-epic"`
+	DateFormat       string   `toml:"date-format"`
+	// ExcludeTypes are the issue types left out of the report, like Epic.
+	ExcludeTypes []string `toml:"exclude-types"`
+	// IncludeSubtasks counts the subtasks in the report totals.
+	IncludeSubtasks bool `toml:"include-subtasks"`
+	// UnestimatedSubtasks lists the subtasks without story points too.
+	UnestimatedSubtasks bool `toml:"unestimated-subtasks"`
+	// HistoryDir stores the reports to show the changes since the last one,
+	// HistoryKeep of them are kept for each project.
+	HistoryDir  string `toml:"history-dir"`
+	HistoryKeep int    `toml:"history-keep"`
+	// BoardIDs are the boards whose active sprints are reported as one.
+	BoardIDs []int `toml:"board-ids"`
+	// Capacity has the story points of each assignee per sprint, by user
+	// name or display name, DefaultCapacity is for the others.
+	Capacity        map[string]float64 `toml:"capacity"`
+	DefaultCapacity float64            `toml:"default-capacity"`
+	// StaleAfter is how long an issue in progress goes without an update
+	// before it is reported as stale.
+	StaleAfter Duration `toml:"stale-after"`
+	// LoggedTime reports the hours logged in the worklogs "alongside" the
+	// story points or "instead" of them.
+	LoggedTime string `toml:"logged-time"`
+	// VelocityChart attaches a chart of the velocity of this many last
+	// sprints to the Slack and email reports, none if it is 0.
+	VelocityChart int `toml:"velocity-chart"`
+	// Notify are the channels the sprint report is sent to, every
+	// configured one by default.
+	Notify []string `toml:"notify"`
 }
 
 type Member struct {
@@ -47,24 +177,318 @@ type Confluence struct {
 	WeeklyPath string `toml:"weekly-path"`
 }
 
+// MSTeams posts the sprint report to a Microsoft Teams channel.
+type MSTeams struct {
+	Webhook string `toml:"webhook"`
+}
+
+type Email struct {
+	Host     string   `toml:"host"`
+	Port     int      `toml:"port"`
+	User     string   `toml:"user"`
+	Password string   `toml:"password"`
+	From     string   `toml:"from"`
+	To       []string `toml:"to"`
+}
+
 type Config struct {
 	Slack      Slack      `toml:"slack"`
 	Jira       Jira       `toml:"jira"`
 	Confluence Confluence `toml:"confluence"`
 	Github     Github     `toml:"github"`
 	Teams      []Team     `toml:"teams"`
+	Report     Report     `toml:"report"`
+	Email      Email      `toml:"email"`
+	MSTeams    MSTeams    `toml:"ms-teams"`
+	Schedule   Schedule   `toml:"schedule"`
+	Metrics    Metrics    `toml:"metrics"`
+	StateFile  string     `toml:"state-file"`
+	DryRun     bool       `toml:"dry-run"`
+	// Timeout bounds a whole run, zero means no limit.
+	Timeout Duration `toml:"timeout"`
+}
+
+type Schedule struct {
+	Cron     string   `toml:"cron"`
+	Timezone string   `toml:"timezone"`
+	Commands []string `toml:"commands"`
+}
+
+type Metrics struct {
+	Listen string `toml:"listen"`
+}
+
+func newDefaultConfig() *Config {
+	c := new(Config)
+	c.Jira.Retry = Retry{
+		MaxRetries: 3,
+		BaseDelay:  Duration{time.Second},
+	}
+	c.Jira.MoveBatchSize = maxMoveBatchSize
+	c.Jira.Workers = defaultWorkers
+	return c
 }
 
-// NewConfigFromFile creates the configuration from file
+// NewConfigFromFile creates the configuration from file, overridden by the
+// environment.
 func NewConfigFromFile(path string) (*Config, error) {
+	c, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = applyConfigEnv(c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func readConfigFile(path string) (*Config, error) {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	c := new(Config)
+	c := newDefaultConfig()
 	if err = toml.Unmarshal(data, c); err != nil {
 		return nil, err
 	}
+	return c, nil
+}
 
+// loadConfig layers the config file, the Jira environment of --env, the
+// environment and the --set flags, see configEnvPrefix. The file is optional
+// unless required, so the config can come from the environment only.
+func loadConfig(path string, required bool, jiraEnvironment string, sets []string) (*Config, error) {
+	c, err := readConfigFile(path)
+	if os.IsNotExist(err) && !required {
+		c, err = newDefaultConfig(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(jiraEnvironment) > 0 {
+		if err = c.useJiraEnvironment(jiraEnvironment); err != nil {
+			return nil, err
+		}
+	}
+	if err = applyConfigEnv(c); err != nil {
+		return nil, err
+	}
+	if err = applyConfigSets(c, sets); err != nil {
+		return nil, err
+	}
 	return c, nil
 }
+
+// jiraProjects returns the projects to work on, projects takes precedence
+// over the single project.
+func (c *Config) jiraProjects() []string {
+	if len(c.Jira.Projects) > 0 {
+		return c.Jira.Projects
+	}
+	return []string{c.Jira.Project}
+}
+
+// useJiraEnvironment points the jira section at the named environment. The
+// credentials of the jira section are only kept for the same endpoint, they
+// must not be sent to another Jira.
+func (c *Config) useJiraEnvironment(name string) error {
+	env, ok := c.Jira.Environments[name]
+	if !ok {
+		names := make([]string, 0, len(c.Jira.Environments))
+		for name := range c.Jira.Environments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown jira environment %q, the configured ones are %s", name, strings.Join(names, ", "))
+	}
+
+	if len(env.Endpoint) > 0 && env.Endpoint != c.Jira.Endpoint {
+		c.Jira.User, c.Jira.Password, c.Jira.Auth = "", "", JiraAuth{}
+	}
+	for _, field := range []struct {
+		value   string
+		setting *string
+	}{
+		{env.Endpoint, &c.Jira.Endpoint},
+		{env.ServerID, &c.Jira.ServerID},
+		{env.Server, &c.Jira.Server},
+		{env.Deployment, &c.Jira.Deployment},
+		{env.User, &c.Jira.User},
+		{env.Password, &c.Jira.Password},
+	} {
+		if len(field.value) > 0 {
+			*field.setting = field.value
+		}
+	}
+	if len(env.Auth.Type) > 0 {
+		c.Jira.Auth = env.Auth
+	}
+	return nil
+}
+
+var storyPointFieldPattern = regexp.MustCompile(`^customfield_\d+$`)
+
+// Validate checks the config before any API call is made, it returns all
+// the problems found in one error.
+func (c *Config) Validate() error {
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if len(c.Jira.Endpoint) == 0 {
+		addProblem("jira endpoint is required")
+	}
+	if len(c.Jira.Project) == 0 && len(c.Jira.Projects) == 0 {
+		// An empty project matches the sprints of every project.
+		addProblem("jira project or projects is required")
+	}
+	for _, project := range c.Jira.Projects {
+		if len(project) == 0 {
+			addProblem("jira projects must not contain an empty project")
+		}
+	}
+	problems = append(problems, jiraAuthProblems(c.Jira)...)
+
+	for name, env := range c.Jira.Environments {
+		if len(env.Endpoint) == 0 {
+			addProblem("jira environment %s needs an endpoint", name)
+		}
+	}
+	if c.Jira.BoardID < 0 {
+		addProblem("jira board-id must be positive, got %d", c.Jira.BoardID)
+	}
+	if c.Jira.BoardID > 0 && len(c.Jira.Projects) > 1 {
+		// The board belongs to one project.
+		addProblem("jira board-id can't be used with several projects")
+	}
+	switch c.Jira.BoardType {
+	case "", boardTypeScrum, boardTypeKanban, boardTypeSimple:
+	default:
+		addProblem("jira board-type must be %q, %q or %q, got %q", boardTypeScrum, boardTypeKanban, boardTypeSimple, c.Jira.BoardType)
+	}
+
+	switch c.Jira.Deployment {
+	case "", deploymentCloud, deploymentServer:
+	default:
+		addProblem("jira deployment must be %q or %q, got %q", deploymentCloud, deploymentServer, c.Jira.Deployment)
+	}
+
+	if len(c.Jira.Timezone) > 0 {
+		if _, err := time.LoadLocation(c.Jira.Timezone); err != nil {
+			addProblem("jira timezone %q is invalid: %v", c.Jira.Timezone, err)
+		}
+	}
+	if tod := c.Jira.SprintStartTimeOfDay; len(tod) > 0 {
+		if _, err := time.Parse("15:04", tod); err != nil {
+			addProblem("jira sprint-start-time-of-day %q is invalid, expect HH:MM", tod)
+		}
+	}
+	if field := c.Jira.StoryPointField; len(field) > 0 && !storyPointFieldPattern.MatchString(field) {
+		addProblem("jira story-point-field %q is invalid, expect customfield_<id>", field)
+	}
+	if field := c.Jira.EpicLinkField; len(field) > 0 && !storyPointFieldPattern.MatchString(field) {
+		addProblem("jira epic-link-field %q is invalid, expect customfield_<id>", field)
+	}
+	if field := c.Jira.FlaggedField; len(field) > 0 && !storyPointFieldPattern.MatchString(field) {
+		addProblem("jira flagged-field %q is invalid, expect customfield_<id>", field)
+	}
+	// Jira Server/Data Center may be configured with another limit.
+	if c.Jira.MoveBatchSize < 1 {
+		addProblem("jira move-batch-size must be at least 1, got %d", c.Jira.MoveBatchSize)
+	} else if c.Jira.MoveBatchSize > maxMoveBatchSize && c.Jira.Deployment != deploymentServer {
+		addProblem("jira move-batch-size must be between 1 and %d on Jira Cloud, got %d", maxMoveBatchSize, c.Jira.MoveBatchSize)
+	}
+	if c.Jira.SprintPageSize < 0 || c.Jira.SprintPageSize > maxSprintPageSize {
+		addProblem("jira sprint-page-size must be between 0 (default) and %d, got %d", maxSprintPageSize, c.Jira.SprintPageSize)
+	}
+	if c.Jira.RequestsPerSecond < 0 {
+		addProblem("jira requests-per-second must not be negative, got %v", c.Jira.RequestsPerSecond)
+	}
+	if c.Jira.CacheTTL.Duration < 0 {
+		addProblem("jira cache-ttl must not be negative, got %v", c.Jira.CacheTTL.Duration)
+	}
+	if strings.ContainsAny(c.Jira.CarryoverLabel, " \t") {
+		addProblem("jira carryover-label %q must not contain spaces", c.Jira.CarryoverLabel)
+	}
+	if c.Slack.HealthyPercent < 0 || c.Slack.HealthyPercent > 100 {
+		addProblem("slack healthy-percent must be between 0 and 100, got %d", c.Slack.HealthyPercent)
+	}
+	if c.Slack.AtRiskPercent < 0 || c.Slack.AtRiskPercent > 100 {
+		addProblem("slack at-risk-percent must be between 0 and 100, got %d", c.Slack.AtRiskPercent)
+	}
+	if c.Slack.HealthyPercent > 0 && c.Slack.AtRiskPercent > c.Slack.HealthyPercent {
+		addProblem("slack at-risk-percent %d must not be above healthy-percent %d", c.Slack.AtRiskPercent, c.Slack.HealthyPercent)
+	}
+	for _, name := range c.Report.Notify {
+		if !isNotifierName(name) {
+			addProblem("report notify %q must be one of %s", name, strings.Join(notifierNames, ", "))
+		} else if !notifierConfigured(c, name) {
+			addProblem("report notify %q is not configured", name)
+		}
+	}
+	if t := c.Report.LoggedTime; len(t) > 0 && t != loggedTimeAlongside && t != loggedTimeInstead {
+		addProblem("report logged-time must be %q or %q, got %q", loggedTimeAlongside, loggedTimeInstead, t)
+	}
+	if c.Report.VelocityChart < 0 {
+		addProblem("report velocity-chart must not be negative, got %d", c.Report.VelocityChart)
+	}
+	if c.Report.StaleAfter.Duration < 0 {
+		addProblem("report stale-after must not be negative, got %v", c.Report.StaleAfter.Duration)
+	}
+	if c.Report.DefaultCapacity < 0 {
+		addProblem("report default-capacity must not be negative, got %v", c.Report.DefaultCapacity)
+	}
+	for user, capacity := range c.Report.Capacity {
+		if capacity < 0 {
+			addProblem("report capacity of %s must not be negative, got %v", user, capacity)
+		}
+	}
+	if c.Report.HistoryKeep < 0 {
+		addProblem("report history-keep must not be negative, got %d", c.Report.HistoryKeep)
+	}
+	for _, id := range c.Report.BoardIDs {
+		if id <= 0 {
+			addProblem("report board-ids must be positive, got %d", id)
+		}
+	}
+	if c.Jira.Workers < 0 {
+		addProblem("jira workers must not be negative, got %d", c.Jira.Workers)
+	}
+	if len(c.Schedule.Cron) > 0 {
+		loc := time.Local
+		if len(c.Schedule.Timezone) > 0 {
+			var err error
+			if loc, err = time.LoadLocation(c.Schedule.Timezone); err != nil {
+				addProblem("schedule timezone %q is invalid: %v", c.Schedule.Timezone, err)
+			}
+		}
+		if _, err := parseCron(c.Schedule.Cron, loc); err != nil {
+			addProblem("schedule cron is invalid: %v", err)
+		}
+		if len(c.Schedule.Commands) == 0 {
+			addProblem("schedule commands are required with a cron")
+		}
+	}
+	if len(c.Jira.SprintNameTemplate) > 0 {
+		if _, err := template.New("sprint").Parse(c.Jira.SprintNameTemplate); err != nil {
+			addProblem("jira sprint-name-template is invalid: %v", err)
+		}
+	}
+	if len(c.Jira.SprintMatch) > 0 {
+		if _, err := regexp.Compile(c.Jira.SprintMatch); err != nil {
+			addProblem("jira sprint-match is invalid: %v", err)
+		}
+	}
+	if len(c.Jira.SprintGoalTemplate) > 0 {
+		if _, err := template.New("sprint").Parse(c.Jira.SprintGoalTemplate); err != nil {
+			addProblem("jira sprint-goal-template is invalid: %v", err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
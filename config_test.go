@@ -0,0 +1,251 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestDurationUnmarshalText(t *testing.T) {
+	cases := []struct {
+		text   string
+		expect time.Duration
+	}{
+		{"1s", time.Second},
+		{"336h", 14 * 24 * time.Hour},
+		{"14d", 14 * 24 * time.Hour},
+	}
+
+	for _, c := range cases {
+		var d Duration
+		if err := d.UnmarshalText([]byte(c.text)); err != nil {
+			t.Fatalf("%s: %v", c.text, err)
+		}
+		if d.Duration != c.expect {
+			t.Fatalf("%s: expect %s, got %s", c.text, c.expect, d.Duration)
+		}
+	}
+
+	var d Duration
+	if err := d.UnmarshalText([]byte("twod")); err == nil {
+		t.Fatal("expect error for invalid day count")
+	}
+}
+
+func TestNewConfigFromFileValidation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "work-reporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.toml")
+	for _, c := range []struct {
+		text  string
+		valid bool
+	}{
+		{"[jira]\n", true},
+		{"[jira]\nmove-batch-size = 10\n", true},
+		{"[jira]\nmove-batch-size = 0\n", false},
+		{"[jira]\nmove-batch-size = 51\n", false},
+		{"[jira]\ndeployment = \"server\"\nmove-batch-size = 100\n", true},
+		{"[jira]\ndeployment = \"server\"\nmove-batch-size = 0\n", false},
+		{"[jira]\ndeployment = \"server\"\n", true},
+		{"[jira]\ndeployment = \"onprem\"\n", false},
+	} {
+		if err = ioutil.WriteFile(path, []byte(c.text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := NewConfigFromFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Jira.Endpoint, cfg.Jira.Project, cfg.Jira.User, cfg.Jira.Password = "http://jira", "TEST", "user", "password"
+		err = cfg.Validate()
+		if (err == nil) != c.valid {
+			t.Errorf("%q: expect valid %v, got %v", c.text, c.valid, err)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	c := &Config{}
+	c.Jira.MoveBatchSize = maxMoveBatchSize
+	c.Jira.Timezone = "Mars/Olympus"
+	c.Jira.StoryPointField = "Story Points"
+	c.Jira.BoardType = "board"
+	c.Jira.BoardID = -1
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("expect invalid config")
+	}
+	for _, problem := range []string{"endpoint", "project", "password", "timezone", "story-point-field", "board-type", "board-id"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("expect %s problem in %q", problem, err)
+		}
+	}
+
+	c.Jira.Endpoint, c.Jira.Project, c.Jira.Auth.Token = "http://jira", "TEST", "token"
+	c.Jira.BoardID = 42
+	c.Jira.Deployment = deploymentServer
+	c.Jira.Timezone = "Asia/Shanghai"
+	c.Jira.StoryPointField = "customfield_10016"
+	c.Jira.BoardType = boardTypeKanban
+	if err = c.Validate(); err != nil {
+		t.Fatalf("expect valid config, got %v", err)
+	}
+}
+
+func TestConfigJiraProjects(t *testing.T) {
+	c := &Config{}
+	c.Jira.Project = "TEST"
+	if projects := c.jiraProjects(); len(projects) != 1 || projects[0] != "TEST" {
+		t.Fatalf("expect the single project, got %v", projects)
+	}
+
+	c.Jira.Projects = []string{"API", "WEB"}
+	if projects := c.jiraProjects(); len(projects) != 2 || projects[1] != "WEB" {
+		t.Fatalf("expect the project list, got %v", projects)
+	}
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "work-reporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.toml")
+	text := "[jira]\nproject = \"FILE\"\nendpoint = \"http://jira\"\nworkers = 2\n"
+	if err = ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("WORK_REPORTER_JIRA_PROJECT", "ENV")
+	t.Setenv("WORK_REPORTER_JIRA_SPRINT_DURATION", "14d")
+	t.Setenv("WORK_REPORTER_JIRA_PASSWORD", "secret")
+	t.Setenv("WORK_REPORTER_REPORT_BOARD_IDS", "1, 2")
+	t.Setenv("WORK_REPORTER_GITHUB_REPOS", "tikv/tikv,tikv/pd")
+
+	cfg, err := loadConfig(path, true, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Jira.Project != "ENV" || cfg.Jira.Endpoint != "http://jira" || cfg.Jira.Workers != 2 {
+		t.Fatalf("expect the environment over the file, got %+v", cfg.Jira)
+	}
+	if cfg.Jira.SprintDuration.Duration != 14*24*time.Hour || cfg.Jira.Password != "secret" {
+		t.Fatalf("expect the duration and the password from the environment, got %+v", cfg.Jira)
+	}
+	if len(cfg.Report.BoardIDs) != 2 || cfg.Report.BoardIDs[1] != 2 || len(cfg.Github.Repos) != 2 {
+		t.Fatalf("expect the lists from the environment, got %v, %v", cfg.Report.BoardIDs, cfg.Github.Repos)
+	}
+
+	cfg, err = loadConfig(path, true, "", []string{"jira.project=FLAG", "jira.disable-board-cache=true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Jira.Project != "FLAG" || !cfg.Jira.DisableBoardCache {
+		t.Fatalf("expect the flags over the environment, got %+v", cfg.Jira)
+	}
+
+	for _, set := range []string{"jira.password=secret", "jira.nope=1", "jira.workers=many", "jira.project"} {
+		if _, err = loadConfig(path, true, "", []string{set}); err == nil {
+			t.Errorf("%s: expect error", set)
+		}
+	}
+
+	missing := filepath.Join(dir, "missing.toml")
+	if _, err = loadConfig(missing, true, "", nil); err == nil {
+		t.Fatal("expect error for a missing required config file")
+	}
+	if cfg, err = loadConfig(missing, false, "", nil); err != nil || cfg.Jira.Project != "ENV" || cfg.Jira.MoveBatchSize != maxMoveBatchSize {
+		t.Fatalf("expect the defaults and the environment without config file, got %v", err)
+	}
+}
+
+func TestLoadConfigJiraEnvironment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "work-reporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.toml")
+	text := `
+[jira]
+endpoint = "https://jira.example.com/"
+user = "prod"
+project = "FILE"
+
+[jira.environments.sandbox]
+endpoint = "https://example-sandbox.atlassian.net/"
+user = "sandbox"
+`
+	if err = ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("WORK_REPORTER_JIRA_PASSWORD", "env-secret")
+
+	// The environment and --set override the Jira environment.
+	cfg, err := loadConfig(path, true, "sandbox", []string{"jira.user=flag"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Jira.Endpoint != "https://example-sandbox.atlassian.net/" || cfg.Jira.User != "flag" || cfg.Jira.Password != "env-secret" {
+		t.Fatalf("expect the sandbox endpoint under the environment and --set, got %+v", cfg.Jira)
+	}
+	if _, err = loadConfig(path, true, "staging", nil); err == nil {
+		t.Fatal("expect an error for an unknown environment")
+	}
+}
+
+func TestUseJiraEnvironment(t *testing.T) {
+	text := `
+[jira]
+endpoint = "https://jira.example.com/"
+user = "prod"
+password = "prod-secret"
+project = "TEST"
+
+[jira.environments.sandbox]
+endpoint = "https://example-sandbox.atlassian.net/"
+password = "sandbox-secret"
+`
+	cfg := newDefaultConfig()
+	if err := toml.Unmarshal([]byte(text), cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.useJiraEnvironment("staging"); err == nil || !strings.Contains(err.Error(), "sandbox") {
+		t.Fatalf("expect an unknown environment error listing sandbox, got %v", err)
+	}
+	if err := cfg.useJiraEnvironment("sandbox"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Jira.Endpoint != "https://example-sandbox.atlassian.net/" || cfg.Jira.Password != "sandbox-secret" || cfg.Jira.Project != "TEST" {
+		t.Fatalf("expect the sandbox endpoint and password over the jira ones, got %+v", cfg.Jira)
+	}
+	// The prod user isn't sent to the sandbox, the sandbox needs its own.
+	if cfg.Jira.User != "" {
+		t.Fatalf("expect no user from the jira section, got %q", cfg.Jira.User)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "jira user and password are required") {
+		t.Fatalf("expect the missing sandbox user, got %v", err)
+	}
+	cfg.Jira.User = "sandbox"
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Jira.Environments["empty"] = JiraEnvironment{}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "environment empty") {
+		t.Fatalf("expect an error for the environment without endpoint, got %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The config is layered: the config file, then the environment, then the
+// --set flags, each overriding the one before. Every option can be set in
+// the environment, named after its path in the file: jira.sprint-duration
+// is WORK_REPORTER_JIRA_SPRINT_DURATION. Lists are comma separated.
+const configEnvPrefix = "WORK_REPORTER_"
+
+// secretConfigFields can't be set with --set, flags show up in the process
+// list and the shell history. They belong in the environment, e.g. from a
+// Kubernetes secret, or in the config file.
+var secretConfigFields = map[string]bool{
+	"slack.token":             true,
+	"slack.webhook":           true,
+	"ms-teams.webhook":        true,
+	"jira.password":           true,
+	"jira.auth.token":         true,
+	"jira.auth.client-secret": true,
+	"jira.auth.refresh-token": true,
+	"confluence.password":     true,
+	"github.token":            true,
+	"email.password":          true,
+}
+
+// configField is an option of the config, path is its dotted path in the
+// config file.
+type configField struct {
+	path  string
+	value reflect.Value
+}
+
+// configFields returns the options of c which can be set from a string,
+// sorted by path. Lists of tables, like the teams, are only read from the
+// file.
+func configFields(c *Config) []configField {
+	var fields []configField
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			tag := strings.Split(t.Field(i).Tag.Get("toml"), ",")[0]
+			if tag == "-" {
+				continue
+			}
+			if len(tag) == 0 {
+				// Like the TOML decoder, which matches the untagged
+				// fields by name ignoring the case.
+				tag = strings.ToLower(t.Field(i).Name)
+			}
+			path := prefix + tag
+			field := v.Field(i)
+			if _, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+				fields = append(fields, configField{path: path, value: field})
+				continue
+			}
+			switch field.Kind() {
+			case reflect.Struct:
+				walk(path+".", field)
+			case reflect.String, reflect.Bool, reflect.Int, reflect.Float64:
+				fields = append(fields, configField{path: path, value: field})
+			case reflect.Slice:
+				if kind := field.Type().Elem().Kind(); kind == reflect.String || kind == reflect.Int {
+					fields = append(fields, configField{path: path, value: field})
+				}
+			}
+		}
+	}
+	walk("", reflect.ValueOf(c).Elem())
+	sort.Slice(fields, func(i, j int) bool { return fields[i].path < fields[j].path })
+	return fields
+}
+
+// configEnvName returns the environment variable of the option path.
+func configEnvName(path string) string {
+	return configEnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(path))
+}
+
+// applyConfigEnv overrides the options of c set in the environment.
+func applyConfigEnv(c *Config) error {
+	for _, field := range configFields(c) {
+		name := configEnvName(field.path)
+		if value, ok := os.LookupEnv(name); ok {
+			if err := setConfigField(field, value); err != nil {
+				return fmt.Errorf("invalid %s: %v", name, err)
+			}
+		}
+	}
+	// The older names of the credentials.
+	applyJiraAuthEnv(&c.Jira)
+	return nil
+}
+
+// applyConfigSets overrides the options of c with the path=value pairs of
+// the --set flags.
+func applyConfigSets(c *Config, sets []string) error {
+	fields := make(map[string]configField)
+	for _, field := range configFields(c) {
+		fields[field.path] = field
+	}
+	for _, set := range sets {
+		i := strings.Index(set, "=")
+		if i < 0 {
+			return fmt.Errorf("invalid --set %q, expect option=value", set)
+		}
+		path, value := strings.TrimSpace(set[:i]), set[i+1:]
+		if secretConfigFields[path] {
+			return fmt.Errorf("%s can't be set with --set, use %s or the config file", path, configEnvName(path))
+		}
+		field, ok := fields[path]
+		if !ok {
+			return fmt.Errorf("unknown config option %q", path)
+		}
+		if err := setConfigField(field, value); err != nil {
+			return fmt.Errorf("invalid --set %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+func setConfigField(field configField, s string) error {
+	v := field.value
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("expect true or false, got %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("expect an integer, got %q", s)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("expect a number, got %q", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				items = append(items, item)
+			}
+		}
+		list := reflect.MakeSlice(v.Type(), 0, len(items))
+		for _, item := range items {
+			if v.Type().Elem().Kind() == reflect.String {
+				list = reflect.Append(list, reflect.ValueOf(item))
+				continue
+			}
+			n, err := strconv.Atoi(item)
+			if err != nil {
+				return fmt.Errorf("expect integers, got %q", item)
+			}
+			list = reflect.Append(list, reflect.ValueOf(n))
+		}
+		v.Set(list)
+	default:
+		return fmt.Errorf("unsupported option type %s", v.Type())
+	}
+	return nil
+}
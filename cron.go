@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed standard 5 field cron expression: minute, hour,
+// day of month, month and day of week. Every field is a bit set of the
+// values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// Like cron, if both day fields are restricted a day matching either
+	// of them matches.
+	domRestricted, dowRestricted bool
+	loc                          *time.Location
+}
+
+var cronDescriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// parseCron parses the expression, the times it matches are in loc. Each
+// field is *, a value, a range a-b or a list of them, optionally with a
+// step like */15. Sunday is 0 or 7 in the day of week.
+func parseCron(expr string, loc *time.Location) (*cronSchedule, error) {
+	if descriptor, ok := cronDescriptors[strings.TrimSpace(expr)]; ok {
+		expr = descriptor
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q, expect 5 fields", expr)
+	}
+
+	s := &cronSchedule{loc: loc}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid cron minute %q: %v", fields[0], err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid cron hour %q: %v", fields[1], err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid cron day of month %q: %v", fields[2], err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid cron month %q: %v", fields[3], err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid cron day of week %q: %v", fields[4], err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domRestricted = !strings.HasPrefix(fields[2], "*")
+	s.dowRestricted = !strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", part[i+1:])
+			}
+			part = part[:i]
+		}
+
+		from, to := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", bounds[0])
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value %q", bounds[1])
+				}
+			} else if step > 1 {
+				// 5/15 means from 5 to the end every 15.
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return 0, fmt.Errorf("%d-%d is out of range %d-%d", from, to, min, max)
+		}
+
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first matching time after t.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc).Truncate(time.Minute).Add(time.Minute)
+
+	// Every year has all the days, so a match is found within 5 years
+	// unless the expression can't match, like February 30.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+	// A Wednesday.
+	from := time.Date(2018, time.January, 3, 10, 30, 0, 0, loc)
+
+	cases := []struct {
+		expr   string
+		expect time.Time
+	}{
+		{"*/15 * * * *", time.Date(2018, time.January, 3, 10, 45, 0, 0, loc)},
+		{"0 9 * * 1", time.Date(2018, time.January, 8, 9, 0, 0, 0, loc)},
+		{"30 10 * * 3", time.Date(2018, time.January, 10, 10, 30, 0, 0, loc)},
+		{"0 9 * * 1-5", time.Date(2018, time.January, 4, 9, 0, 0, 0, loc)},
+		{"0 0 1 * *", time.Date(2018, time.February, 1, 0, 0, 0, 0, loc)},
+		{"0 0 * * 7", time.Date(2018, time.January, 7, 0, 0, 0, 0, loc)},
+		// Either day field matches when both are restricted.
+		{"0 0 15 * 5", time.Date(2018, time.January, 5, 0, 0, 0, 0, loc)},
+		{"@daily", time.Date(2018, time.January, 4, 0, 0, 0, 0, loc)},
+	}
+	for _, c := range cases {
+		schedule, err := parseCron(c.expr, loc)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+		if next := schedule.Next(from); !next.Equal(c.expect) {
+			t.Errorf("%s: expect %v, got %v", c.expr, c.expect, next)
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := parseCron(expr, time.UTC); err == nil {
+			t.Errorf("expect error for %q", expr)
+		}
+	}
+
+	schedule, err := parseCron("0 0 30 2 *", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := schedule.Next(time.Now()); !next.IsZero() {
+		t.Fatalf("expect February 30 to never match, got %v", next)
+	}
+}
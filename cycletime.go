@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// CycleTime is how long an issue took from in progress to done.
+type CycleTime struct {
+	Key      string
+	Started  time.Time
+	Done     time.Time
+	Duration time.Duration
+}
+
+// sprintCycleTimes returns the cycle times of the issues completed in the
+// sprint and their average, see cycleTimes.
+func sprintCycleTimes(sprintID int) ([]CycleTime, time.Duration, error) {
+	jql := NewJQL().Sprint(sprintID).Done(config.Jira.DoneStatuses...).String()
+	issues, err := queryJiraIssuesWithOptions(jql, &jira.SearchOptions{Expand: "changelog"})
+	if err != nil {
+		return nil, 0, err
+	}
+	return cycleTimes(issues)
+}
+
+// cycleTimes returns the cycle times of the completed issues and their
+// average, from the status changes in their changelog, so the issues must
+// be queried with expand=changelog. An issue which went to done without
+// being in progress has no cycle time and is left out.
+func cycleTimes(issues []jira.Issue) ([]CycleTime, time.Duration, error) {
+	categories, err := getStatusCategories()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var times []CycleTime
+	var total time.Duration
+	for _, issue := range issues {
+		if cycleTime, ok := issueCycleTime(issue, categories); ok {
+			times = append(times, cycleTime)
+			total += cycleTime.Duration
+		}
+	}
+	if len(times) == 0 {
+		return nil, 0, nil
+	}
+	return times, total / time.Duration(len(times)), nil
+}
+
+// statusChange is a change of the status of an issue in its changelog.
+type statusChange struct {
+	at       time.Time
+	status   string
+	category string
+}
+
+// issueCycleTime measures from the first move to an in progress status to
+// the last move to done, ok is false if the issue never got to both. The
+// categories have the status category key by status ID.
+func issueCycleTime(issue jira.Issue, categories map[string]string) (CycleTime, bool) {
+	if issue.Changelog == nil {
+		return CycleTime{}, false
+	}
+
+	var changes []statusChange
+	for _, history := range issue.Changelog.Histories {
+		at, err := history.CreatedTime()
+		if err != nil {
+			continue
+		}
+		for _, item := range history.Items {
+			if item.Field != "status" {
+				continue
+			}
+			to, _ := item.To.(string)
+			changes = append(changes, statusChange{at: at, status: item.ToString, category: categories[to]})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].at.Before(changes[j].at) })
+
+	var started, done time.Time
+	for _, change := range changes {
+		switch {
+		case isDoneStatusChange(change):
+			if !started.IsZero() {
+				done = change.at
+			}
+		case change.category == jira.StatusCategoryInProgress:
+			if started.IsZero() {
+				started = change.at
+			}
+		}
+	}
+	if started.IsZero() || done.IsZero() {
+		return CycleTime{}, false
+	}
+	return CycleTime{Key: issue.Key, Started: started, Done: done, Duration: done.Sub(started)}, true
+}
+
+func isDoneStatusChange(change statusChange) bool {
+	if change.category == jira.StatusCategoryComplete {
+		return true
+	}
+	for _, name := range config.Jira.DoneStatuses {
+		if strings.EqualFold(name, change.status) {
+			return true
+		}
+	}
+	return false
+}
+
+// getStatusCategories returns the status category key of every status by
+// status ID, the changelog only has the IDs and names of the statuses.
+func getStatusCategories() (map[string]string, error) {
+	req, err := newJiraRequest(globalCtx, "GET", "rest/api/2/status", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []jira.Status
+	if _, err = doWithRetry(req, &statuses); err != nil {
+		return nil, err
+	}
+	categories := make(map[string]string, len(statuses))
+	for _, status := range statuses {
+		categories[status.ID] = status.StatusCategory.Key
+	}
+	return categories, nil
+}
+
+// formatCycleTime formats the duration in days and hours, like "2d 4h".
+func formatCycleTime(d time.Duration) string {
+	hours := int(d.Round(time.Hour) / time.Hour)
+	if hours < 24 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dd %dh", hours/24, hours%24)
+}
+
+// renderCycleTimes lists the cycle times, the longest first, after their
+// average.
+func renderCycleTimes(times []CycleTime, average time.Duration) string {
+	if len(times) == 0 {
+		return "no completed issues with a cycle time\n"
+	}
+	sorted := append([]CycleTime(nil), times...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Duration > sorted[j].Duration })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "average cycle time %s over %d issues\n", formatCycleTime(average), len(sorted))
+	for _, t := range sorted {
+		fmt.Fprintf(&b, "- %s %s\n", t.Key, formatCycleTime(t.Duration))
+	}
+	return b.String()
+}
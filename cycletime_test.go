@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// newChangelogIssue creates an issue moved to the status IDs at the times,
+// given as pairs like "3", "2018-10-05T10:00:00.000+0000".
+func newChangelogIssue(key string, changes ...string) jira.Issue {
+	changelog := &jira.Changelog{}
+	for i := 0; i+1 < len(changes); i += 2 {
+		changelog.Histories = append(changelog.Histories, jira.ChangelogHistory{
+			Created: changes[i+1],
+			Items:   []jira.ChangelogItems{{Field: "status", To: changes[i]}},
+		})
+	}
+	return jira.Issue{Key: key, Changelog: changelog}
+}
+
+func TestCycleTimes(t *testing.T) {
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/status" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		writeJSON(t, w, []jira.Status{
+			{ID: "1", Name: "To Do", StatusCategory: jira.StatusCategory{Key: jira.StatusCategoryToDo}},
+			{ID: "3", Name: "In Progress", StatusCategory: jira.StatusCategory{Key: jira.StatusCategoryInProgress}},
+			{ID: "4", Name: "In Review", StatusCategory: jira.StatusCategory{Key: jira.StatusCategoryInProgress}},
+			{ID: "10", Name: "Done", StatusCategory: jira.StatusCategory{Key: jira.StatusCategoryComplete}},
+		})
+	})()
+
+	issues := []jira.Issue{
+		newChangelogIssue("TEST-1",
+			"3", "2018-10-05T10:00:00.000+0000",
+			"4", "2018-10-06T10:00:00.000+0000",
+			"10", "2018-10-07T10:00:00.000+0000"),
+		// Reopened and done again, the cycle ends at the last done.
+		newChangelogIssue("TEST-2",
+			"3", "2018-10-05T10:00:00.000+0000",
+			"10", "2018-10-06T10:00:00.000+0000",
+			"3", "2018-10-07T10:00:00.000+0000",
+			"10", "2018-10-09T10:00:00.000+0000"),
+		// Done straight from To Do, left out.
+		newChangelogIssue("TEST-3", "10", "2018-10-05T10:00:00.000+0000"),
+		{Key: "TEST-4"},
+	}
+
+	times, average, err := cycleTimes(issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 2 || times[0].Duration != 48*time.Hour || times[1].Duration != 96*time.Hour {
+		t.Fatalf("expect the cycle times of TEST-1 and TEST-2, got %+v", times)
+	}
+	if average != 72*time.Hour {
+		t.Fatalf("expect an average of 3 days, got %v", average)
+	}
+
+	expect := "average cycle time 3d 0h over 2 issues\n- TEST-2 4d 0h\n- TEST-1 2d 0h\n"
+	if out := renderCycleTimes(times, average); out != expect {
+		t.Fatalf("expect %q, got %q", expect, out)
+	}
+}
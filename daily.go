@@ -22,7 +22,7 @@ func newDailyCommand() *cobra.Command {
 }
 
 func runDailyCommandFunc(cmd *cobra.Command, args []string) {
-	now := time.Now().UTC()
+	now := nowFunc().UTC()
 	start := now.Add(-24 * time.Hour).Format(githubUTCDateFormat)
 
 	var buf bytes.Buffer
@@ -44,15 +44,17 @@ func runDailyCommandFunc(cmd *cobra.Command, args []string) {
 	// formatGitHubIssuesForSlackOutput(&buf, issues)
 	// buf.WriteString("\n")
 
-	oncallIssues := queryJiraIssues("project = ONCALL AND created >= \"-1d\"")
+	oncallIssues, err := queryJiraIssues("project = ONCALL AND created >= \"-1d\"")
+	perror(err)
 	formatSectionForSlackOutput(&buf, "New OnCalls", "New on calls in last 24 hours")
 	formatJiraIssuesForSlackOutput(&buf, oncallIssues)
 	buf.WriteString("\n")
 
-	oncallIssues = queryJiraIssues("project = ONCALL AND priority = Highest AND resolution = Unresolved AND updated <= \"-3d\"")
+	oncallIssues, err = queryJiraIssues("project = ONCALL AND priority = Highest AND resolution = Unresolved AND updated <= \"-3d\"")
+	perror(err)
 	formatSectionForSlackOutput(&buf, "Inactive OnCalls", "Highest priority on calls inactive >= 3 days")
 	formatJiraIssuesForSlackOutput(&buf, oncallIssues)
 	buf.WriteString("\n")
 
-	sendToSlack(buf.String())
+	runStatus.record("post daily report to slack", sendToSlack("%s", buf.String()))
 }
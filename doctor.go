@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the Jira Connection, Boards and Active Sprints Without Changing Anything",
+		Run:   runDoctorCommandFunc,
+	}
+	return m
+}
+
+func runDoctorCommandFunc(cmd *cobra.Command, args []string) {
+	// Nothing must be written, even if a check went wrong.
+	config.DryRun = true
+
+	// Reaching and authenticating to Jira was checked by initJiraClient.
+	fmt.Printf("[ok] jira %s is reachable and the credentials are accepted\n", config.Jira.Endpoint)
+	perror(runDoctorChecks(os.Stdout, config.jiraProjects()))
+}
+
+// runDoctorChecks resolves the board and the active sprint of every project
+// and searches its issues, it prints a line for every check and returns an
+// error if any failed.
+func runDoctorChecks(w io.Writer, projects []string) error {
+	failed := 0
+	check := func(err error, format string, args ...interface{}) bool {
+		msg := fmt.Sprintf(format, args...)
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "[FAIL] %s: %v\n", msg, err)
+			return false
+		}
+		fmt.Fprintf(w, "[ok] %s\n", msg)
+		return true
+	}
+
+	for _, project := range projects {
+		boardID, err := getSprintBoardID(project)
+		if err != nil {
+			check(err, "[%s] board", project)
+			continue
+		}
+		check(nil, "[%s] board %d", project, boardID)
+		sprint, err := getActiveSprint(project, boardID)
+		if err != nil {
+			check(err, "[%s] active sprint", project)
+			continue
+		}
+		check(nil, "[%s] active sprint %s (%d)", project, sprint.Name, sprint.ID)
+		n, err := countJiraIssues(NewJQL().Sprint(sprint.ID).String())
+		check(err, "[%s] %d issues in the active sprint", project, n)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d doctor checks failed", failed)
+	}
+	return nil
+}
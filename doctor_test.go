@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestRunDoctorChecks(t *testing.T) {
+	f, cleanup := newFakeJira(t)
+	defer cleanup()
+	f.boards = []jira.Board{{ID: 3, Name: "TEST board", Type: "scrum"}}
+	f.addSprint(jira.Sprint{ID: 7, Name: "TEST 7", State: "active"})
+	f.addIssue(7, newReportIssue("TEST-1", "alice", "To Do", 1))
+
+	var out bytes.Buffer
+	if err := runDoctorChecks(&out, []string{"TEST"}); err != nil {
+		t.Fatalf("expect all checks to pass, got %v:\n%s", err, out.String())
+	}
+	expect := "[ok] [TEST] board 3\n[ok] [TEST] active sprint TEST 7 (7)\n[ok] [TEST] 1 issues in the active sprint\n"
+	if out.String() != expect {
+		t.Fatalf("expect\n%s\ngot\n%s", expect, out.String())
+	}
+
+	out.Reset()
+	if err := runDoctorChecks(&out, []string{"API"}); err == nil {
+		t.Fatal("expect the check to fail without an active sprint")
+	}
+	if !strings.Contains(out.String(), "[FAIL] [API] active sprint") {
+		t.Fatalf("expect the failed check, got\n%s", out.String())
+	}
+
+	out.Reset()
+	f.boards = nil
+	if err := runDoctorChecks(&out, []string{"NONE"}); err == nil || err.Error() != "1 doctor checks failed" {
+		t.Fatalf("expect the board check to fail, got %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "[FAIL] [NONE] board: ") {
+		t.Fatalf("expect the failed board lookup without a board ID, got\n%s", out.String())
+	}
+	for _, r := range f.requests {
+		if !strings.HasPrefix(r, "GET ") {
+			t.Fatalf("expect only reads, got %s", r)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultSMTPPort = 587
+
+// renderHTML renders the assignee report as an HTML table, for mail
+// clients which don't render Markdown.
+func renderHTML(report map[string]AssigneeSummary) string {
+	return renderGroupedHTML(report, "Assignee")
+}
+
+// renderGroupedHTML is renderHTML with title as the group column.
+func renderGroupedHTML(report map[string]AssigneeSummary, title string) string {
+	header, rows, totals := groupedReportTable(report, title)
+
+	var buf bytes.Buffer
+	writeRow := func(tag string, cells []string) {
+		buf.WriteString("<tr>")
+		for i, cell := range cells {
+			align := "right"
+			if i == 0 {
+				align = "left"
+			}
+			fmt.Fprintf(&buf, `<%s style="border: 1px solid #ccc; padding: 4px 8px; text-align: %s">%s</%s>`,
+				tag, align, html.EscapeString(cell), tag)
+		}
+		buf.WriteString("</tr>\n")
+	}
+
+	buf.WriteString(`<table style="border-collapse: collapse">` + "\n")
+	writeRow("th", header)
+	for _, row := range rows {
+		writeRow("td", row)
+	}
+	writeRow("th", totals)
+	buf.WriteString("</table>\n")
+
+	return buf.String()
+}
+
+// emailAttachment is a file attached to a mail.
+type emailAttachment struct {
+	name        string
+	contentType string
+	content     []byte
+}
+
+// buildEmail builds a multipart/alternative MIME message with the plain
+// text and the HTML version of the body. With attachments the message is
+// multipart/mixed, with the alternative body first.
+func buildEmail(from string, to []string, subject, text, htmlBody string, attachments ...emailAttachment) ([]byte, error) {
+	var body bytes.Buffer
+	contentType, err := writeAlternativeBody(&body, text, htmlBody)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) > 0 {
+		var mixed bytes.Buffer
+		if contentType, err = writeMixedBody(&mixed, contentType, body.Bytes(), attachments); err != nil {
+			return nil, err
+		}
+		body = mixed
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: %s\r\n\r\n", contentType)
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// writeAlternativeBody writes the plain text and the HTML parts to body and
+// returns its content type.
+func writeAlternativeBody(body *bytes.Buffer, text, htmlBody string) (string, error) {
+	writer := multipart.NewWriter(body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", htmlBody},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return "", err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err = qp.Write([]byte(part.content)); err != nil {
+			return "", err
+		}
+		if err = qp.Close(); err != nil {
+			return "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return "multipart/alternative; boundary=" + writer.Boundary(), nil
+}
+
+// writeMixedBody writes the alternative body and the attachments to mixed
+// and returns its content type.
+func writeMixedBody(mixed *bytes.Buffer, contentType string, body []byte, attachments []emailAttachment) (string, error) {
+	writer := multipart.NewWriter(mixed)
+	w, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+	if err != nil {
+		return "", err
+	}
+	if _, err = w.Write(body); err != nil {
+		return "", err
+	}
+
+	for _, attachment := range attachments {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.name})},
+		})
+		if err != nil {
+			return "", err
+		}
+		if err = writeBase64Lines(w, attachment.content); err != nil {
+			return "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return "multipart/mixed; boundary=" + writer.Boundary(), nil
+}
+
+// writeBase64Lines writes content base64 encoded in lines of 76 characters,
+// the most MIME allows.
+func writeBase64Lines(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 0 {
+		n := 76
+		if n > len(encoded) {
+			n = len(encoded)
+		}
+		if _, err := io.WriteString(w, encoded[:n]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
+
+// sendEmail mails the report to the configured recipients. In dry-run the
+// MIME message is printed instead.
+func sendEmail(subject, text, htmlBody string, attachments ...emailAttachment) error {
+	cfg := config.Email
+	if len(cfg.To) == 0 {
+		return fmt.Errorf("no email recipients")
+	}
+
+	msg, err := buildEmail(cfg.From, cfg.To, subject, text, htmlBody, attachments...)
+	if err != nil {
+		return err
+	}
+
+	if config.DryRun {
+		fmt.Printf("[dry-run] send email to %s:\n%s\n", strings.Join(cfg.To, ", "), msg)
+		return nil
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	var auth smtp.Auth
+	if len(cfg.User) > 0 {
+		auth = smtp.PlainAuth("", cfg.User, cfg.Password, cfg.Host)
+	}
+
+	// SendMail upgrades the connection with STARTTLS if the server supports it.
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	if err = smtp.SendMail(addr, auth, cfg.From, cfg.To, msg); err != nil {
+		return fmt.Errorf("can not send email: %v", err)
+	}
+	return nil
+}
+
+// emailNotifier mails the report, with the HTML tables for the mail
+// clients which don't render Markdown.
+type emailNotifier struct{}
+
+func (emailNotifier) Name() string {
+	return "email"
+}
+
+func (emailNotifier) Send(ctx context.Context, report SprintReport) error {
+	var plainText, htmlBody bytes.Buffer
+	for _, r := range report.Projects {
+		fmt.Fprintf(&plainText, "## %s\n\n%s\n", r.title(), renderProjectText(r))
+		fmt.Fprintf(&htmlBody, "<h2>%s</h2>\n<p>%s</p>\n<p>%s</p>\n%s", html.EscapeString(r.title()),
+			html.EscapeString(r.progress.String()), r.carryover, renderHTML(r.report))
+		if r.components != nil {
+			fmt.Fprintf(&htmlBody, "<br />\n%s", renderGroupedHTML(r.components, "Component"))
+		}
+		if r.epics != nil {
+			fmt.Fprintf(&htmlBody, "<br />\n%s", renderGroupedHTML(r.epics, "Epic"))
+		}
+		if blocked := renderBlocked(r.blocked); len(blocked) > 0 {
+			fmt.Fprintf(&htmlBody, "<pre>%s</pre>\n", html.EscapeString(blocked))
+		}
+		if stale := renderStale(r.stale); len(stale) > 0 {
+			fmt.Fprintf(&htmlBody, "<pre>%s</pre>\n", html.EscapeString(stale))
+		}
+		if unestimated := renderUnestimated(r.unestimated); len(unestimated) > 0 {
+			fmt.Fprintf(&htmlBody, "<p>%s</p>\n", html.EscapeString(unestimated))
+		}
+		if overloaded := renderOverloaded(r.report); len(overloaded) > 0 {
+			fmt.Fprintf(&htmlBody, "<p>%s</p>\n", html.EscapeString(overloaded))
+		}
+		if r.changes != nil {
+			fmt.Fprintf(&htmlBody, "<pre>%s</pre>\n", html.EscapeString(r.changes.String()))
+		}
+	}
+
+	var attachments []emailAttachment
+	for _, r := range report.Projects {
+		if len(r.chart) > 0 {
+			attachments = append(attachments, emailAttachment{name: r.chartName(), contentType: "image/png", content: r.chart})
+		}
+	}
+	return sendEmail(report.Subject(), plainText.String(), htmlBody.String(), attachments...)
+}
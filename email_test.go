@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestRenderHTML(t *testing.T) {
+	config = &Config{}
+	config.Jira.StoryPointField = "customfield_10001"
+	defer func() { config = nil }()
+
+	out := renderHTML(buildAssigneeReport([]jira.Issue{
+		newReportIssue("TEST-1", "<alice>", "Done", 3),
+	}))
+	for _, expect := range []string{"Assignee</th>", "&lt;alice&gt;</td>", ">Total</th>", ">3</th>"} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("expect %q in %s", expect, out)
+		}
+	}
+}
+
+func TestBuildEmail(t *testing.T) {
+	msg, err := buildEmail("bot@example.com", []string{"a@example.com", "b@example.com"}, "Sprint report", "| a |", "<table></table>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to := m.Header.Get("To"); to != "a@example.com, b@example.com" {
+		t.Fatalf("unexpected To %q", to)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %s, %v", mediaType, err)
+	}
+
+	var types []string
+	reader := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+	}
+	if len(types) != 2 || !strings.HasPrefix(types[1], "text/html") {
+		t.Fatalf("expect text and html parts, got %v", types)
+	}
+}
+
+func TestBuildEmailAttachments(t *testing.T) {
+	chart := []byte("\x89PNG not really")
+	msg, err := buildEmail("bot@example.com", []string{"a@example.com"}, "Sprint report", "| a |", "<table></table>",
+		emailAttachment{name: "TEST-velocity.png", contentType: "image/png", content: chart})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("unexpected content type %s, %v", mediaType, err)
+	}
+
+	reader := multipart.NewReader(m.Body, params["boundary"])
+	body, err := reader.NextPart()
+	if err != nil || !strings.HasPrefix(body.Header.Get("Content-Type"), "multipart/alternative") {
+		t.Fatalf("expect the alternative body first, got %v %v", body, err)
+	}
+	attachment, err := reader.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attachment.FileName() != "TEST-velocity.png" || attachment.Header.Get("Content-Type") != "image/png" {
+		t.Fatalf("unexpected attachment %v", attachment.Header)
+	}
+	data, err := ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, attachment))
+	if err != nil || !bytes.Equal(data, chart) {
+		t.Fatalf("expect the chart, got %q %v", data, err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+const (
+	// defaultEpicLinkField is the epic link field of our Jira, it differs
+	// between instances.
+	defaultEpicLinkField = "customfield_10100"
+	noEpicLabel          = "(no epic)"
+	// epicBatchSize limits the keys in one query, so it stays well below
+	// the URL length limit.
+	epicBatchSize = 100
+)
+
+func epicLinkField() string {
+	if field := config.Jira.EpicLinkField; len(field) > 0 {
+		return field
+	}
+	return defaultEpicLinkField
+}
+
+// issueEpic returns the key of the epic of the issue, or "" if it has none.
+func issueEpic(issue jira.Issue) string {
+	if issue.Fields == nil {
+		return ""
+	}
+	key, _ := issue.Fields.Unknowns[epicLinkField()].(string)
+	return key
+}
+
+// groupByEpic buckets the issues by the key of their epic, the issues
+// without epic are put under noEpicLabel.
+func groupByEpic(issues []jira.Issue) map[string][]jira.Issue {
+	groups := make(map[string][]jira.Issue)
+	for _, issue := range issues {
+		epic := issueEpic(issue)
+		if len(epic) == 0 {
+			epic = noEpicLabel
+		}
+		groups[epic] = append(groups[epic], issue)
+	}
+	return groups
+}
+
+// epicSummaries returns the summaries of the epics by key, the epics are
+// fetched with a few batched searches instead of one request each.
+func epicSummaries(keys []string) (map[string]string, error) {
+	summaries := make(map[string]string, len(keys))
+	for start := 0; start < len(keys); start += epicBatchSize {
+		end := start + epicBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		epics, err := queryJiraIssuesWithOptions(NewJQL().Keys(keys[start:end]...).String(),
+			&jira.SearchOptions{Fields: []string{"summary"}})
+		if err != nil {
+			return nil, err
+		}
+		for _, epic := range epics {
+			if epic.Fields != nil {
+				summaries[epic.Key] = epic.Fields.Summary
+			}
+		}
+	}
+	return summaries, nil
+}
+
+// buildEpicReport groups the issues by epic, the summaries keep the epic
+// key and summary in Assignee.
+func buildEpicReport(issues []jira.Issue) (map[string]AssigneeSummary, error) {
+	groups := groupByEpic(issues)
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		if key != noEpicLabel {
+			keys = append(keys, key)
+		}
+	}
+	summaries, err := epicSummaries(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildGroupedReport(issues, func(issue jira.Issue) []string {
+		epic := issueEpic(issue)
+		if len(epic) == 0 {
+			return []string{noEpicLabel}
+		}
+		if summary, ok := summaries[epic]; ok {
+			return []string{fmt.Sprintf("%s %s", epic, summary)}
+		}
+		return []string{epic}
+	}), nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func newEpicIssue(key, epic string) jira.Issue {
+	issue := newReportIssue(key, "alice", "To Do", 1)
+	if len(epic) > 0 {
+		issue.Fields.Unknowns["customfield_10200"] = epic
+	}
+	return issue
+}
+
+func TestGroupByEpic(t *testing.T) {
+	config = &Config{}
+	config.Jira.EpicLinkField = "customfield_10200"
+	defer func() { config = nil }()
+
+	groups := groupByEpic([]jira.Issue{
+		newEpicIssue("TEST-1", "TEST-100"),
+		newEpicIssue("TEST-2", ""),
+		newEpicIssue("TEST-3", "TEST-100"),
+	})
+	if len(groups) != 2 || len(groups["TEST-100"]) != 2 || len(groups[noEpicLabel]) != 1 {
+		t.Fatalf("expect 2 issues in TEST-100 and 1 without epic, got %v", groups)
+	}
+}
+
+func TestBuildEpicReport(t *testing.T) {
+	f, cleanup := newFakeJira(t)
+	defer cleanup()
+	config.Jira.EpicLinkField = "customfield_10200"
+
+	var queries []string
+	f.search = func(jql string) []jira.Issue {
+		queries = append(queries, jql)
+		return []jira.Issue{{Key: "TEST-100", Fields: &jira.IssueFields{Summary: "Sprint reports"}}}
+	}
+
+	report, err := buildEpicReport([]jira.Issue{
+		newEpicIssue("TEST-1", "TEST-100"),
+		newEpicIssue("TEST-2", ""),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 1 || queries[0] != `key in ("TEST-100")` {
+		t.Fatalf("expect one batched epic query, got %v", queries)
+	}
+	if report["TEST-100 Sprint reports"].Issues != 1 || report[noEpicLabel].Issues != 1 {
+		t.Fatalf("expect the epic summary in the report, got %v", report)
+	}
+}
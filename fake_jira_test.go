@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// fakeJira is an in-memory Jira serving the agile and search endpoints
+// used by work-reporter.
+type fakeJira struct {
+	sync.Mutex
+
+	t      *testing.T
+	boards []jira.Board
+	// archived are the IDs of the archived boards.
+	archived []int
+	// teamManaged are the keys of the team-managed projects.
+	teamManaged []string
+	sprints     []jira.Sprint
+	issues      []fakeIssue
+	// requests records "METHOD path" of every request.
+	requests []string
+	// search overrides the default JQL matching when set.
+	search func(jql string) []jira.Issue
+}
+
+type fakeIssue struct {
+	jira.Issue
+	sprintID int
+}
+
+var (
+	fakeSprintPath      = regexp.MustCompile(`^/rest/agile/1.0/sprint/(\d+)$`)
+	fakeSprintIssuePath = regexp.MustCompile(`^/rest/agile/1.0/sprint/(\d+)/issue$`)
+	fakeBoardSprintPath = regexp.MustCompile(`^/rest/agile/1.0/board/(\d+)/sprint$`)
+	fakeBoardPath       = regexp.MustCompile(`^/rest/agile/1.0/board/(\d+)$`)
+	fakeIssuePath       = regexp.MustCompile(`^/rest/api/2/issue/([^/]+)$`)
+	fakeWorklogPath     = regexp.MustCompile(`^/rest/api/2/issue/([^/]+)/worklog$`)
+	fakeProjectPath     = regexp.MustCompile(`^/rest/api/2/project/([^/]+)$`)
+	fakeSprintClause    = regexp.MustCompile(`(?i)sprint = (\d+)`)
+	fakeNotDoneClause   = regexp.MustCompile(`statusCategory != "?Done"?`)
+)
+
+// newFakeJira starts a fake Jira and points jiraClient at it, see
+// newTestJiraServer.
+func newFakeJira(t *testing.T) (*fakeJira, func()) {
+	f := &fakeJira{t: t}
+	return f, newTestJiraServer(t, f.serveHTTP)
+}
+
+// newInProcessFakeJira is like newFakeJira, but jiraClient calls the fake
+// directly instead of going through a local server.
+func newInProcessFakeJira(t *testing.T) (*fakeJira, func()) {
+	f := &fakeJira{t: t}
+
+	oldClient, oldConfig, oldCtx := jiraClient, config, globalCtx
+	jiraClient, config, globalCtx = handlerJira{http.HandlerFunc(f.serveHTTP)}, new(Config), context.Background()
+	resetBoardIDCache()
+	return f, func() {
+		resetBoardIDCache()
+		jiraClient, config, globalCtx = oldClient, oldConfig, oldCtx
+	}
+}
+
+// handlerJira implements jiraAPI by serving the requests with handler.
+type handlerJira struct {
+	handler http.Handler
+}
+
+func (h handlerJira) NewRequest(method, urlStr string, body interface{}) (*http.Request, error) {
+	var buf io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		buf = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, "http://jira.test/"+strings.TrimPrefix(urlStr, "/"), buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func (h handlerJira) Do(req *http.Request, v interface{}) (*jira.Response, error) {
+	recorder := httptest.NewRecorder()
+	h.handler.ServeHTTP(recorder, req)
+
+	resp := &jira.Response{Response: recorder.Result()}
+	if err := jira.CheckResponse(resp.Response); err != nil {
+		return resp, err
+	}
+	if v == nil {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	return resp, json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (f *fakeJira) addSprint(sprint jira.Sprint) {
+	f.Lock()
+	defer f.Unlock()
+	f.sprints = append(f.sprints, sprint)
+}
+
+func (f *fakeJira) addIssue(sprintID int, issue jira.Issue) {
+	f.Lock()
+	defer f.Unlock()
+	f.issues = append(f.issues, fakeIssue{Issue: issue, sprintID: sprintID})
+}
+
+func (f *fakeJira) sprint(id int) *jira.Sprint {
+	for i := range f.sprints {
+		if f.sprints[i].ID == id {
+			return &f.sprints[i]
+		}
+	}
+	return nil
+}
+
+// issuesIn returns the keys of the issues in the sprint.
+func (f *fakeJira) issuesIn(sprintID int) []string {
+	f.Lock()
+	defer f.Unlock()
+	var keys []string
+	for _, issue := range f.issues {
+		if issue.sprintID == sprintID {
+			keys = append(keys, issue.Key)
+		}
+	}
+	return keys
+}
+
+func (f *fakeJira) countRequests(prefix string) int {
+	f.Lock()
+	defer f.Unlock()
+	n := 0
+	for _, r := range f.requests {
+		if strings.HasPrefix(r, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeJira) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	path := r.URL.Path
+	f.requests = append(f.requests, r.Method+" "+path)
+
+	switch {
+	case r.Method == "GET" && path == "/rest/agile/1.0/board":
+		// The boards without a type match any.
+		boardType := r.URL.Query().Get("boardType")
+		var boards []jira.Board
+		for _, board := range f.boards {
+			if len(boardType) == 0 || len(board.Type) == 0 || board.Type == boardType {
+				boards = append(boards, board)
+			}
+		}
+		writeJSON(f.t, w, jira.BoardsList{IsLast: true, Values: boards})
+	case r.Method == "GET" && fakeProjectPath.MatchString(path):
+		key := fakeProjectPath.FindStringSubmatch(path)[1]
+		project := jiraProject{Key: key, Style: projectStyleClassic}
+		if containsString(f.teamManaged, key) {
+			project.Style, project.Simplified = projectStyleNextGen, true
+		}
+		writeJSON(f.t, w, project)
+	case r.Method == "GET" && fakeBoardPath.MatchString(path):
+		id, _ := strconv.Atoi(fakeBoardPath.FindStringSubmatch(path)[1])
+		for _, board := range f.boards {
+			if board.ID == id {
+				archived := false
+				for _, a := range f.archived {
+					archived = archived || a == id
+				}
+				writeJSON(f.t, w, jiraBoard{Board: board, Archived: archived})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == "GET" && fakeBoardSprintPath.MatchString(path):
+		var states []string
+		if state := r.URL.Query().Get("state"); len(state) > 0 {
+			states = strings.Split(state, ",")
+		}
+		boardID, _ := strconv.Atoi(fakeBoardSprintPath.FindStringSubmatch(path)[1])
+		var sprints []jira.Sprint
+		for _, sprint := range f.sprints {
+			// A sprint without an origin board is on every board.
+			if sprint.OriginBoardID != 0 && sprint.OriginBoardID != boardID {
+				continue
+			}
+			if len(states) == 0 || containsString(states, sprint.State) {
+				sprints = append(sprints, sprint)
+			}
+		}
+		writeJSON(f.t, w, jira.SprintsList{IsLast: true, Values: sprints})
+	case r.Method == "POST" && path == "/rest/agile/1.0/sprint":
+		var args map[string]string
+		f.decode(r, &args)
+		sprint := jira.Sprint{
+			ID:        1000 + len(f.sprints),
+			Name:      args["name"],
+			State:     "future",
+			StartDate: parseFakeTime(args["startDate"]),
+			EndDate:   parseFakeTime(args["endDate"]),
+		}
+		f.sprints = append(f.sprints, sprint)
+		writeJSON(f.t, w, sprint)
+	case fakeSprintIssuePath.MatchString(path):
+		id, _ := strconv.Atoi(fakeSprintIssuePath.FindStringSubmatch(path)[1])
+		var payload jira.IssuesWrapper
+		f.decode(r, &payload)
+		for i := range f.issues {
+			// Like Jira, subtasks are silently left with their parent.
+			if containsString(payload.Issues, f.issues[i].ID) && !f.issues[i].Fields.Type.Subtask {
+				f.issues[i].sprintID = id
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case fakeSprintPath.MatchString(path):
+		id, _ := strconv.Atoi(fakeSprintPath.FindStringSubmatch(path)[1])
+		sprint := f.sprint(id)
+		if sprint == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case "POST":
+			var args map[string]string
+			f.decode(r, &args)
+			if state, ok := args["state"]; ok {
+				sprint.State = state
+			}
+			if start, ok := args["startDate"]; ok {
+				sprint.StartDate = parseFakeTime(start)
+			}
+			if end, ok := args["endDate"]; ok {
+				sprint.EndDate = parseFakeTime(end)
+			}
+			writeJSON(f.t, w, sprint)
+		case "DELETE":
+			for i := range f.sprints {
+				if f.sprints[i].ID == id {
+					f.sprints = append(f.sprints[:i], f.sprints[i+1:]...)
+					break
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeJSON(f.t, w, sprint)
+		}
+	case r.Method == "PUT" && fakeIssuePath.MatchString(path):
+		id := fakeIssuePath.FindStringSubmatch(path)[1]
+		var payload struct {
+			Update struct {
+				Labels []map[string]string `json:"labels"`
+			} `json:"update"`
+		}
+		f.decode(r, &payload)
+		for i := range f.issues {
+			if f.issues[i].ID != id {
+				continue
+			}
+			for _, op := range payload.Update.Labels {
+				if label, ok := op["add"]; ok && !containsString(f.issues[i].Fields.Labels, label) {
+					f.issues[i].Fields.Labels = append(f.issues[i].Fields.Labels, label)
+				}
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "GET" && fakeWorklogPath.MatchString(path):
+		key := fakeWorklogPath.FindStringSubmatch(path)[1]
+		for _, issue := range f.issues {
+			if issue.Key == key || issue.ID == key {
+				worklog := &jira.Worklog{}
+				if issue.Fields != nil && issue.Fields.Worklog != nil {
+					worklog = issue.Fields.Worklog
+				}
+				writeJSON(f.t, w, worklog)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == "GET" && path == "/rest/api/2/search":
+		issues := f.searchIssues(r.URL.Query().Get("jql"))
+		writeJSON(f.t, w, map[string]interface{}{
+			"startAt":    0,
+			"maxResults": len(issues),
+			"total":      len(issues),
+			"issues":     issues,
+		})
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// searchIssues understands "sprint = N" and "statusCategory != Done"
+// clauses of the JQL, unless f.search is set.
+func (f *fakeJira) searchIssues(jql string) []jira.Issue {
+	if f.search != nil {
+		return f.search(jql)
+	}
+
+	sprintID := -1
+	if m := fakeSprintClause.FindStringSubmatch(jql); m != nil {
+		sprintID, _ = strconv.Atoi(m[1])
+	}
+	notDone := fakeNotDoneClause.MatchString(jql)
+
+	issues := []jira.Issue{}
+	for _, issue := range f.issues {
+		if sprintID >= 0 && issue.sprintID != sprintID {
+			continue
+		}
+		if notDone && issue.Fields != nil && issue.Fields.Status != nil &&
+			issue.Fields.Status.StatusCategory.Key == jira.StatusCategoryComplete {
+			continue
+		}
+		issues = append(issues, issue.Issue)
+	}
+	return issues
+}
+
+func (f *fakeJira) decode(r *http.Request, v interface{}) {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		f.t.Error(err)
+	}
+}
+
+func parseFakeTime(s string) *time.Time {
+	if len(s) == 0 {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// newFakeIssue creates an issue with the given status category key, like
+// jira.StatusCategoryComplete.
+func newFakeIssue(id int, category string) jira.Issue {
+	return jira.Issue{
+		ID:  strconv.Itoa(id),
+		Key: "TEST-" + strconv.Itoa(id),
+		Fields: &jira.IssueFields{
+			Status: &jira.Status{
+				StatusCategory: jira.StatusCategory{Key: category},
+			},
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// SprintForecast projects whether the sprint completes at the pace so far.
+type SprintForecast struct {
+	Sprint          jira.Sprint
+	TotalPoints     float64
+	DonePoints      float64
+	RemainingPoints float64
+	// Elapsed is the passed fraction of the sprint window, from 0 to 1.
+	Elapsed  float64
+	DaysLeft float64
+	// ProjectedPoints is what is done by the end if the pace so far holds.
+	ProjectedPoints float64
+	// AtRisk is set if the projected points are below the total.
+	AtRisk bool
+}
+
+func (f SprintForecast) String() string {
+	status := "on track"
+	if f.AtRisk {
+		status = "at risk"
+	}
+	return fmt.Sprintf("%s: %s of %s points done, %s remaining with %.1f days left (%.0f%% elapsed), %s projected, %s",
+		f.Sprint.Name, formatPoints(f.DonePoints), formatPoints(f.TotalPoints), formatPoints(f.RemainingPoints),
+		f.DaysLeft, f.Elapsed*100, formatPoints(f.ProjectedPoints), status)
+}
+
+// sprintForecast forecasts the sprint from its issues and dates.
+func sprintForecast(sprintID int) (SprintForecast, error) {
+	sprint, err := getSprintByID(sprintID)
+	if err != nil {
+		return SprintForecast{}, err
+	}
+	if sprint.StartDate == nil || sprint.EndDate == nil {
+		return SprintForecast{}, fmt.Errorf("sprint %s has no start or end date", sprint.Name)
+	}
+
+	issues, err := issuesInSprint(sprint.ID)
+	if err != nil {
+		return SprintForecast{}, err
+	}
+	return forecastSprint(sprint, issues, nowFunc()), nil
+}
+
+func forecastSprint(sprint jira.Sprint, issues []jira.Issue, now time.Time) SprintForecast {
+	f := SprintForecast{Sprint: sprint}
+	for _, issue := range issues {
+		points, _ := storyPoints(issue)
+		f.TotalPoints += points
+		if isDone(issue) {
+			f.DonePoints += points
+		}
+	}
+	f.RemainingPoints = f.TotalPoints - f.DonePoints
+
+	window := sprint.EndDate.Sub(*sprint.StartDate)
+	elapsed := now.Sub(*sprint.StartDate)
+	switch {
+	case window <= 0 || elapsed >= window:
+		f.Elapsed = 1
+	case elapsed > 0:
+		f.Elapsed = float64(elapsed) / float64(window)
+	}
+	if left := sprint.EndDate.Sub(now); left > 0 {
+		f.DaysLeft = left.Hours() / 24
+	}
+
+	if f.Elapsed > 0 {
+		f.ProjectedPoints = f.DonePoints / f.Elapsed
+		f.AtRisk = f.ProjectedPoints < f.TotalPoints
+	} else {
+		// Nothing can be told before the sprint starts.
+		f.ProjectedPoints = f.TotalPoints
+	}
+	return f
+}
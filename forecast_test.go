@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestForecastSprint(t *testing.T) {
+	config = &Config{}
+	config.Jira.StoryPointField = "customfield_10001"
+	defer func() { config = nil }()
+
+	sprint := jira.Sprint{Name: "TEST 1", StartDate: day(1, 1), EndDate: day(1, 11)}
+	issues := []jira.Issue{
+		newReportIssue("TEST-1", "alice", "Done", 3),
+		newReportIssue("TEST-2", "alice", "In Progress", 5),
+		newReportIssue("TEST-3", "bob", "To Do", 2),
+	}
+
+	cases := []struct {
+		now       time.Time
+		elapsed   float64
+		projected float64
+		atRisk    bool
+	}{
+		{*day(1, 1), 0, 10, false},
+		// 3 points after a fifth of the sprint projects 15.
+		{*day(1, 3), 0.2, 15, false},
+		// 3 points after half of it projects only 6.
+		{*day(1, 6), 0.5, 6, true},
+		{*day(1, 20), 1, 3, true},
+	}
+	for _, c := range cases {
+		f := forecastSprint(sprint, issues, c.now)
+		if f.TotalPoints != 10 || f.DonePoints != 3 || f.RemainingPoints != 7 {
+			t.Fatalf("expect 3 of 10 points done, got %+v", f)
+		}
+		if f.Elapsed != c.elapsed || f.ProjectedPoints != c.projected || f.AtRisk != c.atRisk {
+			t.Errorf("at %v: expect elapsed %v, projected %v, at risk %v, got %v, %v, %v",
+				c.now, c.elapsed, c.projected, c.atRisk, f.Elapsed, f.ProjectedPoints, f.AtRisk)
+		}
+	}
+}
+
+func TestSprintForecast(t *testing.T) {
+	f, cleanup := newFakeJira(t)
+	defer cleanup()
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST 1", State: "active"})
+
+	if _, err := sprintForecast(1); err == nil {
+		t.Fatal("expect error for a sprint without dates")
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+const (
+	// The reports are kept for this many runs of each project unless
+	// configured otherwise.
+	defaultHistoryKeep = 10
+	// historyTimeFormat is part of the file names, so it sorts by time.
+	historyTimeFormat = "20060102T150405Z"
+)
+
+// reportSnapshot is the stored report of one run, with the issues to diff
+// the next run against.
+type reportSnapshot struct {
+	Time   time.Time       `json:"time"`
+	Report JSONReport      `json:"report"`
+	Issues []snapshotIssue `json:"issues"`
+}
+
+type snapshotIssue struct {
+	Key      string `json:"key"`
+	Assignee string `json:"assignee"`
+	Done     bool   `json:"done"`
+}
+
+func newReportSnapshot(r projectReport, issues []jira.Issue, now time.Time) reportSnapshot {
+	snapshot := reportSnapshot{Time: now.UTC(), Report: buildJSONReport(r)}
+	for _, issue := range issues {
+		snapshot.Issues = append(snapshot.Issues, snapshotIssue{
+			Key:      issue.Key,
+			Assignee: issueAssignee(issue),
+			Done:     isDone(issue),
+		})
+	}
+	return snapshot
+}
+
+// reportChanges are the changes of the issues since the previous report.
+type reportChanges struct {
+	Since     time.Time
+	Completed []string
+	Added     []string
+	Removed   []string
+	Reopened  []string
+	// Reassigned has "KEY (from → to)" of the issues with a new assignee.
+	Reassigned []string
+}
+
+// diffSnapshots compares the issues of the snapshots by key.
+func diffSnapshots(previous, current reportSnapshot) reportChanges {
+	changes := reportChanges{Since: previous.Time}
+	before := make(map[string]snapshotIssue, len(previous.Issues))
+	for _, issue := range previous.Issues {
+		before[issue.Key] = issue
+	}
+
+	seen := make(map[string]bool, len(current.Issues))
+	for _, issue := range current.Issues {
+		seen[issue.Key] = true
+		old, ok := before[issue.Key]
+		if !ok {
+			changes.Added = append(changes.Added, issue.Key)
+			continue
+		}
+		if issue.Done && !old.Done {
+			changes.Completed = append(changes.Completed, issue.Key)
+		} else if !issue.Done && old.Done {
+			changes.Reopened = append(changes.Reopened, issue.Key)
+		}
+		if issue.Assignee != old.Assignee {
+			changes.Reassigned = append(changes.Reassigned, fmt.Sprintf("%s (%s → %s)", issue.Key, old.Assignee, issue.Assignee))
+		}
+	}
+	for _, issue := range previous.Issues {
+		if !seen[issue.Key] {
+			changes.Removed = append(changes.Removed, issue.Key)
+		}
+	}
+	return changes
+}
+
+func (c reportChanges) empty() bool {
+	return len(c.Completed)+len(c.Added)+len(c.Removed)+len(c.Reopened)+len(c.Reassigned) == 0
+}
+
+// String renders the changes as the "Changes since last report" section.
+func (c reportChanges) String() string {
+	loc, err := sprintLocation(time.Local)
+	if err != nil {
+		loc = time.Local
+	}
+	title := "Changes since last report (" + c.Since.In(loc).Format(displayDateFormat()) + ")"
+	if c.empty() {
+		return title + ": none\n"
+	}
+
+	var b strings.Builder
+	b.WriteString(title + ":\n")
+	for _, group := range []struct {
+		name string
+		keys []string
+	}{
+		{"completed", c.Completed},
+		{"added", c.Added},
+		{"removed", c.Removed},
+		{"reopened", c.Reopened},
+		{"reassigned", c.Reassigned},
+	} {
+		if len(group.keys) > 0 {
+			fmt.Fprintf(&b, "- %d %s: %s\n", len(group.keys), group.name, strings.Join(group.keys, ", "))
+		}
+	}
+	return b.String()
+}
+
+func historyKeep() int {
+	if keep := config.Report.HistoryKeep; keep > 0 {
+		return keep
+	}
+	return defaultHistoryKeep
+}
+
+// historyFiles returns the stored reports of the project, oldest first.
+func historyFiles(dir, project string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	prefix := project + "-"
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		// Skip the reports of a project whose name starts with this one.
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".json")
+		if _, err := time.Parse(historyTimeFormat, stamp); err != nil {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadLatestSnapshot returns the last stored report of the project, ok is
+// false if there is none.
+func loadLatestSnapshot(dir, project string) (snapshot reportSnapshot, ok bool, err error) {
+	files, err := historyFiles(dir, project)
+	if err != nil || len(files) == 0 {
+		return snapshot, false, err
+	}
+	data, err := ioutil.ReadFile(files[len(files)-1])
+	if err != nil {
+		return snapshot, false, err
+	}
+	if err = json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, false, fmt.Errorf("invalid report %s: %v", files[len(files)-1], err)
+	}
+	return snapshot, true, nil
+}
+
+// saveSnapshot stores the report of the project and removes its oldest
+// reports beyond the retention count.
+func saveSnapshot(dir, project string, snapshot reportSnapshot) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.json", project, snapshot.Time.UTC().Format(historyTimeFormat))
+	if err = writeFileAtomic(filepath.Join(dir, name), data); err != nil {
+		return err
+	}
+
+	files, err := historyFiles(dir, project)
+	if err != nil {
+		return err
+	}
+	for len(files) > historyKeep() {
+		if err = os.Remove(files[0]); err != nil {
+			return err
+		}
+		files = files[1:]
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	previous := reportSnapshot{
+		Time: time.Date(2018, time.January, 2, 9, 0, 0, 0, time.UTC),
+		Issues: []snapshotIssue{
+			{Key: "TEST-1", Assignee: "alice"},
+			{Key: "TEST-2", Assignee: "bob", Done: true},
+			{Key: "TEST-3", Assignee: "bob"},
+			{Key: "TEST-4", Assignee: "alice"},
+		},
+	}
+	current := reportSnapshot{Issues: []snapshotIssue{
+		{Key: "TEST-1", Assignee: "alice", Done: true},
+		{Key: "TEST-2", Assignee: "bob"},
+		{Key: "TEST-3", Assignee: "alice"},
+		{Key: "TEST-5", Assignee: "bob"},
+	}}
+
+	changes := diffSnapshots(previous, current)
+	expect := `Changes since last report (Jan 2):
+- 1 completed: TEST-1
+- 1 added: TEST-5
+- 1 removed: TEST-4
+- 1 reopened: TEST-2
+- 1 reassigned: TEST-3 (bob → alice)
+`
+	config.Jira.Timezone = "UTC"
+	if got := changes.String(); got != expect {
+		t.Fatalf("expect\n%s\ngot\n%s", expect, got)
+	}
+
+	if got := diffSnapshots(current, current).String(); !strings.HasSuffix(got, ": none\n") {
+		t.Fatalf("expect no changes, got %q", got)
+	}
+}
+
+func TestReportHistory(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+	config.Report.HistoryKeep = 2
+
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, ok, err := loadLatestSnapshot(dir+"/missing", "TEST"); ok || err != nil {
+		t.Fatalf("expect no report in a missing dir, got %v, %v", ok, err)
+	}
+
+	start := time.Date(2018, time.January, 2, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		snapshot := reportSnapshot{Time: start.AddDate(0, 0, 7*i), Issues: []snapshotIssue{{Key: "TEST-1"}}}
+		if err = saveSnapshot(dir, "TEST", snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Another project whose name starts with TEST.
+	if err = saveSnapshot(dir, "TEST-ONE", reportSnapshot{Time: start.AddDate(1, 0, 0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := historyFiles(dir, "TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expect the last 2 reports kept, got %v", files)
+	}
+	snapshot, ok, err := loadLatestSnapshot(dir, "TEST")
+	if err != nil || !ok {
+		t.Fatalf("expect the last report, got %v, %v", ok, err)
+	}
+	if expect := start.AddDate(0, 0, 14); !snapshot.Time.Equal(expect) || len(snapshot.Issues) != 1 {
+		t.Fatalf("expect the report of %v, got %+v", expect, snapshot)
+	}
+}
@@ -1,10 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	jira "github.com/andygrunwald/go-jira"
 )
@@ -12,73 +24,619 @@ import (
 const (
 	dayFormat  = "2006-01-02"
 	dateFormat = "2006-01-02T15:04:05Z07:00"
-	// We use one week for a sprint
-	sprintDuration = 7 * 24 * time.Hour
+	// JQL does not accept RFC 3339 dates, and interprets the dates in the
+	// timezone of the Jira user.
+	jqlDateFormat = "2006-01-02 15:04"
+	// We use one week for a sprint unless configured otherwise
+	defaultSprintDuration = 7 * 24 * time.Hour
+	// The maximum number of issues that can be moved in one operation on
+	// Jira Cloud is 50, it is the default on Jira Server/Data Center.
+	maxMoveBatchSize = 50
+	// Empty future sprints are only pruned if they should have started
+	// this long ago.
+	defaultPruneAfter = 14 * 24 * time.Hour
+
+	boardTypeScrum  = "scrum"
+	boardTypeKanban = "kanban"
+	// The boards of the team-managed projects, they may have sprints.
+	boardTypeSimple = "simple"
+
+	deploymentCloud  = "cloud"
+	deploymentServer = "server"
 )
 
-// Get the board ID by project and boardType.
-// Here we assume that you must create a board in the project and
-// the function will return the first board ID.
-func getBoardID(project string, boardType string) int {
-	opts := jira.BoardListOptions{
+// nowFunc is the clock of everything which depends on the date, like
+// picking, rolling over and pruning the sprints. Tests set it to freeze the
+// time.
+var nowFunc = time.Now
+
+// isJiraServer returns whether we talk to Jira Server/Data Center, Jira
+// Cloud is assumed unless configured otherwise.
+func isJiraServer() bool {
+	return config.Jira.Deployment == deploymentServer
+}
+
+func sprintDuration() time.Duration {
+	if d := config.Jira.SprintDuration.Duration; d > 0 {
+		return d
+	}
+	return defaultSprintDuration
+}
+
+// sprintLocation returns the configured timezone of the sprints, or
+// fallback if there is none.
+func sprintLocation(fallback *time.Location) (*time.Location, error) {
+	if len(config.Jira.Timezone) == 0 {
+		return fallback, nil
+	}
+	return time.LoadLocation(config.Jira.Timezone)
+}
+
+// alignSprintStart moves t to the configured start time of day of its
+// date in the configured timezone. Without any configuration t is
+// returned unchanged.
+func alignSprintStart(t time.Time) (time.Time, error) {
+	loc, err := sprintLocation(t.Location())
+	if err != nil {
+		return t, err
+	}
+	t = t.In(loc)
+
+	if len(config.Jira.SprintStartTimeOfDay) == 0 {
+		return t, nil
+	}
+
+	tod, err := time.Parse("15:04", config.Jira.SprintStartTimeOfDay)
+	if err != nil {
+		return t, fmt.Errorf("invalid sprint start time of day %q, expect HH:MM", config.Jira.SprintStartTimeOfDay)
+	}
+
+	year, month, day := t.Date()
+	return time.Date(year, month, day, tod.Hour(), tod.Minute(), 0, 0, loc), nil
+}
+
+// sprintEnd returns the end of a sprint starting at start. A duration of
+// whole days is added on the calendar, so the sprint ends at the same
+// wall clock time even if a DST transition happens within it.
+func sprintEnd(start time.Time) time.Time {
+	d := sprintDuration()
+	if d%(24*time.Hour) == 0 {
+		return start.AddDate(0, 0, int(d/(24*time.Hour)))
+	}
+	return start.Add(d)
+}
+
+// sprintLastDay returns the last day of a sprint ending at end, the day
+// before the calendar date of end in its location. The end is exclusive, and
+// a sprint from 10:00 to 10:00 has the same days as one from midnight to
+// midnight.
+func sprintLastDay(end time.Time) time.Time {
+	y, m, d := end.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, end.Location()).AddDate(0, 0, -1)
+}
+
+// boardKey identifies a cached board lookup.
+type boardKey struct {
+	project   string
+	boardType string
+	name      string
+}
+
+// boardIDCache remembers the resolved board IDs for the lifetime of the
+// process, the boards rarely change and the lookup pages through all of them.
+var boardIDCache = struct {
+	sync.Mutex
+	ids map[boardKey]int
+}{ids: make(map[boardKey]int)}
+
+func resetBoardIDCache() {
+	boardIDCache.Lock()
+	boardIDCache.ids = make(map[boardKey]int)
+	boardIDCache.Unlock()
+}
+
+// getBoardID returns the board ID of the project, the result is cached
+// unless jira disable-board-cache is set. The project must have a board of
+// boardType, the first one is taken unless a board name is configured and
+// one of the boards matches it exactly.
+func getBoardID(project string, boardType string) (int, error) {
+	if id := config.Jira.BoardID; id > 0 {
+		// Checked by checkBoardID at startup.
+		return id, nil
+	}
+	if config.Jira.DisableBoardCache {
+		return lookupBoardID(project, boardType)
+	}
+
+	key := boardKey{project: project, boardType: boardType, name: config.Jira.BoardName}
+	boardIDCache.Lock()
+	id, ok := boardIDCache.ids[key]
+	boardIDCache.Unlock()
+	if ok {
+		logger.Debug("board resolved from cache", "project", project, "board_type", boardType, "board_id", id)
+		return id, nil
+	}
+
+	id, err := lookupBoardID(project, boardType)
+	if err != nil {
+		return 0, err
+	}
+
+	boardIDCache.Lock()
+	boardIDCache.ids[key] = id
+	boardIDCache.Unlock()
+	logger.Info("board resolved", "project", project, "board_type", boardType, "board_id", id)
+	return id, nil
+}
+
+func lookupBoardID(project string, boardType string) (int, error) {
+	boards, err := getAllBoards(jira.BoardListOptions{
 		BoardType:      boardType,
 		ProjectKeyOrID: project,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(boards) == 0 {
+		if boardType != boardTypeSimple {
+			// A team-managed project only has a simple board.
+			if id, ok, err := lookupTeamManagedBoardID(project); ok || err != nil {
+				return id, err
+			}
+		}
+		return 0, fmt.Errorf("no %s board found for project %q", boardType, project)
 	}
 
-	boards, _, err := jiraClient.Board.GetAllBoards(&opts)
-	perror(err)
+	board := boards[0]
+	if name := config.Jira.BoardName; len(name) > 0 {
+		for _, b := range boards {
+			if b.Name == name {
+				board = b
+				break
+			}
+		}
+	}
 
-	return boards.Values[0].ID
+	// The board list may still have a board which was just deleted, and
+	// archived boards aren't fit to report on.
+	if _, err = getBoard(board.ID); err != nil {
+		if err == errBoardGone {
+			return 0, fmt.Errorf("%s board %s (%d) of project %q is %v, set jira board-name or board-id to another board",
+				boardType, board.Name, board.ID, project, err)
+		}
+		return 0, fmt.Errorf("can not get %s board %s (%d) of project %q: %v", boardType, board.Name, board.ID, project, err)
+	}
+	return board.ID, nil
 }
 
-func getSprints(boardID int, opts jira.GetAllSprintsOptions) []jira.Sprint {
+func boardType() string {
+	if t := config.Jira.BoardType; len(t) > 0 {
+		return t
+	}
+	return boardTypeScrum
+}
+
+// getSprintBoardID returns the ID of the configured board type of the
+// project, for the commands working on its sprints. Kanban boards don't have
+// sprints.
+func getSprintBoardID(project string) (int, error) {
+	t := boardType()
+	if t == boardTypeKanban {
+		return 0, fmt.Errorf("project %s: %s boards have no sprints, use a %s board", project, t, boardTypeScrum)
+	}
+	return getBoardID(project, t)
+}
+
+// jiraBoard is a board of the agile API, archived boards are still served
+// but flagged.
+type jiraBoard struct {
+	jira.Board
+	Archived bool `json:"isArchived"`
+}
+
+// errBoardGone is returned by getBoard for a board which was deleted or
+// archived.
+var errBoardGone = errors.New("deleted or archived")
+
+// getBoard returns the board with the ID, or errBoardGone if it was deleted
+// or archived.
+func getBoard(boardID int) (jira.Board, error) {
+	req, err := newJiraRequest(globalCtx, "GET", "rest/agile/1.0/board/"+strconv.Itoa(boardID), nil)
+	if err != nil {
+		return jira.Board{}, err
+	}
+
+	board := new(jiraBoard)
+	if _, err = doWithRetry(req, board); err != nil {
+		if apiErr, ok := err.(*jiraAPIError); ok && apiErr.StatusCode == http.StatusNotFound {
+			return jira.Board{}, errBoardGone
+		}
+		return jira.Board{}, err
+	}
+	if board.Archived {
+		return board.Board, errBoardGone
+	}
+	return board.Board, nil
+}
+
+// checkBoardID makes sure the configured board ID exists and isn't
+// archived, the board is used without a lookup.
+func checkBoardID(boardID int) error {
+	if _, err := getBoard(boardID); err != nil {
+		if err == errBoardGone {
+			return fmt.Errorf("jira board-id %d not found or archived, set board-id to an existing board", boardID)
+		}
+		return fmt.Errorf("can not get jira board-id %d: %v", boardID, err)
+	}
+	return nil
+}
+
+// Get the board ID by project, boardType and the exact board name.
+func getBoardIDByName(project string, boardType string, name string) (int, error) {
+	boards, err := getAllBoards(jira.BoardListOptions{
+		BoardType:      boardType,
+		ProjectKeyOrID: project,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, board := range boards {
+		if board.Name == name {
+			return board.ID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no %s board named %q found for project %q", boardType, name, project)
+}
+
+// getAllBoards returns the boards matching opts from all pages, the
+// paging options in opts are ignored.
+func getAllBoards(opts jira.BoardListOptions) ([]jira.Board, error) {
+	var allBoards []jira.Board
+
+	pos := 0
+	for {
+		nextOpts := opts
+		nextOpts.SearchOptions = jira.SearchOptions{
+			StartAt:    pos,
+			MaxResults: 100,
+		}
+		url, err := addOptions("rest/agile/1.0/board", &nextOpts)
+		if err != nil {
+			return nil, err
+		}
+		req, err := newJiraRequest(globalCtx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		results := new(jira.BoardsList)
+		if _, err = doWithRetry(req, results); err != nil {
+			return nil, err
+		}
+		allBoards = append(allBoards, results.Values...)
+
+		if results.IsLast || len(results.Values) == 0 {
+			break
+		}
+		pos += len(results.Values)
+	}
+
+	return allBoards, nil
+}
+
+// getSprints lists the sprints of the board. opts.State is one state or a
+// comma separated list of them, empty for all sprints.
+func getSprints(boardID int, opts jira.GetAllSprintsOptions) ([]jira.Sprint, error) {
+	return listSprintsCtx(globalCtx, boardID, sprintListOptions{GetAllSprintsOptions: opts})
+}
+
+// getSprintsInStates lists the sprints of the board in any of the states,
+// in one paginated sweep.
+func getSprintsInStates(boardID int, states ...string) ([]jira.Sprint, error) {
+	return listSprintsCtx(globalCtx, boardID, sprintListOptions{States: states})
+}
+
+var sprintStates = []string{"future", "active", "closed"}
+
+// joinSprintStates merges the comma separated state and the states into
+// the state parameter of the API, it rejects unknown states since Jira
+// answers them with a bare 400.
+func joinSprintStates(state string, states []string) (string, error) {
+	var joined []string
+	for _, s := range append(strings.Split(state, ","), states...) {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if len(s) == 0 || containsState(joined, s) {
+			continue
+		}
+		if !containsState(sprintStates, s) {
+			return "", fmt.Errorf("unknown sprint state %q, expect %s", s, strings.Join(sprintStates, ", "))
+		}
+		joined = append(joined, s)
+	}
+	return strings.Join(joined, ","), nil
+}
+
+func containsState(states []string, state string) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// The sprints are listed in pages of jira sprint-page-size, or of
+// defaultSprintPageSize which is what Jira Cloud returns at most anyway.
+// Smaller pages help against timeouts on slow links.
+const (
+	defaultSprintPageSize = 50
+	maxSprintPageSize     = 100
+)
+
+// sprintPageSize returns the page size asked for in MaxResults, else the
+// configured one.
+func sprintPageSize(opts sprintListOptions) (int, error) {
+	size := opts.MaxResults
+	if size == 0 {
+		size = config.Jira.SprintPageSize
+	}
+	if size == 0 {
+		return defaultSprintPageSize, nil
+	}
+	if size < 0 || size > maxSprintPageSize {
+		return 0, fmt.Errorf("sprint page size must be between 0 (default) and %d, got %d", maxSprintPageSize, size)
+	}
+	return size, nil
+}
+
+// sprintListOptions limits how many sprints listSprints fetches. The state
+// filter is applied by Jira, the limits are applied while paging so we stop
+// fetching as soon as we have enough. MaxResults is the page size, see
+// sprintPageSize.
+type sprintListOptions struct {
+	jira.GetAllSprintsOptions
+	// States are added to the State of GetAllSprintsOptions.
+	States []string
+	// Limit is the maximum number of sprints returned, 0 means no limit.
+	Limit int
+	// Stop ends the listing at the first sprint it returns true for, that
+	// sprint is not included.
+	Stop func(jira.Sprint) bool
+}
+
+func listSprints(boardID int, opts sprintListOptions) ([]jira.Sprint, error) {
+	return listSprintsCtx(globalCtx, boardID, opts)
+}
+
+// upcomingSprintOptions lists the active and future sprints which may be
+// picked by getNearestFutureSprint. Jira returns them in planned order, so
+// the listing stops at the first sprint starting more than a sprint
+// duration from now.
+func upcomingSprintOptions() sprintListOptions {
+	horizon := nowFunc().Add(sprintDuration())
+	return sprintListOptions{
+		GetAllSprintsOptions: jira.GetAllSprintsOptions{State: "active,future"},
+		Stop: func(sprint jira.Sprint) bool {
+			return sprint.StartDate != nil && sprint.StartDate.After(horizon)
+		},
+	}
+}
+
+func listSprintsCtx(ctx context.Context, boardID int, opts sprintListOptions) ([]jira.Sprint, error) {
 	var allSprints []jira.Sprint
 
+	apiEndpoint := fmt.Sprintf("rest/agile/1.0/board/%d/sprint", boardID)
+	state, err := joinSprintStates(opts.State, opts.States)
+	if err != nil {
+		return nil, err
+	}
+	pageSize, err := sprintPageSize(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	pos := 0
 	for {
 		nextOpts := &jira.GetAllSprintsOptions{
-			State: opts.State,
+			State: state,
 			SearchOptions: jira.SearchOptions{
 				StartAt:    pos,
-				MaxResults: 100,
+				MaxResults: pageSize,
 			},
 		}
-		results, _, err := jiraClient.Board.GetAllSprintsWithOptions(boardID, nextOpts)
-		perror(err)
-		allSprints = append(allSprints, results.Values...)
+		url, err := addOptions(apiEndpoint, nextOpts)
+		if err != nil {
+			return nil, err
+		}
+		req, err := newJiraRequest(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		results := new(jiraSprintsList)
+		if _, err = doWithRetry(req, results); err != nil {
+			return nil, err
+		}
+		for _, sprint := range results.sprints() {
+			if opts.Stop != nil && opts.Stop(sprint) {
+				return allSprints, nil
+			}
+			allSprints = append(allSprints, sprint)
+			if opts.Limit > 0 && len(allSprints) >= opts.Limit {
+				return allSprints, nil
+			}
+		}
 
-		if results.IsLast {
+		if results.IsLast || len(results.Values) == 0 {
 			break
 		}
 		pos += len(results.Values)
 	}
 
-	return allSprints
+	return allSprints, nil
+}
+
+// Returns the only active sprint of the project. The errors of a
+// team-managed board explain that its sprints may be turned off.
+func getActiveSprint(project string, boardID int) (*jira.Sprint, error) {
+	sprint, err := findActiveSprint(project, boardID)
+	if err == nil && sprint == nil {
+		err = noActiveSprintError(project, boardID)
+	}
+	return sprint, err
+}
+
+// getCurrentSprint returns the sprint of the project to report on. On boards
+// whose sprints weren't started in Jira, it falls back to the future sprint
+// whose dates contain now. The commands changing the sprints need a started
+// one and use getActiveSprint.
+func getCurrentSprint(project string, boardID int) (*jira.Sprint, error) {
+	sprint, err := findActiveSprint(project, boardID)
+	if err != nil || sprint != nil {
+		return sprint, err
+	}
+
+	// A closed sprint was transitioned, so it is not a candidate.
+	sprints, err := getSprints(boardID, jira.GetAllSprintsOptions{
+		State: "future",
+	})
+	if err != nil {
+		return nil, explainSprintError(boardID, err)
+	}
+	if sprint := currentSprintByDate(sprints, project, nowFunc()); sprint != nil {
+		logger.Warn("no active sprint, using the sprint whose dates contain now",
+			"project", project, "board_id", boardID, "sprint_id", sprint.ID, "sprint", sprint.Name)
+		return sprint, nil
+	}
+	return nil, noActiveSprintError(project, boardID)
 }
 
-// Returns the only active sprint
-func getActiveSprint(boardID int) jira.Sprint {
-	sprints := getSprints(boardID, jira.GetAllSprintsOptions{
+// findActiveSprint returns the active sprint of the project, or nil if there
+// is none.
+func findActiveSprint(project string, boardID int) (*jira.Sprint, error) {
+	sprints, err := getSprints(boardID, jira.GetAllSprintsOptions{
 		State: "active",
 	})
-	for _, sprint := range sprints {
-		if strings.Contains(sprint.Name, config.Jira.Project) {
+	if err != nil {
+		return nil, explainSprintError(boardID, err)
+	}
+	for idx, sprint := range sprints {
+		if sprintBelongsToProject(sprint, project) {
 			// Only care about current project's sprints.
-			return sprint
+			return &sprints[idx], nil
+		}
+	}
+	return nil, nil
+}
+
+func noActiveSprintError(project string, boardID int) error {
+	return explainSprintError(boardID, fmt.Errorf("no active sprint found for project %q on board %d", project, boardID))
+}
+
+// currentSprintByDate returns the sprint of the project whose start and
+// end contain now, the one which started last if they overlap, or nil if
+// there is none.
+func currentSprintByDate(sprints []jira.Sprint, project string, now time.Time) *jira.Sprint {
+	var current *jira.Sprint
+	for idx, sprint := range sprints {
+		if !sprintBelongsToProject(sprint, project) || sprint.StartDate == nil || sprint.EndDate == nil {
+			continue
+		}
+		if now.Before(*sprint.StartDate) || !now.Before(*sprint.EndDate) {
+			continue
+		}
+		if current == nil || sprint.StartDate.After(*current.StartDate) {
+			current = &sprints[idx]
+		}
+	}
+	return current
+}
+
+// sprintBelongsToProject reports whether the sprint name contains the
+// project as a whole word, so that "API" matches "API-3" and "[API] 12"
+// but not "RAPID-3". With jira sprint-match the name must match it instead.
+func sprintBelongsToProject(sprint jira.Sprint, project string) bool {
+	if len(project) == 0 {
+		return false
+	}
+	if len(config.Jira.SprintMatch) > 0 {
+		// Validated with the config.
+		if pattern, err := compileSprintMatch(config.Jira.SprintMatch); err == nil {
+			return sprintMatchesProject(pattern, sprint.Name, project)
+		}
+	}
+
+	name := sprint.Name
+	for pos := 0; pos < len(name); {
+		idx := strings.Index(name[pos:], project)
+		if idx < 0 {
+			return false
+		}
+		start, end := pos+idx, pos+idx+len(project)
+
+		before, _ := utf8.DecodeLastRuneInString(name[:start])
+		after, _ := utf8.DecodeRuneInString(name[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		pos = start + 1
+	}
+	return false
+}
+
+// sprintMatchCache remembers the compiled jira sprint-match patterns, the
+// sprint lookups check every sprint on the board against it.
+var sprintMatchCache = struct {
+	sync.Mutex
+	patterns map[string]*regexp.Regexp
+}{patterns: make(map[string]*regexp.Regexp)}
+
+func compileSprintMatch(expr string) (*regexp.Regexp, error) {
+	sprintMatchCache.Lock()
+	defer sprintMatchCache.Unlock()
+	if pattern, ok := sprintMatchCache.patterns[expr]; ok {
+		return pattern, nil
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	sprintMatchCache.patterns[expr] = pattern
+	return pattern, nil
+}
+
+// sprintMatchesProject reports whether the name matches the pattern, with
+// the project in the group named project if the pattern has one.
+func sprintMatchesProject(pattern *regexp.Regexp, name string, project string) bool {
+	group := pattern.SubexpIndex("project")
+	for _, match := range pattern.FindAllStringSubmatch(name, -1) {
+		if group < 0 || match[group] == project {
+			return true
 		}
 	}
-	return sprints[0]
+	return false
 }
 
-func getLatestPassedSprint(sprints []jira.Sprint) *jira.Sprint {
-	now := time.Now()
-	minDiff := time.Hour * 7 * 24
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func getLatestPassedSprint(project string, sprints []jira.Sprint) *jira.Sprint {
+	now := nowFunc()
+	minDiff := sprintDuration()
 	var minSprint *jira.Sprint
 	for idx, sprint := range sprints {
-		if !strings.Contains(sprint.Name, config.Jira.Project) {
+		if !sprintBelongsToProject(sprint, project) {
 			// Only care about current project's sprints.
 			continue
 		}
+		if sprint.StartDate == nil || sprint.EndDate == nil {
+			// Future sprints often have no dates yet.
+			continue
+		}
 		// 1. Sprint Start Date < Now
 		// 2. Sprint End Date < Now
 		// 3. Min(Now - Sprint End Date)
@@ -90,6 +648,7 @@ func getLatestPassedSprint(sprints []jira.Sprint) *jira.Sprint {
 		}
 		diff := now.Sub(*sprint.EndDate)
 		if diff < minDiff {
+			minDiff = diff
 			minSprint = &sprints[idx]
 		}
 	}
@@ -97,15 +656,18 @@ func getLatestPassedSprint(sprints []jira.Sprint) *jira.Sprint {
 	return minSprint
 }
 
-func getNearestFutureSprint(sprints []jira.Sprint) *jira.Sprint {
-	now := time.Now()
-	minDiff := time.Hour * 7 * 24
+func getNearestFutureSprint(project string, sprints []jira.Sprint) *jira.Sprint {
+	now := nowFunc()
+	minDiff := sprintDuration()
 	var minSprint *jira.Sprint
 	for idx, sprint := range sprints {
-		if !strings.Contains(sprint.Name, config.Jira.Project) {
+		if !sprintBelongsToProject(sprint, project) {
 			// Only care about current project's sprints.
 			continue
 		}
+		if sprint.StartDate == nil || sprint.EndDate == nil {
+			continue
+		}
 		// 1. Sprint End Date > Now
 		// 2. Min(Sprint Start Date - Now)
 		if sprint.EndDate.Before(now) {
@@ -113,6 +675,7 @@ func getNearestFutureSprint(sprints []jira.Sprint) *jira.Sprint {
 		}
 		diff := (*sprint.StartDate).Sub(now)
 		if diff < minDiff {
+			minDiff = diff
 			minSprint = &sprints[idx]
 		}
 	}
@@ -120,109 +683,801 @@ func getNearestFutureSprint(sprints []jira.Sprint) *jira.Sprint {
 	return minSprint
 }
 
-func createSprint(boardID int, name string, startDate, endDate string) jira.Sprint {
+// Sprints are at most four weeks long, or sprint-duration if it's longer.
+const maxSprintLength = 28 * 24 * time.Hour
+
+// checkSprintDates checks that the sprint ends after it starts and is not
+// longer than maxSprintLength.
+func checkSprintDates(start, end time.Time) error {
+	if !end.After(start) {
+		return fmt.Errorf("sprint end %s must be after its start %s", end.Format(dateFormat), start.Format(dateFormat))
+	}
+	limit := maxSprintLength
+	if d := sprintDuration(); d > limit {
+		limit = d
+	}
+	if end.Sub(start) > limit {
+		return fmt.Errorf("sprint of %s from %s to %s is longer than %s", formatCycleTime(end.Sub(start)),
+			start.Format(dateFormat), end.Format(dateFormat), formatCycleTime(limit))
+	}
+	return nil
+}
+
+// createSprint creates the future sprint on the board, unless the board
+// has a future sprint of the name already, which is returned instead. A
+// retry after a lost response returns the sprint the first attempt created.
+func createSprint(boardID int, name, goal string, startDate, endDate string) (jira.Sprint, error) {
+	existing, err := findFutureSprint(boardID, name)
+	if err != nil {
+		return jira.Sprint{}, err
+	}
+	if existing != nil {
+		logger.Info("sprint exists", "sprint_id", existing.ID, "name", name)
+		return *existing, nil
+	}
+
 	apiEndpoint := "rest/agile/1.0/sprint"
-	sprint := map[string]string{
+	sprint := map[string]interface{}{
 		"name":          name,
 		"startDate":     startDate,
 		"endDate":       endDate,
 		"originBoardId": strconv.Itoa(boardID),
 	}
-	req, err := jiraClient.NewRequest("POST", apiEndpoint, sprint)
-	perror(err)
+	if len(goal) > 0 {
+		sprint["goal"] = goal
+	}
+	if isJiraServer() {
+		// Jira Server/Data Center rejects the board ID as a string.
+		sprint["originBoardId"] = boardID
+	}
+	req, err := newJiraRequest(globalCtx, "POST", apiEndpoint, sprint)
+	if err != nil {
+		return jira.Sprint{}, err
+	}
+
+	decoded := new(jiraSprint)
+	_, err = doWithRetryCheck(req, decoded, func() (applied bool, err error) {
+		existing, err = findFutureSprint(boardID, name)
+		return existing != nil, err
+	})
+	if err != nil {
+		return jira.Sprint{}, err
+	}
+	if existing != nil {
+		logger.Info("sprint created by an earlier attempt", "sprint_id", existing.ID, "name", name)
+		metrics.addSprintsCreated(1)
+		runStatus.addSprintCreated(boardID, *existing)
+		return *existing, nil
+	}
+	responseSprint := decoded.sprint()
+
+	if config.DryRun {
+		// Nothing is created, return what would be.
+		responseSprint.Name = name
+		responseSprint.State = "future"
+		responseSprint.OriginBoardID = boardID
+	} else {
+		metrics.addSprintsCreated(1)
+		runStatus.addSprintCreated(boardID, responseSprint)
+	}
+
+	logger.Info("sprint created", "sprint_id", responseSprint.ID, "name", name,
+		"board_id", boardID, "endpoint", apiEndpoint)
+	return responseSprint, nil
+}
+
+const defaultSprintNameTemplate = `{{.Project}} {{.Start.Format "2006-01-02"}} - {{.End.Format "2006-01-02"}}`
+
+// sprintNameData is the data passed to the sprint name template.
+type sprintNameData struct {
+	Project string
+	// Start is the start time of the sprint.
+	Start time.Time
+	// End is the last day of the sprint, at midnight.
+	End time.Time
+	// Index is the 1-based number of the sprint on its board.
+	Index int
+	// Quarter is the quarter of the start, 1 to 4.
+	Quarter int
+	// Name is the rendered sprint name, it is only set for the goal.
+	Name string
+}
+
+func renderSprintName(data sprintNameData) (string, error) {
+	text := config.Jira.SprintNameTemplate
+	if len(text) == 0 {
+		text = defaultSprintNameTemplate
+	}
+	return renderSprintTemplate("name", text, data)
+}
+
+// renderSprintGoal renders the goal template, the goal is empty without one.
+func renderSprintGoal(data sprintNameData) (string, error) {
+	if len(config.Jira.SprintGoalTemplate) == 0 {
+		return "", nil
+	}
+	return renderSprintTemplate("goal", config.Jira.SprintGoalTemplate, data)
+}
+
+func renderSprintTemplate(what, text string, data sprintNameData) (string, error) {
+	tmpl, err := template.New("sprint-" + what).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid sprint %s template: %v", what, err)
+	}
+
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render sprint %s: %v", what, err)
+	}
+	return buf.String(), nil
+}
+
+func createNextSprint(project string, boardID int, startDate time.Time) (jira.Sprint, error) {
+	plan, err := planNextSprint(project, boardID, startDate)
+	if err != nil {
+		return jira.Sprint{}, err
+	}
+	if plan.existing != nil {
+		return *plan.existing, nil
+	}
+
+	if err = checkSprintOverlap(plan.sprints, project, plan.start, plan.end); err != nil {
+		return jira.Sprint{}, fmt.Errorf("can't create sprint %s: %v", plan.data.Name, err)
+	}
 
-	responseSprint := new(jira.Sprint)
-	_, err = jiraClient.Do(req, responseSprint)
-	perror(err)
+	goal, err := renderSprintGoal(plan.data)
+	if err != nil {
+		return jira.Sprint{}, err
+	}
 
-	return *responseSprint
+	return createSprint(boardID, plan.data.Name, goal, plan.start.Format(dateFormat), plan.end.Format(dateFormat))
 }
 
-func createNextSprint(boardID int, startDate time.Time) jira.Sprint {
+// nextSprintPlan is the sprint createNextSprint creates.
+type nextSprintPlan struct {
+	data       sprintNameData
+	start, end time.Time
+	// sprints are all the sprints of the board.
+	sprints []jira.Sprint
+	// existing is the future sprint with the name, if it was created
+	// already.
+	existing *jira.Sprint
+}
+
+func planNextSprint(project string, boardID int, startDate time.Time) (nextSprintPlan, error) {
 	// We assuem the sprint starts at 00:00 and ends at 00:00
-	// E.g, current sprint time range is 2018-09-28T00:00:00+08:00 2018-10-05T00:00:00+08:00
+	// E.g, with the default one week duration, current sprint time range is 2018-09-28T00:00:00+08:00 2018-10-05T00:00:00+08:00
 	// So the next sprint is 2018-10-05T00:00:00+08:00, 2018-10-12T00:00:00+08:00
 	// The sprint name is 2018-10-05 - 2018-10-11
-	endDate := startDate.Add(sprintDuration)
+	startDate, err := alignSprintStart(startDate)
+	if err != nil {
+		return nextSprintPlan{}, err
+	}
+	endDate := sprintEnd(startDate)
 
-	name := fmt.Sprintf("%s %s - %s", config.Jira.Project, startDate.Format(dayFormat), endDate.Add(-time.Second).Format(dayFormat))
+	sprints, err := getSprints(boardID, jira.GetAllSprintsOptions{})
+	if err != nil {
+		return nextSprintPlan{}, err
+	}
 
-	sprints := getSprints(boardID, jira.GetAllSprintsOptions{
-		State: "future",
-	})
+	// The index counts the sprints started before this one, so it stays
+	// the same if the sprint has been created already.
+	index := 1
 	for _, sprint := range sprints {
-		if sprint.Name == name {
-			return sprint
+		if sprint.StartDate != nil && sprint.StartDate.Before(startDate) {
+			index++
 		}
 	}
 
-	return createSprint(boardID, name, startDate.Format(dateFormat), endDate.Format(dateFormat))
+	data := sprintNameData{
+		Project: project,
+		Start:   startDate,
+		End:     sprintLastDay(endDate),
+		Index:   index,
+		Quarter: (int(startDate.Month())-1)/3 + 1,
+	}
+	name, err := renderSprintName(data)
+	if err != nil {
+		return nextSprintPlan{}, err
+	}
+	data.Name = name
+
+	plan := nextSprintPlan{data: data, start: startDate, end: endDate, sprints: sprints}
+	for i, sprint := range sprints {
+		if sprint.State == "future" && sameSprintName(sprint.Name, name) {
+			plan.existing = &sprints[i]
+			break
+		}
+	}
+	return plan, nil
 }
 
-func deleteSprint(sprintID int) {
+// findFutureSprint returns the future sprint of the board with the name, or
+// nil if there is none.
+func findFutureSprint(boardID int, name string) (*jira.Sprint, error) {
+	sprints, err := getSprintsInStates(boardID, "future")
+	if err != nil {
+		return nil, err
+	}
+	for i := range sprints {
+		if sameSprintName(sprints[i].Name, name) {
+			return &sprints[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// sameSprintName compares sprint names ignoring surrounding and repeated
+// whitespace, which Jira or a manual edit may add, and the case if jira
+// sprint-name-ignore-case is set.
+func sameSprintName(a, b string) bool {
+	a, b = normalizeSprintName(a), normalizeSprintName(b)
+	if config.Jira.SprintNameIgnoreCase {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+func normalizeSprintName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// validateSprintDates checks that [start, end) does not overlap any active
+// or future sprint of the project on the board, which happens when a sprint
+// was extended by hand.
+func validateSprintDates(project string, boardID int, start, end time.Time) error {
+	sprints, err := getSprints(boardID, jira.GetAllSprintsOptions{})
+	if err != nil {
+		return err
+	}
+
+	return checkSprintOverlap(sprints, project, start, end)
+}
+
+// checkSprintOverlap checks the sprints of the project, the sprints of the
+// other projects sharing the board run in parallel.
+func checkSprintOverlap(sprints []jira.Sprint, project string, start, end time.Time) error {
+	for _, sprint := range sprints {
+		if sprint.State != "active" && sprint.State != "future" {
+			continue
+		}
+		if !sprintBelongsToProject(sprint, project) {
+			continue
+		}
+		if sprint.StartDate == nil || sprint.EndDate == nil {
+			continue
+		}
+
+		if sprint.StartDate.Before(end) && start.Before(*sprint.EndDate) {
+			return fmt.Errorf("%s - %s overlaps %s sprint %s (%s - %s)",
+				start.Format(dateFormat), end.Format(dateFormat), sprint.State, sprint.Name,
+				sprint.StartDate.Format(dateFormat), sprint.EndDate.Format(dateFormat))
+		}
+	}
+
+	return nil
+}
+
+// createFutureSprints creates the next count sprints starting at
+// startDate, one after another. The sprints which exist already are reused,
+// all of them are returned in chronological order.
+func createFutureSprints(project string, boardID int, startDate time.Time, count int) ([]jira.Sprint, error) {
+	startDate, err := alignSprintStart(startDate)
+	if err != nil {
+		return nil, err
+	}
+
+	sprints := make([]jira.Sprint, 0, count)
+	for i := 0; i < count; i++ {
+		sprint, err := createNextSprint(project, boardID, startDate)
+		if err != nil {
+			return sprints, err
+		}
+		sprints = append(sprints, sprint)
+		startDate = sprintEnd(startDate)
+	}
+
+	return sprints, nil
+}
+
+// pruneEmptyFutureSprints deletes the future sprints of the project on the
+// board which have no issues, like the ones left behind by aborted rollovers. A sprint
+// is only deleted once its start date is older than jira prune-after, so
+// a sprint nobody has planned yet is kept. Sprints without dates are kept
+// too, and so are the sprints of the other projects sharing the board. It
+// returns the IDs of the deleted sprints.
+func pruneEmptyFutureSprints(project string, boardID int) ([]int, error) {
+	sprints, err := getSprints(boardID, jira.GetAllSprintsOptions{State: "future"})
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := config.Jira.PruneAfter.Duration
+	if threshold <= 0 {
+		threshold = defaultPruneAfter
+	}
+	cutoff := nowFunc().Add(-threshold)
+
+	var deleted []int
+	for _, sprint := range sprints {
+		// Don't trust the state filter blindly, deleting an active or
+		// closed sprint loses its history.
+		if sprint.State != "future" || sprint.StartDate == nil || !sprint.StartDate.Before(cutoff) {
+			continue
+		}
+		if !sprintBelongsToProject(sprint, project) {
+			continue
+		}
+
+		count, err := countJiraIssues(NewJQL().Sprint(sprint.ID).String())
+		if err != nil {
+			return deleted, err
+		}
+		if count > 0 {
+			continue
+		}
+
+		if err = deleteSprint(sprint.ID); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, sprint.ID)
+	}
+
+	return deleted, nil
+}
+
+func deleteSprint(sprintID int) error {
 	apiEndpoint := "rest/agile/1.0/sprint/" + strconv.Itoa(sprintID)
-	req, err := jiraClient.NewRequest("DELETE", apiEndpoint, nil)
-	perror(err)
+	req, err := newJiraRequest(globalCtx, "DELETE", apiEndpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err = doWithRetry(req, nil); err != nil {
+		return err
+	}
 
-	_, err = jiraClient.Do(req, nil)
-	perror(err)
+	logger.Info("sprint deleted", "sprint_id", sprintID, "endpoint", apiEndpoint)
+	return nil
 }
 
-func updateSprintTime(sprintID int, startDate, endDate string) jira.Sprint {
+func updateSprintTime(sprintID int, startDate, endDate string) (jira.Sprint, error) {
 	return updateSprint(sprintID, map[string]string{
 		"startDate": startDate,
 		"endDate":   endDate,
 	})
 }
 
-func updateSprintState(sprintID int, state string) jira.Sprint {
-	return updateSprint(sprintID, map[string]string{
+// sprintTransitions are the state changes Jira allows: a sprint is started
+// once and closed once, a closed sprint can't be reopened.
+var sprintTransitions = map[string]string{
+	"future": "active",
+	"active": "closed",
+}
+
+// checkSprintTransition fails for an unknown state or a change from the
+// state of the sprint Jira would refuse with an opaque 400.
+func checkSprintTransition(sprint jira.Sprint, state string) error {
+	if !containsState(sprintStates, state) {
+		return fmt.Errorf("unknown sprint state %q, expect %s", state, strings.Join(sprintStates, ", "))
+	}
+	if sprintTransitions[sprint.State] != state {
+		return fmt.Errorf("can't change sprint %s (%d) from %s to %s", sprint.Name, sprint.ID, sprint.State, state)
+	}
+	return nil
+}
+
+// updateSprintState changes the state of the sprint, which has the current
+// state of the sprint to check the change.
+func updateSprintState(sprint jira.Sprint, state string) (jira.Sprint, error) {
+	if err := checkSprintTransition(sprint, state); err != nil {
+		return jira.Sprint{}, err
+	}
+	return updateSprint(sprint.ID, map[string]string{
 		"state": state,
 	})
 }
 
-func updateSprint(sprintID int, args map[string]string) jira.Sprint {
+// getSprintByID returns the sprint with the ID.
+func getSprintByID(sprintID int) (jira.Sprint, error) {
 	apiEndpoint := "rest/agile/1.0/sprint/" + strconv.Itoa(sprintID)
+	req, err := newJiraRequest(globalCtx, "GET", apiEndpoint, nil)
+	if err != nil {
+		return jira.Sprint{}, err
+	}
+
+	sprint := new(jiraSprint)
+	if _, err = doWithRetry(req, sprint); err != nil {
+		return jira.Sprint{}, err
+	}
+	return sprint.sprint(), nil
+}
+
+// getIssue returns the issue with the key or ID.
+func getIssue(key string) (jira.Issue, error) {
+	req, err := newJiraRequest(globalCtx, "GET", "rest/api/2/issue/"+url.PathEscape(key), nil)
+	if err != nil {
+		return jira.Issue{}, err
+	}
 
-	req, err := jiraClient.NewRequest("POST", apiEndpoint, args)
-	perror(err)
+	issue := new(jira.Issue)
+	if _, err = doWithRetry(req, issue); err != nil {
+		return jira.Issue{}, err
+	}
+	return *issue, nil
+}
 
-	responseSprint := new(jira.Sprint)
-	_, err = jiraClient.Do(req, responseSprint)
-	perror(err)
+// activateSprint starts the future sprint. Jira allows only one active
+// sprint per board, so it fails with the conflicting sprint instead of the
+// opaque 400 Jira would return.
+func activateSprint(sprintID int) error {
+	sprint, err := getSprintByID(sprintID)
+	if err != nil {
+		return err
+	}
+	if err = checkSprintTransition(sprint, "active"); err != nil {
+		return err
+	}
 
-	return *responseSprint
+	if sprint.OriginBoardID > 0 {
+		active, err := getSprints(sprint.OriginBoardID, jira.GetAllSprintsOptions{State: "active"})
+		if err != nil {
+			return err
+		}
+		for _, other := range active {
+			if other.ID != sprint.ID {
+				return fmt.Errorf("can't activate sprint %s, sprint %s is already active on board %d",
+					sprint.Name, other.Name, sprint.OriginBoardID)
+			}
+		}
+	}
+
+	_, err = updateSprintState(sprint, "active")
+	return err
+}
+
+func updateSprint(sprintID int, args map[string]string) (jira.Sprint, error) {
+	apiEndpoint := "rest/agile/1.0/sprint/" + strconv.Itoa(sprintID)
+
+	req, err := newJiraRequest(globalCtx, "POST", apiEndpoint, args)
+	if err != nil {
+		return jira.Sprint{}, err
+	}
+
+	decoded := new(jiraSprint)
+	if _, err = doWithRetry(req, decoded); err != nil {
+		return jira.Sprint{}, err
+	}
+	responseSprint := decoded.sprint()
+
+	if config.DryRun {
+		responseSprint.ID = sprintID
+		responseSprint.State = args["state"]
+	}
+
+	logger.Info("sprint updated", "sprint_id", sprintID, "state", responseSprint.State, "endpoint", apiEndpoint)
+	return responseSprint, nil
+}
+
+// BatchError describes a batch of issues which failed to move.
+type BatchError struct {
+	IssueIDs []string
+	Err      error
+}
+
+func (e BatchError) Error() string {
+	return fmt.Sprintf("move issues %s: %v", strings.Join(e.IssueIDs, ","), e.Err)
 }
 
 // A pagination-aware alternative for SprintService.MoveIssuesToSprint.
+// A failed batch doesn't stop the remaining ones, all failures are returned
+// so that the caller can retry just those. The issues in the sprint already
+// are skipped, Jira rejects the whole batch for them, e.g. when a rollover
+// runs again.
 //
 // https://developer.atlassian.com/cloud/jira/software/rest/#api-rest-agile-1-0-sprint-sprintId-issue-post
-func moveIssuesToSprint(sprintID int, issues []jira.Issue) {
+func moveIssuesToSprint(sprintID int, issues []jira.Issue) []BatchError {
 	apiEndpoint := fmt.Sprintf("rest/agile/1.0/sprint/%d/issue", sprintID)
 
-	// The maximum number of issues that can be moved in one operation is 50.
-	batchMax := 50
-	buffer := make([]string, 0, batchMax)
-	total := len(issues)
-	for idx, ise := range issues {
-		buffer = append(buffer, ise.ID)
-		if len(buffer) == batchMax || idx+1 == total {
-			payload := jira.IssuesWrapper{Issues: buffer}
-			req, err := jiraClient.NewRequest("POST", apiEndpoint, payload)
-			perror(err)
-			_, err = jiraClient.Do(req, nil)
-			perror(err)
+	if len(issues) > 0 {
+		issues = skipIssuesInSprint(sprintID, issues)
+	}
+
+	var failures []BatchError
+	batches := 0
+
+	batchMax := config.Jira.MoveBatchSize
+	if batchMax <= 0 {
+		batchMax = maxMoveBatchSize
+	}
+	for start := 0; start < len(issues); start += batchMax {
+		end := start + batchMax
+		if end > len(issues) {
+			end = len(issues)
+		}
+
+		batch := make([]string, 0, end-start)
+		for _, ise := range issues[start:end] {
+			batch = append(batch, ise.ID)
+		}
+
+		batches++
+		payload := jira.IssuesWrapper{Issues: batch}
+		req, err := newJiraRequest(globalCtx, "POST", apiEndpoint, payload)
+		if err == nil {
+			_, err = doWithRetry(req, nil)
+		}
+		if err != nil {
+			logger.Warn("moving issues failed", "sprint_id", sprintID, "issues", len(batch), "endpoint", apiEndpoint, "error", err)
+			failures = append(failures, BatchError{IssueIDs: batch, Err: err})
+		} else if !config.DryRun {
+			metrics.addIssuesMoved(len(batch))
+			runStatus.addIssuesMoved(sprintID, len(batch))
+		}
+	}
+
+	logger.Info("issues moved", "sprint_id", sprintID, "issues", len(issues), "batches", batches,
+		"failed_batches", len(failures), "endpoint", apiEndpoint)
+	return failures
+}
+
+// skipIssuesInSprint returns the issues which are not in the sprint yet. If
+// the sprint can't be listed all issues are returned, to be moved as before.
+func skipIssuesInSprint(sprintID int, issues []jira.Issue) []jira.Issue {
+	present, err := queryJiraIssuesWithOptions(NewJQL().Sprint(sprintID).String(), &jira.SearchOptions{Fields: []string{"key"}})
+	if err != nil {
+		logger.Warn("listing the issues of the target sprint failed", "sprint_id", sprintID, "error", err)
+		return issues
+	}
+	if len(present) == 0 {
+		return issues
+	}
+
+	inSprint := make(map[string]bool, len(present))
+	for _, issue := range present {
+		inSprint[issue.ID] = true
+	}
+	pending := make([]jira.Issue, 0, len(issues))
+	for _, issue := range issues {
+		if !inSprint[issue.ID] {
+			pending = append(pending, issue)
+		}
+	}
+	if skipped := len(issues) - len(pending); skipped > 0 {
+		logger.Info("issues already in the sprint skipped", "sprint_id", sprintID, "issues", skipped)
+	}
+	return pending
+}
+
+// hasLabel tells if the issue has the label already.
+func hasLabel(issue jira.Issue, label string) bool {
+	if issue.Fields == nil {
+		return false
+	}
+	for _, l := range issue.Fields.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// addIssueLabel adds the label to the issues which don't have it yet, it
+// returns how many issues were labeled. Jira has no bulk edit in this API,
+// so every issue is one edit request; the "add" operation keeps the other
+// labels and never duplicates the label.
+func addIssueLabel(issues []jira.Issue, label string) (int, []BatchError) {
+	payload := map[string]interface{}{
+		"update": map[string]interface{}{
+			"labels": []map[string]string{{"add": label}},
+		},
+	}
+
+	labeled := 0
+	var failures []BatchError
+	for _, issue := range issues {
+		if hasLabel(issue, label) {
+			continue
+		}
+		req, err := newJiraRequest(globalCtx, "PUT", "rest/api/2/issue/"+issue.ID, payload)
+		if err == nil {
+			_, err = doWithRetry(req, nil)
+		}
+		if err != nil {
+			failures = append(failures, BatchError{IssueIDs: []string{issue.ID}, Err: err})
+			continue
+		}
+		labeled++
+	}
+
+	logger.Info("issues labeled", "label", label, "issues", labeled, "failed", len(failures))
+	return labeled, failures
+}
+
+// issuesInSprint returns all issues of the sprint.
+func issuesInSprint(sprintID int) ([]jira.Issue, error) {
+	return queryJiraIssues(NewJQL().Sprint(sprintID).String())
+}
+
+// incompleteIssuesInSprint returns the issues of the sprint which are not
+// done, see NotDone.
+func incompleteIssuesInSprint(sprintID int) ([]jira.Issue, error) {
+	return queryJiraIssues(NewJQL().Sprint(sprintID).NotDone(config.Jira.DoneStatuses...).String())
+}
+
+func queryJiraIssues(jql string) ([]jira.Issue, error) {
+	return queryJiraIssuesCtx(globalCtx, jql)
+}
+
+func queryJiraIssuesCtx(ctx context.Context, jql string) ([]jira.Issue, error) {
+	return queryJiraIssuesWithOptionsCtx(ctx, jql, nil)
+}
+
+// queryJiraIssuesWithOptions is like queryJiraIssues, but lets the caller
+// limit the returned fields and expand sections, which makes the query much
+// cheaper on large boards. MaxResults is used as the page size.
+func queryJiraIssuesWithOptions(jql string, opts *jira.SearchOptions) ([]jira.Issue, error) {
+	return queryJiraIssuesWithOptionsCtx(globalCtx, jql, opts)
+}
+
+func queryJiraIssuesWithOptionsCtx(ctx context.Context, jql string, opts *jira.SearchOptions) ([]jira.Issue, error) {
+	var allIssues []jira.Issue
+
+	if opts == nil {
+		opts = &jira.SearchOptions{}
+	}
+	pageSize := opts.MaxResults
+	if pageSize <= 0 {
+		pageSize = 1000
+	}
+
+	query := url.Values{}
+	query.Set("jql", jql)
+	query.Set("maxResults", strconv.Itoa(pageSize))
+	if len(opts.Fields) > 0 {
+		query.Set("fields", strings.Join(opts.Fields, ","))
+	}
+	if len(opts.Expand) > 0 {
+		query.Set("expand", opts.Expand)
+	}
+	if len(opts.ValidateQuery) > 0 {
+		query.Set("validateQuery", opts.ValidateQuery)
+	}
+
+	pos := opts.StartAt
+	for {
+		query.Set("startAt", strconv.Itoa(pos))
+		apiEndpoint := "rest/api/2/search?" + query.Encode()
+		req, err := newJiraRequest(ctx, "GET", apiEndpoint, nil)
+		if err != nil {
+			return nil, err
+		}
 
-			// clear buffer
-			buffer = buffer[:0]
+		result := new(searchResult)
+		if _, err = doWithRetry(req, result); err != nil {
+			return nil, err
+		}
+		allIssues = append(allIssues, result.Issues...)
+
+		// The server may cap MaxResults, so keep going until we get
+		// everything it reported or an empty page comes back.
+		pos += len(result.Issues)
+		if len(result.Issues) == 0 || pos >= result.Total {
+			break
 		}
 	}
+
+	return allIssues, nil
+}
+
+// doneStatuses returns the statuses an issue is moved to when it is
+// completed, Done and the configured ones.
+func doneStatuses() []string {
+	statuses := []string{"Done"}
+	for _, status := range config.Jira.DoneStatuses {
+		if !strings.EqualFold(status, "Done") {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
 }
 
-func queryJiraIssues(jql string) []jira.Issue {
-	issues, _, err := jiraClient.Issue.Search(jql, &jira.SearchOptions{
-		MaxResults: 1000,
+// completedIssues returns the issues of the project moved to a done status in
+// [from, to), sorted by resolution date. The dates are converted to the
+// configured timezone before they are put in the query.
+func completedIssues(project string, from, to time.Time) ([]jira.Issue, error) {
+	loc, err := sprintLocation(time.Local)
+	if err != nil {
+		return nil, err
+	}
+
+	jql := NewJQL().
+		Project(project).
+		StatusChangedToAnyDuring(doneStatuses(), from.In(loc).Format(jqlDateFormat), to.In(loc).Format(jqlDateFormat)).
+		String()
+	issues, err := queryJiraIssues(jql)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return resolutionDate(issues[i]).Before(resolutionDate(issues[j]))
 	})
-	perror(err)
-	return issues
+	return issues, nil
+}
+
+func resolutionDate(issue jira.Issue) time.Time {
+	if issue.Fields == nil {
+		return time.Time{}
+	}
+	return time.Time(issue.Fields.Resolutiondate)
+}
+
+// countJiraIssues returns the number of issues matching jql without
+// fetching them.
+func countJiraIssues(jql string) (int, error) {
+	query := url.Values{}
+	query.Set("jql", jql)
+	query.Set("maxResults", "0")
+	req, err := newJiraRequest(globalCtx, "GET", "rest/api/2/search?"+query.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	result := new(searchResult)
+	if _, err = doWithRetry(req, result); err != nil {
+		return 0, err
+	}
+	return result.Total, nil
+}
+
+// searchResult is the page returned by the issue search endpoint.
+type searchResult struct {
+	Issues     []jira.Issue `json:"issues"`
+	StartAt    int          `json:"startAt"`
+	MaxResults int          `json:"maxResults"`
+	Total      int          `json:"total"`
+}
+
+// jiraAPI is the part of the go-jira client used to talk to Jira. All the
+// requests go through NewRequest and Do, so that they are paginated,
+// retried and throttled the same way. The client is not passed around, it is
+// the package global jiraClient set by initJiraClient, which tests swap for
+// a fake.
+type jiraAPI interface {
+	NewRequest(method, urlStr string, body interface{}) (*http.Request, error)
+	Do(req *http.Request, v interface{}) (*jira.Response, error)
+}
+
+// newJiraRequest creates a Jira API request bound to ctx, so it is
+// cancelled with the run.
+func newJiraRequest(ctx context.Context, method, urlStr string, body interface{}) (*http.Request, error) {
+	if jiraClient == nil {
+		return nil, fmt.Errorf("jira client is not initialized")
+	}
+	req, err := jiraClient.NewRequest(method, urlStr, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return req.WithContext(ctx), nil
+}
+
+// skipInDryRun prints a mutating request instead of letting it through
+// in dry-run mode. Read requests are always sent so that the output still
+// reflects the real state.
+func skipInDryRun(req *http.Request) bool {
+	if !config.DryRun || req.Method == "GET" {
+		return false
+	}
+
+	var body []byte
+	if req.GetBody != nil {
+		if r, err := req.GetBody(); err == nil {
+			body, _ = ioutil.ReadAll(r)
+			r.Close()
+		}
+	}
+	fmt.Printf("[dry-run] %s %s %s\n", req.Method, req.URL, bytes.TrimSpace(body))
+	return true
 }
@@ -0,0 +1,1346 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// newTestJiraServer points jiraClient at a local server driven by handler,
+// resets config and globalCtx and returns a function that restores them.
+func newTestJiraServer(t *testing.T, handler http.HandlerFunc) func() {
+	server := httptest.NewServer(handler)
+
+	client, err := jira.NewClient(server.Client(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldClient, oldConfig, oldCtx := jiraClient, config, globalCtx
+	jiraClient, config, globalCtx = client, new(Config), context.Background()
+	resetBoardIDCache()
+	resetUserCache()
+	return func() {
+		resetBoardIDCache()
+		resetUserCache()
+		jiraClient, config, globalCtx = oldClient, oldConfig, oldCtx
+		server.Close()
+	}
+}
+
+// day returns midnight UTC of the given day in 2018.
+func day(month time.Month, d int) *time.Time {
+	t := time.Date(2018, month, d, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQueryJiraIssuesPagination(t *testing.T) {
+	const pageSize = 100
+	const total = 300
+
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		var issues []jira.Issue
+		for i := startAt; i < startAt+pageSize && i < total; i++ {
+			issues = append(issues, jira.Issue{ID: strconv.Itoa(i), Key: fmt.Sprintf("TEST-%d", i)})
+		}
+		writeJSON(t, w, map[string]interface{}{
+			"startAt":    startAt,
+			"maxResults": pageSize,
+			"total":      total,
+			"issues":     issues,
+		})
+	})()
+
+	issues, err := queryJiraIssues("project = TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != total {
+		t.Fatalf("expect %d issues, got %d", total, len(issues))
+	}
+	for i, issue := range issues {
+		if issue.ID != strconv.Itoa(i) {
+			t.Fatalf("expect issue %d at position %d, got %s", i, i, issue.ID)
+		}
+	}
+}
+
+func TestGetBoardIDNoBoards(t *testing.T) {
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, jira.BoardsList{IsLast: true})
+	})()
+
+	_, err := getBoardID("NOPE", "scrum")
+	if err == nil {
+		t.Fatal("expect error for empty board list")
+	}
+	if expect := `no scrum board found for project "NOPE"`; err.Error() != expect {
+		t.Fatalf("expect %q, got %q", expect, err.Error())
+	}
+}
+
+func TestGetBoardIDConfigured(t *testing.T) {
+	var paths []string
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path != "/rest/agile/1.0/board/42" {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(t, w, jira.Board{ID: 42, Name: "TEST Scrum", Type: "scrum"})
+	})()
+	config.Jira.BoardID = 42
+
+	if err := checkBoardID(42); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := getSprintBoardID("TEST"); err != nil || id != 42 {
+		t.Fatalf("expect board 42, got %d, %v", id, err)
+	}
+	if len(paths) != 1 {
+		t.Fatalf("expect only the board check, got %v", paths)
+	}
+	if err := checkBoardID(7); err == nil || err.Error() != "jira board-id 7 not found or archived, set board-id to an existing board" {
+		t.Fatalf("expect missing board error, got %v", err)
+	}
+}
+
+func TestGetSprintBoardID(t *testing.T) {
+	var boardType string
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rest/agile/1.0/board/1" {
+			writeJSON(t, w, jira.Board{ID: 1})
+			return
+		}
+		boardType = r.URL.Query().Get("boardType")
+		writeJSON(t, w, jira.BoardsList{IsLast: true, Values: []jira.Board{{ID: 1}}})
+	})()
+
+	if id, err := getSprintBoardID("TEST"); err != nil || id != 1 || boardType != "scrum" {
+		t.Fatalf("expect scrum board 1, got %d, %q, %v", id, boardType, err)
+	}
+
+	config.Jira.BoardType = boardTypeSimple
+	if _, err := getSprintBoardID("TEST"); err != nil || boardType != "simple" {
+		t.Fatalf("expect simple board, got %q, %v", boardType, err)
+	}
+
+	config.Jira.BoardType = boardTypeKanban
+	boardType = ""
+	_, err := getSprintBoardID("TEST")
+	if err == nil || !strings.Contains(err.Error(), "kanban boards have no sprints") {
+		t.Fatalf("expect kanban error, got %v", err)
+	}
+	if len(boardType) > 0 {
+		t.Fatal("expect no board lookup for kanban")
+	}
+}
+
+func TestGetBoardIDByName(t *testing.T) {
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, jira.BoardsList{
+			IsLast: true,
+			Values: []jira.Board{
+				{ID: 1, Name: "TEST Kanban"},
+				{ID: 2, Name: "TEST Scrum"},
+			},
+		})
+	})()
+
+	id, err := getBoardIDByName("TEST", "scrum", "TEST Scrum")
+	if err != nil || id != 2 {
+		t.Fatalf("expect board 2, got %d, %v", id, err)
+	}
+	if _, err = getBoardIDByName("TEST", "scrum", "TEST"); err == nil {
+		t.Fatal("expect error for unknown board name")
+	}
+
+	config.Jira.BoardName = "TEST Scrum"
+	if id, err = getBoardID("TEST", "scrum"); err != nil || id != 2 {
+		t.Fatalf("expect board 2, got %d, %v", id, err)
+	}
+}
+
+func TestQueryJiraIssuesError(t *testing.T) {
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})()
+
+	if _, err := queryJiraIssues("project = TEST"); err == nil {
+		t.Fatal("expect error from a failed search")
+	}
+}
+
+func TestQueryJiraIssuesCtxCancelled(t *testing.T) {
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]interface{}{"total": 0})
+	})()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := queryJiraIssuesCtx(ctx, "project = TEST"); err == nil {
+		t.Fatal("expect error from a cancelled context")
+	}
+}
+
+// newTestSprintServer serves the given sprints for every board sprint
+// listing and echoes created sprints back, recording them in created.
+func newTestSprintServer(t *testing.T, sprints []jira.Sprint, created *[]map[string]string) func() {
+	return newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && r.URL.Path == "/rest/agile/1.0/sprint" {
+			var sprint map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&sprint); err != nil {
+				t.Error(err)
+			}
+			*created = append(*created, sprint)
+			writeJSON(t, w, jira.Sprint{ID: 100 + len(*created), Name: sprint["name"]})
+			return
+		}
+		writeJSON(t, w, jira.SprintsList{IsLast: true, Values: sprints})
+	})
+}
+
+func TestCreateNextSprintDuration(t *testing.T) {
+	var created []map[string]string
+	defer newTestSprintServer(t, nil, &created)()
+
+	config.Jira.SprintDuration = Duration{14 * 24 * time.Hour}
+
+	start := time.Date(2018, 10, 5, 0, 0, 0, 0, time.UTC)
+	sprint, err := createNextSprint("TEST", 1, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "TEST 2018-10-05 - 2018-10-18"; sprint.Name != expect {
+		t.Fatalf("expect sprint %q, got %q", expect, sprint.Name)
+	}
+	if expect := "2018-10-19T00:00:00Z"; created[0]["endDate"] != expect {
+		t.Fatalf("expect end date %s, got %s", expect, created[0]["endDate"])
+	}
+}
+
+func TestCreateNextSprintNameTemplate(t *testing.T) {
+	sprints := []jira.Sprint{
+		{ID: 1, Name: "Sprint 1: TEST (2018/09/21)", State: "closed", StartDate: day(9, 21), EndDate: day(9, 28)},
+		{ID: 2, Name: "Sprint 2: TEST (2018/09/28)", State: "active", StartDate: day(9, 28), EndDate: day(10, 5)},
+	}
+
+	var created []map[string]string
+	defer newTestSprintServer(t, sprints, &created)()
+
+	config.Jira.SprintNameTemplate = `Sprint {{.Index}}: {{.Project}} ({{.Start.Format "2006/01/02"}})`
+
+	sprint, err := createNextSprint("TEST", 1, *day(10, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "Sprint 3: TEST (2018/10/05)"; sprint.Name != expect || len(created) != 1 {
+		t.Fatalf("expect to create sprint %q, got %q", expect, sprint.Name)
+	}
+}
+
+func TestCreateNextSprintGoal(t *testing.T) {
+	var created []map[string]string
+	defer newTestSprintServer(t, nil, &created)()
+	config.Jira.Timezone = "UTC"
+
+	if _, err := createNextSprint("TEST", 1, *day(10, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := created[0]["goal"]; ok {
+		t.Fatalf("expect no goal without a template, got %q", created[0]["goal"])
+	}
+
+	config.Jira.SprintGoalTemplate = `{{.Name}}: Q{{.Quarter}} OKR`
+	if _, err := createNextSprint("TEST", 1, *day(10, 12)); err != nil {
+		t.Fatal(err)
+	}
+	if expect := "TEST 2018-10-12 - 2018-10-18: Q4 OKR"; created[1]["goal"] != expect {
+		t.Fatalf("expect goal %q, got %q", expect, created[1]["goal"])
+	}
+}
+
+func TestCreateNextSprintExisting(t *testing.T) {
+	start := time.Date(2018, 10, 5, 0, 0, 0, 0, time.UTC)
+	sprints := []jira.Sprint{
+		{ID: 7, Name: "Sprint 1: TEST (2018/10/05)", State: "future", StartDate: &start},
+	}
+
+	var created []map[string]string
+	defer newTestSprintServer(t, sprints, &created)()
+
+	config.Jira.SprintNameTemplate = `Sprint {{.Index}}: {{.Project}} ({{.Start.Format "2006/01/02"}})`
+
+	sprint, err := createNextSprint("TEST", 1, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sprint.ID != 7 || len(created) != 0 {
+		t.Fatalf("expect existing sprint 7, got %d and %d created", sprint.ID, len(created))
+	}
+}
+
+func TestCreateNextSprintTimezone(t *testing.T) {
+	var created []map[string]string
+	defer newTestSprintServer(t, nil, &created)()
+
+	config.Jira.Timezone = "America/New_York"
+	config.Jira.SprintStartTimeOfDay = "09:00"
+
+	// 2018-11-01 is a Thursday in EDT, DST ends on 2018-11-04.
+	start := time.Date(2018, 11, 1, 4, 0, 0, 0, time.UTC)
+	sprint, err := createNextSprint("TEST", 1, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "TEST 2018-11-01 - 2018-11-07"; sprint.Name != expect {
+		t.Fatalf("expect sprint %q, got %q", expect, sprint.Name)
+	}
+	if expect := "2018-11-01T09:00:00-04:00"; created[0]["startDate"] != expect {
+		t.Fatalf("expect start date %s, got %s", expect, created[0]["startDate"])
+	}
+	if expect := "2018-11-08T09:00:00-05:00"; created[0]["endDate"] != expect {
+		t.Fatalf("expect end date %s, got %s", expect, created[0]["endDate"])
+	}
+}
+
+func TestGetActiveSprint(t *testing.T) {
+	sprints := []jira.Sprint{
+		{ID: 1, Name: "PROJX 2018-10-05 - 2018-10-11", State: "active"},
+		{ID: 2, Name: "PROJ 2018-10-05 - 2018-10-11", State: "active"},
+	}
+	defer newTestSprintServer(t, sprints, nil)()
+
+	sprint, err := getActiveSprint("PROJ", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sprint.ID != 2 {
+		t.Fatalf("expect sprint 2, got %d", sprint.ID)
+	}
+
+	if sprint, err = getActiveSprint("OTHER", 1); err == nil || sprint != nil {
+		t.Fatalf("expect no active sprint, got %v", sprint)
+	}
+}
+
+func TestGetActiveSprintNone(t *testing.T) {
+	defer newTestSprintServer(t, nil, nil)()
+
+	if _, err := getActiveSprint("PROJ", 1); err == nil {
+		t.Fatal("expect error when there is no active sprint")
+	}
+}
+
+func TestGetCurrentSprintByDate(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	now := time.Now()
+	window := func(start, end time.Duration) (*time.Time, *time.Time) {
+		s, e := now.Add(start), now.Add(end)
+		return &s, &e
+	}
+	past, pastEnd := window(-14*24*time.Hour, -7*24*time.Hour)
+	current, currentEnd := window(-24*time.Hour, 6*24*time.Hour)
+	next, nextEnd := window(6*24*time.Hour, 13*24*time.Hour)
+	f.addSprint(jira.Sprint{ID: 1, Name: "PROJ 1", State: "closed", StartDate: current, EndDate: currentEnd})
+	f.addSprint(jira.Sprint{ID: 2, Name: "PROJ 2", State: "future", StartDate: past, EndDate: pastEnd})
+	f.addSprint(jira.Sprint{ID: 3, Name: "PROJ 3", State: "future", StartDate: current, EndDate: currentEnd})
+	f.addSprint(jira.Sprint{ID: 4, Name: "PROJ 4", State: "future", StartDate: next, EndDate: nextEnd})
+	f.addSprint(jira.Sprint{ID: 5, Name: "OTHER 5", State: "future", StartDate: current, EndDate: currentEnd})
+
+	sprint, err := getCurrentSprint("PROJ", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sprint.ID != 3 {
+		t.Fatalf("expect the future sprint containing now, got %d", sprint.ID)
+	}
+	// Only the reports fall back to a future sprint.
+	if sprint, err = getActiveSprint("PROJ", 1); err == nil {
+		t.Fatalf("expect no active sprint, got %d", sprint.ID)
+	}
+
+	f.sprint(3).State = "active"
+	f.sprint(3).Name = "OTHER 3"
+	if sprint, err = getCurrentSprint("PROJ", 1); err == nil {
+		t.Fatalf("expect no sprint without one containing now, got %d", sprint.ID)
+	}
+}
+
+func TestSprintBelongsToProject(t *testing.T) {
+	oldConfig := config
+	config = new(Config)
+	defer func() { config = oldConfig }()
+
+	cases := []struct {
+		name   string
+		expect bool
+	}{
+		{"API", true},
+		{"API 2018-10-05 - 2018-10-11", true},
+		{"API-3", true},
+		{"[API] Sprint 12", true},
+		{"Sprint 12: API (2018/10/05)", true},
+		{"RAPID-3", false},
+		{"APIX 2018-10-05 - 2018-10-11", false},
+		{"RAPID and API", true},
+		{"api 2018-10-05 - 2018-10-11", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		if got := sprintBelongsToProject(jira.Sprint{Name: c.name}, "API"); got != c.expect {
+			t.Errorf("%q: expect %v, got %v", c.name, c.expect, got)
+		}
+	}
+
+	if sprintBelongsToProject(jira.Sprint{Name: "API"}, "") {
+		t.Error("expect no sprint to belong to an empty project")
+	}
+}
+
+func TestSprintBelongsToProjectMatch(t *testing.T) {
+	oldConfig := config
+	config = new(Config)
+	defer func() { config = oldConfig }()
+
+	config.Jira.SprintMatch = `^\[(?P<project>[A-Z]+)\] Sprint`
+	cases := []struct {
+		name    string
+		project string
+		expect  bool
+	}{
+		{"[API] Sprint 12", "API", true},
+		{"[API] Sprint 12", "WEB", false},
+		{"API Sprint 12", "API", false},
+		{"[WEB] Sprint 3 with API", "API", false},
+	}
+	for _, c := range cases {
+		if got := sprintBelongsToProject(jira.Sprint{Name: c.name}, c.project); got != c.expect {
+			t.Errorf("%q in %s: expect %v, got %v", c.name, c.project, c.expect, got)
+		}
+	}
+
+	// Without a project group the pattern is for every project.
+	config.Jira.SprintMatch = `-Sprint$`
+	if !sprintBelongsToProject(jira.Sprint{Name: "2018-10-05-Sprint"}, "API") {
+		t.Error("expect the sprint to match without a project group")
+	}
+
+	first, _ := compileSprintMatch(config.Jira.SprintMatch)
+	if again, _ := compileSprintMatch(config.Jira.SprintMatch); again != first {
+		t.Error("expect the pattern compiled once")
+	}
+}
+
+func TestGetLatestPassedSprint(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	now := time.Now()
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	sprints := []jira.Sprint{
+		{ID: 1, Name: "TEST 1", StartDate: at(-2 * 24 * time.Hour), EndDate: at(-1 * time.Hour)},
+		{ID: 2, Name: "TEST 2", StartDate: at(-4 * 24 * time.Hour), EndDate: at(-3 * 24 * time.Hour)},
+		{ID: 3, Name: "TEST 3", StartDate: at(-6 * 24 * time.Hour), EndDate: at(-5 * 24 * time.Hour)},
+	}
+
+	if sprint := getLatestPassedSprint("TEST", sprints); sprint == nil || sprint.ID != 1 {
+		t.Fatalf("expect sprint 1, got %v", sprint)
+	}
+}
+
+// freezeNow sets nowFunc to now, call the returned function to restore it.
+func freezeNow(now time.Time) func() {
+	old := nowFunc
+	nowFunc = func() time.Time { return now }
+	return func() { nowFunc = old }
+}
+
+func TestSprintSelection(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+	now := time.Date(2018, time.October, 10, 12, 0, 0, 0, time.UTC)
+	defer freezeNow(now)()
+
+	at := func(hours int) *time.Time {
+		t := now.Add(time.Duration(hours) * time.Hour)
+		return &t
+	}
+	sprint := func(name string, start, end int) jira.Sprint {
+		return jira.Sprint{Name: name, StartDate: at(start), EndDate: at(end)}
+	}
+	const day = 24
+
+	cases := []struct {
+		name    string
+		sprints []jira.Sprint
+		// The names of the sprints picked by getLatestPassedSprint and
+		// getNearestFutureSprint, "" for none.
+		passed string
+		future string
+	}{
+		{"empty", nil, "", ""},
+		{"without dates", []jira.Sprint{{Name: "TEST 1"}, {Name: "TEST 2", EndDate: at(-day)}}, "", ""},
+		{"other projects", []jira.Sprint{
+			sprint("API 1", -8*day, -day),
+			sprint("TESTING 2", -8*day, -day),
+			sprint("API 3", day, 8*day),
+		}, "", ""},
+		{"past and future", []jira.Sprint{
+			sprint("TEST 1", -15*day, -8*day),
+			sprint("TEST 2", -8*day, -day),
+			sprint("TEST 3", 2*day, 9*day),
+			sprint("TEST 4", 9*day, 16*day),
+		}, "TEST 2", "TEST 3"},
+		{"unordered", []jira.Sprint{
+			sprint("TEST 4", 9*day, 16*day),
+			sprint("TEST 1", -15*day+6, -8*day+6),
+			sprint("TEST 3", 2*day, 9*day),
+			sprint("TEST 2", -8*day, -2),
+		}, "TEST 2", "TEST 3"},
+		// The earlier sprint in the list wins a tie.
+		{"ties", []jira.Sprint{
+			sprint("TEST 1", -8*day, -day),
+			sprint("TEST 2", -5*day, -day),
+			sprint("TEST 3", day, 8*day),
+			sprint("TEST 4", day, 5*day),
+		}, "TEST 1", "TEST 3"},
+		// A running sprint is neither passed nor in the future, but it is
+		// the nearest future sprint: it started before now.
+		{"running", []jira.Sprint{
+			sprint("TEST 1", -3*day, 4*day),
+			sprint("TEST 2", 4*day, 11*day),
+		}, "", "TEST 1"},
+		// Ending exactly now counts as passed and as not over yet.
+		{"ends now", []jira.Sprint{sprint("TEST 1", -7*day, 0)}, "TEST 1", "TEST 1"},
+		{"starts now", []jira.Sprint{sprint("TEST 1", 0, 7*day)}, "", "TEST 1"},
+		// Only the sprints within a sprint-duration of now are picked.
+		{"out of reach", []jira.Sprint{
+			sprint("TEST 1", -15*day, -7*day-1),
+			sprint("TEST 2", 7*day, 14*day),
+		}, "", ""},
+		{"just in reach", []jira.Sprint{
+			sprint("TEST 1", -15*day, -7*day+1),
+			sprint("TEST 2", 7*day-1, 14*day),
+		}, "TEST 1", "TEST 2"},
+	}
+
+	name := func(s *jira.Sprint) string {
+		if s == nil {
+			return ""
+		}
+		return s.Name
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := name(getLatestPassedSprint("TEST", c.sprints)); got != c.passed {
+				t.Errorf("expect passed sprint %q, got %q", c.passed, got)
+			}
+			if got := name(getNearestFutureSprint("TEST", c.sprints)); got != c.future {
+				t.Errorf("expect future sprint %q, got %q", c.future, got)
+			}
+		})
+	}
+}
+
+func TestSprintSelectionWithoutDates(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	end := time.Now().Add(-time.Hour)
+	sprints := []jira.Sprint{
+		{ID: 1, Name: "TEST backlog", State: "future"},
+		{ID: 2, Name: "TEST half", State: "future", EndDate: &end},
+	}
+
+	if sprint := getLatestPassedSprint("TEST", sprints); sprint != nil {
+		t.Fatalf("expect no passed sprint, got %v", sprint)
+	}
+	if sprint := getNearestFutureSprint("TEST", sprints); sprint != nil {
+		t.Fatalf("expect no future sprint, got %v", sprint)
+	}
+}
+
+func TestGetNearestFutureSprint(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	now := time.Now()
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	sprints := []jira.Sprint{
+		{ID: 1, Name: "TEST 1", StartDate: at(1 * time.Hour), EndDate: at(2 * 24 * time.Hour)},
+		{ID: 2, Name: "TEST 2", StartDate: at(3 * 24 * time.Hour), EndDate: at(4 * 24 * time.Hour)},
+		{ID: 3, Name: "TEST 3", StartDate: at(5 * 24 * time.Hour), EndDate: at(6 * 24 * time.Hour)},
+	}
+
+	if sprint := getNearestFutureSprint("TEST", sprints); sprint == nil || sprint.ID != 1 {
+		t.Fatalf("expect sprint 1, got %v", sprint)
+	}
+}
+
+func TestMoveIssuesToSprintBatchErrors(t *testing.T) {
+	batches := 0
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			// The target sprint is empty.
+			writeJSON(t, w, searchResult{})
+			return
+		}
+		var payload jira.IssuesWrapper
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		batches++
+		// Fail the second batch only.
+		if payload.Issues[0] == "50" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})()
+
+	var issues []jira.Issue
+	for i := 0; i < 120; i++ {
+		issues = append(issues, jira.Issue{ID: strconv.Itoa(i)})
+	}
+
+	failures := moveIssuesToSprint(1, issues)
+	if batches != 3 {
+		t.Fatalf("expect 3 batches, got %d", batches)
+	}
+	if len(failures) != 1 {
+		t.Fatalf("expect 1 failed batch, got %d", len(failures))
+	}
+	ids := failures[0].IssueIDs
+	if len(ids) != 50 || ids[0] != "50" || ids[49] != "99" || failures[0].Err == nil {
+		t.Fatalf("expect issues 50..99 to fail, got %v", failures[0])
+	}
+}
+
+func TestMoveIssuesToSprintBatchSize(t *testing.T) {
+	var sizes []int
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			// The target sprint is empty.
+			writeJSON(t, w, searchResult{})
+			return
+		}
+		var payload jira.IssuesWrapper
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		sizes = append(sizes, len(payload.Issues))
+		w.WriteHeader(http.StatusNoContent)
+	})()
+
+	config.Jira.MoveBatchSize = 20
+
+	var issues []jira.Issue
+	for i := 0; i < 45; i++ {
+		issues = append(issues, jira.Issue{ID: strconv.Itoa(i)})
+	}
+
+	if failures := moveIssuesToSprint(1, issues); len(failures) > 0 {
+		t.Fatal(failures)
+	}
+	if fmt.Sprint(sizes) != "[20 20 5]" {
+		t.Fatalf("expect batches of [20 20 5], got %v", sizes)
+	}
+}
+
+func TestMoveIssuesToSprintSkipsPresent(t *testing.T) {
+	var moved [][]string
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			writeJSON(t, w, searchResult{Total: 1, Issues: []jira.Issue{{ID: "1", Key: "TEST-1"}}})
+			return
+		}
+		var payload jira.IssuesWrapper
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		moved = append(moved, payload.Issues)
+		if containsString(payload.Issues, "1") {
+			// Like Jira for an issue in the sprint already.
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})()
+
+	issues := []jira.Issue{{ID: "1", Key: "TEST-1"}, {ID: "2", Key: "TEST-2"}}
+	if failures := moveIssuesToSprint(2, issues); len(failures) > 0 {
+		t.Fatal(failures)
+	}
+	if fmt.Sprint(moved) != "[[2]]" {
+		t.Fatalf("expect only the issue not in the sprint moved, got %v", moved)
+	}
+}
+
+func TestCreateFutureSprints(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	start := *day(10, 5)
+	end := start.AddDate(0, 0, 7)
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST 2018-10-05 - 2018-10-11", State: "future", StartDate: &start, EndDate: &end})
+
+	sprints, err := createFutureSprints("TEST", 1, start, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, sprint := range sprints {
+		names = append(names, sprint.Name)
+	}
+	expect := []string{
+		"TEST 2018-10-05 - 2018-10-11",
+		"TEST 2018-10-12 - 2018-10-18",
+		"TEST 2018-10-19 - 2018-10-25",
+	}
+	if fmt.Sprint(names) != fmt.Sprint(expect) {
+		t.Fatalf("expect sprints %v, got %v", expect, names)
+	}
+	if sprints[0].ID != 1 || f.countRequests("POST /rest/agile/1.0/sprint") != 2 {
+		t.Fatal("expect the existing sprint to be reused and 2 sprints to be created")
+	}
+}
+
+func TestQueryJiraIssuesWithOptions(t *testing.T) {
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("fields") != "assignee,status" || query.Get("expand") != "changelog" || query.Get("maxResults") != "50" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		writeJSON(t, w, map[string]interface{}{
+			"total":  1,
+			"issues": []jira.Issue{{ID: "1"}},
+		})
+	})()
+
+	issues, err := queryJiraIssuesWithOptions("project = TEST", &jira.SearchOptions{
+		MaxResults: 50,
+		Fields:     []string{"assignee", "status"},
+		Expand:     "changelog",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("expect 1 issue, got %d", len(issues))
+	}
+}
+
+func TestCompletedIssues(t *testing.T) {
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		jql := r.URL.Query().Get("jql")
+		if expect := `project = "TEST" AND status CHANGED TO "Done" DURING ("2018-01-01 09:00", "2018-01-08 09:00")`; jql != expect {
+			t.Errorf("expect jql %s, got %s", expect, jql)
+		}
+		writeJSON(t, w, map[string]interface{}{
+			"total": 2,
+			"issues": []map[string]interface{}{
+				{"id": "2", "fields": map[string]interface{}{"resolutiondate": "2018-01-05T10:00:00.000+0000"}},
+				{"id": "1", "fields": map[string]interface{}{"resolutiondate": "2018-01-02T10:00:00.000+0000"}},
+			},
+		})
+	})()
+
+	config.Jira.Timezone = "Asia/Shanghai"
+	from := time.Date(2018, 1, 1, 1, 0, 0, 0, time.UTC)
+	issues, err := completedIssues("TEST", from, from.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 || issues[0].ID != "1" || issues[1].ID != "2" {
+		t.Fatalf("expect issues sorted by resolution date, got %v", issues)
+	}
+}
+
+func TestGetBoardIDCache(t *testing.T) {
+	requests := 0
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rest/agile/1.0/board/3" {
+			writeJSON(t, w, jira.Board{ID: 3, Name: "TEST Scrum"})
+			return
+		}
+		requests++
+		writeJSON(t, w, jira.BoardsList{
+			IsLast: true,
+			Values: []jira.Board{{ID: 3, Name: "TEST Scrum"}},
+		})
+	})()
+
+	for i := 0; i < 2; i++ {
+		if id, err := getBoardID("TEST", "scrum"); err != nil || id != 3 {
+			t.Fatalf("expect board 3, got %d, %v", id, err)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expect 1 request with the cache, got %d", requests)
+	}
+
+	config.Jira.DisableBoardCache = true
+	if _, err := getBoardID("TEST", "scrum"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Fatalf("expect the cache to be bypassed, got %d requests", requests)
+	}
+}
+
+func TestLookupBoardIDGone(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+	f.boards = []jira.Board{{ID: 1, Name: "TEST old", Type: "scrum"}, {ID: 2, Name: "TEST board", Type: "scrum"}}
+	f.archived = []int{1}
+
+	_, err := getBoardID("TEST", "scrum")
+	if err == nil || !strings.Contains(err.Error(), "scrum board TEST old (1) of project \"TEST\" is deleted or archived") {
+		t.Fatalf("expect the archived board error, got %v", err)
+	}
+
+	config.Jira.BoardName = "TEST board"
+	if id, err := getBoardID("TEST", "scrum"); err != nil || id != 2 {
+		t.Fatalf("expect board 2, got %d, %v", id, err)
+	}
+}
+
+func TestLookupBoardIDDeleted(t *testing.T) {
+	// The board list is behind, the board was deleted.
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/agile/1.0/board" {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(t, w, jira.BoardsList{IsLast: true, Values: []jira.Board{{ID: 3, Name: "TEST Scrum"}}})
+	})()
+
+	_, err := getBoardID("TEST", "scrum")
+	if err == nil || !strings.Contains(err.Error(), "TEST Scrum (3)") || !strings.Contains(err.Error(), "set jira board-name or board-id") {
+		t.Fatalf("expect the deleted board error, got %v", err)
+	}
+}
+
+func TestLookupBoardIDTeamManaged(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+	f.boards = []jira.Board{{ID: 4, Name: "NEW board", Type: boardTypeSimple}}
+	f.teamManaged = []string{"NEW"}
+
+	if id, err := getSprintBoardID("NEW"); err != nil || id != 4 {
+		t.Fatalf("expect the simple board 4, got %d, %v", id, err)
+	}
+
+	// A company-managed project without a scrum board is still an error.
+	f.boards = append(f.boards, jira.Board{ID: 5, Name: "OLD board", Type: boardTypeKanban})
+	_, err := getSprintBoardID("OLD")
+	if expect := `no scrum board found for project "OLD"`; err == nil || err.Error() != expect {
+		t.Fatalf("expect %q, got %v", expect, err)
+	}
+}
+
+func TestGetActiveSprintTeamManaged(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+	f.boards = []jira.Board{{ID: 4, Name: "NEW board", Type: boardTypeSimple}, {ID: 5, Name: "TEST board", Type: boardTypeScrum}}
+
+	_, err := getActiveSprint("NEW", 4)
+	if err == nil || !strings.Contains(err.Error(), "NEW board (4) is the board of a team-managed project") {
+		t.Fatalf("expect the team-managed board error, got %v", err)
+	}
+
+	_, err = getActiveSprint("TEST", 5)
+	if expect := `no active sprint found for project "TEST" on board 5`; err == nil || err.Error() != expect {
+		t.Fatalf("expect %q, got %v", expect, err)
+	}
+}
+
+func TestCreateNextSprintOverlap(t *testing.T) {
+	sprints := []jira.Sprint{
+		{ID: 1, Name: "TEST old", State: "closed", StartDate: day(9, 1), EndDate: day(10, 30)},
+		// The active sprint was extended by a few days.
+		{ID: 2, Name: "TEST extended", State: "active", StartDate: day(9, 28), EndDate: day(10, 8)},
+		// The sprints of another project on the board don't overlap.
+		{ID: 3, Name: "API next", State: "future", StartDate: day(10, 10), EndDate: day(10, 17)},
+	}
+
+	var created []map[string]string
+	defer newTestSprintServer(t, sprints, &created)()
+
+	_, err := createNextSprint("TEST", 1, *day(10, 5))
+	if err == nil {
+		t.Fatal("expect overlap error")
+	}
+	if !strings.Contains(err.Error(), "TEST extended") || len(created) != 0 {
+		t.Fatalf("expect the conflicting sprint in the error and nothing created, got %v", err)
+	}
+
+	if err = validateSprintDates("TEST", 1, *day(10, 8), *day(10, 15)); err != nil {
+		t.Fatalf("expect adjacent sprint to be valid, got %v", err)
+	}
+	if err = validateSprintDates("API", 1, *day(10, 8), *day(10, 15)); err == nil || !strings.Contains(err.Error(), "API next") {
+		t.Fatalf("expect the overlap with API next, got %v", err)
+	}
+}
+
+func TestCreateSprintServerDeployment(t *testing.T) {
+	var created map[string]interface{}
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			writeJSON(t, w, jira.SprintsList{IsLast: true})
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+			t.Error(err)
+		}
+		writeJSON(t, w, jira.Sprint{ID: 5})
+	})()
+
+	config.Jira.Deployment = deploymentServer
+	if _, err := createSprint(3, "TEST", "", "2018-10-05T00:00:00Z", "2018-10-12T00:00:00Z"); err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := created["originBoardId"].(float64); !ok || id != 3 {
+		t.Fatalf("expect numeric board ID, got %#v", created["originBoardId"])
+	}
+}
+
+func TestActivateSprint(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST old", State: "closed", OriginBoardID: 1})
+	f.addSprint(jira.Sprint{ID: 2, Name: "TEST current", State: "active", OriginBoardID: 1})
+	f.addSprint(jira.Sprint{ID: 3, Name: "TEST next", State: "future", OriginBoardID: 1})
+
+	if err := activateSprint(1); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Fatalf("expect error for closed sprint, got %v", err)
+	}
+	if err := activateSprint(3); err == nil || !strings.Contains(err.Error(), "TEST current") {
+		t.Fatalf("expect error naming the active sprint, got %v", err)
+	}
+
+	f.sprint(2).State = "closed"
+	if err := activateSprint(3); err != nil {
+		t.Fatal(err)
+	}
+	if state := f.sprint(3).State; state != "active" {
+		t.Fatalf("expect sprint 3 active, got %s", state)
+	}
+}
+
+func TestUpdateSprintStateTransitions(t *testing.T) {
+	requests := 0
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		writeJSON(t, w, jira.Sprint{ID: 1, State: "closed"})
+	})()
+
+	cases := []struct {
+		from, to string
+		expect   string
+	}{
+		{"future", "actve", `unknown sprint state "actve"`},
+		{"closed", "active", "from closed to active"},
+		{"future", "closed", "from future to closed"},
+		{"active", "active", "from active to active"},
+	}
+	for _, c := range cases {
+		_, err := updateSprintState(jira.Sprint{ID: 1, Name: "TEST 1", State: c.from}, c.to)
+		if err == nil || !strings.Contains(err.Error(), c.expect) {
+			t.Errorf("%s to %s: expect %q, got %v", c.from, c.to, c.expect, err)
+		}
+	}
+	if requests != 0 {
+		t.Fatalf("expect no request for a rejected change, got %d", requests)
+	}
+
+	if _, err := updateSprintState(jira.Sprint{ID: 1, State: "active"}, "closed"); err != nil || requests != 1 {
+		t.Fatalf("expect the sprint closed, got %d requests, %v", requests, err)
+	}
+}
+
+func TestListSprintsStopsEarly(t *testing.T) {
+	requests := 0
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if state := r.URL.Query().Get("state"); state != "active,future" {
+			t.Errorf("expect state filter, got %q", state)
+		}
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		writeJSON(t, w, jira.SprintsList{
+			Values: []jira.Sprint{
+				{ID: startAt + 1, StartDate: day(10, startAt+1)},
+				{ID: startAt + 2, StartDate: day(10, startAt+2)},
+			},
+		})
+	})()
+
+	opts := sprintListOptions{GetAllSprintsOptions: jira.GetAllSprintsOptions{State: "active,future"}}
+	opts.Limit = 3
+	sprints, err := listSprints(1, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sprints) != 3 || requests != 2 {
+		t.Fatalf("expect 3 sprints in 2 requests, got %d in %d", len(sprints), requests)
+	}
+
+	requests = 0
+	opts.Limit = 0
+	opts.Stop = func(sprint jira.Sprint) bool {
+		return sprint.StartDate.After(*day(10, 4))
+	}
+	if sprints, err = listSprints(1, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(sprints) != 4 || requests != 3 {
+		t.Fatalf("expect 4 sprints in 3 requests, got %d in %d", len(sprints), requests)
+	}
+}
+
+func TestListSprintsPageSize(t *testing.T) {
+	var sizes []string
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		sizes = append(sizes, r.URL.Query().Get("maxResults"))
+		writeJSON(t, w, jira.SprintsList{IsLast: true})
+	})()
+
+	if _, err := getSprints(1, jira.GetAllSprintsOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	config.Jira.SprintPageSize = 20
+	if _, err := getSprints(1, jira.GetAllSprintsOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	opts := jira.GetAllSprintsOptions{SearchOptions: jira.SearchOptions{MaxResults: 10}}
+	if _, err := getSprints(1, opts); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(sizes) != "[50 20 10]" {
+		t.Fatalf("expect pages of [50 20 10], got %v", sizes)
+	}
+
+	opts.MaxResults = maxSprintPageSize + 1
+	if _, err := getSprints(1, opts); err == nil {
+		t.Fatal("expect error for a page size over the maximum")
+	}
+}
+
+func TestCreateNextSprintExistingNameWhitespace(t *testing.T) {
+	start := time.Date(2018, 10, 5, 0, 0, 0, 0, time.UTC)
+	sprints := []jira.Sprint{
+		{ID: 7, Name: "PROJ 2018-10-05 - 2018-10-11 ", State: "future", StartDate: &start},
+		{ID: 8, Name: "proj  2018-10-12 - 2018-10-18", State: "future"},
+	}
+
+	var created []map[string]string
+	defer newTestSprintServer(t, sprints, &created)()
+
+	sprint, err := createNextSprint("PROJ", 1, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sprint.ID != 7 || len(created) != 0 {
+		t.Fatalf("expect existing sprint 7, got %d and %d created", sprint.ID, len(created))
+	}
+
+	config.Jira.SprintNameIgnoreCase = true
+	if sprint, err = createNextSprint("PROJ", 1, start.AddDate(0, 0, 7)); err != nil {
+		t.Fatal(err)
+	}
+	if sprint.ID != 8 || len(created) != 0 {
+		t.Fatalf("expect existing sprint 8, got %d and %d created", sprint.ID, len(created))
+	}
+}
+
+func TestPruneEmptyFutureSprints(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	old := time.Now().AddDate(0, 0, -30)
+	recent := time.Now().AddDate(0, 0, -1)
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST closed", State: "closed", StartDate: &old})
+	f.addSprint(jira.Sprint{ID: 2, Name: "TEST active", State: "active", StartDate: &old})
+	f.addSprint(jira.Sprint{ID: 3, Name: "TEST stale", State: "future", StartDate: &old})
+	f.addSprint(jira.Sprint{ID: 4, Name: "TEST planned", State: "future", StartDate: &old})
+	f.addSprint(jira.Sprint{ID: 5, Name: "TEST new", State: "future", StartDate: &recent})
+	f.addSprint(jira.Sprint{ID: 6, Name: "TEST undated", State: "future"})
+	// Another project shares the board.
+	f.addSprint(jira.Sprint{ID: 7, Name: "API stale", State: "future", StartDate: &old})
+	f.addIssue(4, newFakeIssue(1, jira.StatusCategoryToDo))
+
+	deleted, err := pruneEmptyFutureSprints("TEST", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || deleted[0] != 3 {
+		t.Fatalf("expect only sprint 3 deleted, got %v", deleted)
+	}
+	if len(f.sprints) != 6 || f.sprint(3) != nil {
+		t.Fatalf("expect sprint 3 to be gone, got %v", f.sprints)
+	}
+}
+
+func TestPruneEmptyFutureSprintsDryRun(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	config.DryRun = true
+	old := time.Now().AddDate(0, 0, -30)
+	f.addSprint(jira.Sprint{ID: 3, Name: "TEST stale", State: "future", StartDate: &old})
+
+	deleted, err := pruneEmptyFutureSprints("TEST", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || f.countRequests("DELETE") != 0 || f.sprint(3) == nil {
+		t.Fatalf("expect sprint 3 to be reported but kept, got %v", deleted)
+	}
+}
+
+func TestGetAllBoardsPagination(t *testing.T) {
+	var starts []string
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		startAt := r.URL.Query().Get("startAt")
+		starts = append(starts, startAt)
+		if startAt != "2" {
+			writeJSON(t, w, jira.BoardsList{
+				MaxResults: 2,
+				Values:     []jira.Board{{ID: 1, Name: "TEST A"}, {ID: 2, Name: "TEST B"}},
+			})
+			return
+		}
+		writeJSON(t, w, jira.BoardsList{
+			StartAt: 2,
+			IsLast:  true,
+			Values:  []jira.Board{{ID: 3, Name: "TEST Scrum"}},
+		})
+	})()
+
+	boards, err := getAllBoards(jira.BoardListOptions{ProjectKeyOrID: "TEST"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(boards) != 3 || len(starts) != 2 || starts[1] != "2" {
+		t.Fatalf("expect 3 boards from 2 pages, got %v from %v", boards, starts)
+	}
+
+	config.Jira.BoardName = "TEST Scrum"
+	if id, err := getBoardID("TEST", "scrum"); err != nil || id != 3 {
+		t.Fatalf("expect board 3 from the second page, got %d, %v", id, err)
+	}
+}
+
+func TestGetActiveSprintCases(t *testing.T) {
+	cases := []struct {
+		name    string
+		sprints []jira.Sprint
+		project string
+		expect  string
+	}{
+		{"none", nil, "TEST", ""},
+		{"only future", []jira.Sprint{{ID: 1, Name: "TEST 1", State: "future"}}, "TEST", ""},
+		{"other project", []jira.Sprint{{ID: 1, Name: "RATEST 1", State: "active"}}, "TEST", ""},
+		{"first of project", []jira.Sprint{
+			{ID: 1, Name: "API 1", State: "active"},
+			{ID: 2, Name: "TEST 2", State: "active"},
+			{ID: 3, Name: "TEST 3", State: "active"},
+		}, "TEST", "TEST 2"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, cleanup := newInProcessFakeJira(t)
+			defer cleanup()
+			for _, sprint := range c.sprints {
+				f.addSprint(sprint)
+			}
+
+			sprint, err := getActiveSprint(c.project, 1)
+			if len(c.expect) == 0 {
+				if err == nil {
+					t.Fatalf("expect no active sprint, got %s", sprint.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sprint.Name != c.expect {
+				t.Fatalf("expect %s, got %s", c.expect, sprint.Name)
+			}
+		})
+	}
+}
+
+func TestGetLatestPassedSprintCases(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	now := time.Now()
+	at := func(days int) *time.Time {
+		t := now.AddDate(0, 0, days)
+		return &t
+	}
+	cases := []struct {
+		name    string
+		sprints []jira.Sprint
+		expect  string
+	}{
+		{"none", nil, ""},
+		{"still running", []jira.Sprint{{Name: "TEST 1", StartDate: at(-3), EndDate: at(4)}}, ""},
+		{"ended too long ago", []jira.Sprint{{Name: "TEST 1", StartDate: at(-20), EndDate: at(-13)}}, ""},
+		{"nearest end", []jira.Sprint{
+			{Name: "TEST 1", StartDate: at(-9), EndDate: at(-6)},
+			{Name: "TEST 2", StartDate: at(-6), EndDate: at(-1)},
+			{Name: "API 3", StartDate: at(-1), EndDate: at(0)},
+		}, "TEST 2"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sprint := getLatestPassedSprint("TEST", c.sprints)
+			if len(c.expect) == 0 {
+				if sprint != nil {
+					t.Fatalf("expect no sprint, got %s", sprint.Name)
+				}
+				return
+			}
+			if sprint == nil || sprint.Name != c.expect {
+				t.Fatalf("expect %s, got %v", c.expect, sprint)
+			}
+		})
+	}
+}
+
+func TestCreateNextSprintCases(t *testing.T) {
+	start := time.Date(2018, time.October, 5, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		sprints []jira.Sprint
+		expect  string
+		created bool
+		err     bool
+	}{
+		{"first sprint", nil, "TEST 2018-10-05 - 2018-10-11", true, false},
+		{"already created", []jira.Sprint{
+			{ID: 7, Name: "TEST 2018-10-05 - 2018-10-11", State: "future", StartDate: &start, EndDate: day(time.October, 12)},
+		}, "TEST 2018-10-05 - 2018-10-11", false, false},
+		{"overlap", []jira.Sprint{
+			{ID: 7, Name: "TEST other", State: "future", StartDate: day(time.October, 8), EndDate: day(time.October, 15)},
+		}, "", false, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, cleanup := newInProcessFakeJira(t)
+			defer cleanup()
+			config.Jira.Timezone = "UTC"
+			for _, sprint := range c.sprints {
+				f.addSprint(sprint)
+			}
+
+			sprint, err := createNextSprint("TEST", 1, start)
+			if c.err {
+				if err == nil {
+					t.Fatalf("expect error, got sprint %s", sprint.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sprint.Name != c.expect {
+				t.Fatalf("expect %s, got %s", c.expect, sprint.Name)
+			}
+			if created := f.countRequests("POST /rest/agile/1.0/sprint") > 0; created != c.created {
+				t.Fatalf("expect created %v, got %v", c.created, created)
+			}
+		})
+	}
+}
+
+func TestGetSprintsInStates(t *testing.T) {
+	var states []string
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		states = append(states, r.URL.Query().Get("state"))
+		if r.URL.Query().Get("startAt") == "1" {
+			writeJSON(t, w, jira.SprintsList{IsLast: true, Values: []jira.Sprint{{ID: 2, State: "closed"}}})
+			return
+		}
+		writeJSON(t, w, jira.SprintsList{Values: []jira.Sprint{{ID: 1, State: "active"}}})
+	})()
+
+	sprints, err := getSprintsInStates(1, "active", " Closed", "active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sprints) != 2 || len(states) != 2 || states[0] != "active,closed" || states[1] != "active,closed" {
+		t.Fatalf("expect both states in every page, got %d sprints with states %v", len(sprints), states)
+	}
+
+	states = nil
+	if _, err = getSprints(1, jira.GetAllSprintsOptions{State: "future,active"}); err != nil {
+		t.Fatal(err)
+	}
+	if states[0] != "future,active" {
+		t.Fatalf("expect the comma separated state to pass, got %q", states[0])
+	}
+
+	if _, err = getSprintsInStates(1, "open"); err == nil {
+		t.Fatal("expect error for an unknown state")
+	}
+}
+
+func TestIssuesInSprint(t *testing.T) {
+	f, cleanup := newFakeJira(t)
+	defer cleanup()
+	f.addIssue(1, newFakeIssue(1, jira.StatusCategoryComplete))
+	f.addIssue(1, newFakeIssue(2, jira.StatusCategoryInProgress))
+	f.addIssue(2, newFakeIssue(3, jira.StatusCategoryToDo))
+
+	issues, err := issuesInSprint(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("expect 2 issues in sprint 1, got %d", len(issues))
+	}
+
+	issues, err = incompleteIssuesInSprint(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Key != "TEST-2" {
+		t.Fatalf("expect TEST-2 incomplete, got %v", issues)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// maxErrorBodySize limits how much of a failed response is read.
+const maxErrorBodySize = 64 << 10
+
+// jiraAPIError is a failed Jira request. go-jira only reports the status
+// code, the messages Jira puts in the body tell what is actually wrong.
+type jiraAPIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Messages holds the errorMessages and the field errors of the body,
+	// or the start of the body if it is not a Jira error.
+	Messages []string
+}
+
+func (e *jiraAPIError) Error() string {
+	msg := fmt.Sprintf("jira %s %s failed with status %d", e.Method, e.URL, e.StatusCode)
+	if len(e.Messages) > 0 {
+		msg += ": " + strings.Join(e.Messages, "; ")
+	}
+	return msg
+}
+
+// wrapJiraError replaces the error of a failed response with a
+// jiraAPIError. Errors without a response, like network errors, are
+// returned as they are.
+func wrapJiraError(req *http.Request, resp *jira.Response, err error) error {
+	if err == nil || resp == nil || resp.Response == nil || resp.StatusCode < 300 {
+		return err
+	}
+
+	apiErr := &jiraAPIError{
+		Method:     req.Method,
+		URL:        req.URL.Path,
+		StatusCode: resp.StatusCode,
+	}
+
+	body, readErr := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	resp.Body.Close()
+	if readErr != nil {
+		return apiErr
+	}
+
+	var jiraErr struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+	}
+	if json.Unmarshal(body, &jiraErr) == nil {
+		apiErr.Messages = append(apiErr.Messages, jiraErr.ErrorMessages...)
+		fields := make([]string, 0, len(jiraErr.Errors))
+		for field := range jiraErr.Errors {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			apiErr.Messages = append(apiErr.Messages, field+": "+jiraErr.Errors[field])
+		}
+	}
+	if len(apiErr.Messages) == 0 {
+		// Proxies and some Jira failures answer with HTML or plain text.
+		if text := strings.TrimSpace(string(body)); len(text) > 0 {
+			if len(text) > 200 {
+				text = text[:200] + "..."
+			}
+			apiErr.Messages = []string{text}
+		}
+	}
+	return apiErr
+}
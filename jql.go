@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JQL builds a JQL query from clauses joined with AND, quoting the values
+// so that keys and names with quotes or special characters stay valid.
+type JQL struct {
+	clauses []string
+	orderBy string
+}
+
+// NewJQL creates an empty JQL query.
+func NewJQL() *JQL {
+	return &JQL{}
+}
+
+// quoteJQL returns s as a JQL string literal.
+func quoteJQL(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return `"` + s + `"`
+}
+
+// Where adds a raw clause, which is put in parentheses.
+func (q *JQL) Where(clause string) *JQL {
+	q.clauses = append(q.clauses, "("+clause+")")
+	return q
+}
+
+// Project limits the query to the project.
+func (q *JQL) Project(key string) *JQL {
+	q.clauses = append(q.clauses, "project = "+quoteJQL(key))
+	return q
+}
+
+// Sprint limits the query to the sprint.
+func (q *JQL) Sprint(id int) *JQL {
+	q.clauses = append(q.clauses, fmt.Sprintf("sprint = %d", id))
+	return q
+}
+
+// StatusCategory limits the query to the issues in the status category.
+func (q *JQL) StatusCategory(category string) *JQL {
+	q.clauses = append(q.clauses, "statusCategory = "+quoteJQL(category))
+	return q
+}
+
+// StatusCategoryNot excludes the issues in the status category.
+func (q *JQL) StatusCategoryNot(category string) *JQL {
+	q.clauses = append(q.clauses, "statusCategory != "+quoteJQL(category))
+	return q
+}
+
+// Done limits the query to the completed issues, the ones in the Done
+// status category or in any of the extra done statuses.
+func (q *JQL) Done(statuses ...string) *JQL {
+	if len(statuses) == 0 {
+		return q.StatusCategory(doneStatusCategory)
+	}
+	q.clauses = append(q.clauses, fmt.Sprintf("(statusCategory = %s OR status in (%s))",
+		quoteJQL(doneStatusCategory), quoteJQLList(statuses)))
+	return q
+}
+
+// NotDone limits the query to the issues which are not completed, see Done.
+func (q *JQL) NotDone(statuses ...string) *JQL {
+	q.StatusCategoryNot(doneStatusCategory)
+	if len(statuses) > 0 {
+		q.clauses = append(q.clauses, fmt.Sprintf("status not in (%s)", quoteJQLList(statuses)))
+	}
+	return q
+}
+
+// StatusChangedDuring limits the query to the issues moved to status
+// between from and to, which must be valid JQL dates.
+func (q *JQL) StatusChangedDuring(status, from, to string) *JQL {
+	q.clauses = append(q.clauses, fmt.Sprintf("status CHANGED TO %s DURING (%s, %s)",
+		quoteJQL(status), quoteJQL(from), quoteJQL(to)))
+	return q
+}
+
+// StatusChangedToAnyDuring is like StatusChangedDuring for several
+// statuses, an issue moved to any of them matches.
+func (q *JQL) StatusChangedToAnyDuring(statuses []string, from, to string) *JQL {
+	if len(statuses) == 1 {
+		return q.StatusChangedDuring(statuses[0], from, to)
+	}
+	changes := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		changes = append(changes, NewJQL().StatusChangedDuring(status, from, to).String())
+	}
+	q.clauses = append(q.clauses, "("+strings.Join(changes, " OR ")+")")
+	return q
+}
+
+// UpdatedSince limits the query to the issues updated at or after since,
+// which must be a valid JQL date.
+func (q *JQL) UpdatedSince(since string) *JQL {
+	q.clauses = append(q.clauses, "updated >= "+quoteJQL(since))
+	return q
+}
+
+// UpdatedBefore limits the query to the issues updated before until, which
+// must be a valid JQL date.
+func (q *JQL) UpdatedBefore(until string) *JQL {
+	q.clauses = append(q.clauses, "updated < "+quoteJQL(until))
+	return q
+}
+
+// Components limits the query to the issues in any of the components,
+// nothing is added without components.
+func (q *JQL) Components(names ...string) *JQL {
+	return q.in("component", names)
+}
+
+// Keys limits the query to the issues with the keys, nothing is added
+// without keys.
+func (q *JQL) Keys(keys ...string) *JQL {
+	return q.in("key", keys)
+}
+
+// Labels limits the query to the issues with any of the labels, nothing is
+// added without labels.
+func (q *JQL) Labels(labels ...string) *JQL {
+	return q.in("labels", labels)
+}
+
+func (q *JQL) in(field string, values []string) *JQL {
+	if len(values) == 0 {
+		return q
+	}
+	q.clauses = append(q.clauses, fmt.Sprintf("%s in (%s)", field, quoteJQLList(values)))
+	return q
+}
+
+func quoteJQLList(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, quoteJQL(value))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// Assignee limits the query to the issues assigned to user.
+func (q *JQL) Assignee(user string) *JQL {
+	q.clauses = append(q.clauses, "assignee = "+quoteJQL(user))
+	return q
+}
+
+// OrderBy sorts the results by field, like "created DESC".
+func (q *JQL) OrderBy(field string) *JQL {
+	q.orderBy = field
+	return q
+}
+
+func (q *JQL) String() string {
+	s := strings.Join(q.clauses, " AND ")
+	if len(q.orderBy) > 0 {
+		s += " ORDER BY " + q.orderBy
+	}
+	return s
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestJQL(t *testing.T) {
+	cases := []struct {
+		query  *JQL
+		expect string
+	}{
+		{NewJQL(), ""},
+		{NewJQL().Project("TEST").Sprint(12), `project = "TEST" AND sprint = 12`},
+		{NewJQL().Sprint(12).StatusCategoryNot("Done"), `sprint = 12 AND statusCategory != "Done"`},
+		{NewJQL().StatusChangedDuring("Done", "2018-01-01 00:00", "2018-01-08 00:00"),
+			`status CHANGED TO "Done" DURING ("2018-01-01 00:00", "2018-01-08 00:00")`},
+		{NewJQL().Sprint(12).Done(), `sprint = 12 AND statusCategory = "Done"`},
+		{NewJQL().Sprint(12).Done("Released"), `sprint = 12 AND (statusCategory = "Done" OR status in ("Released"))`},
+		{NewJQL().Sprint(12).NotDone("Released", "Closed"),
+			`sprint = 12 AND statusCategory != "Done" AND status not in ("Released", "Closed")`},
+		{NewJQL().StatusChangedToAnyDuring([]string{"Done", "Released"}, "2018-01-01 00:00", "2018-01-08 00:00"),
+			`(status CHANGED TO "Done" DURING ("2018-01-01 00:00", "2018-01-08 00:00") OR status CHANGED TO "Released" DURING ("2018-01-01 00:00", "2018-01-08 00:00"))`},
+		{NewJQL().Assignee(`o"neil\x`), `assignee = "o\"neil\\x"`},
+		{NewJQL().Project("TEST").Where("priority = Highest OR labels = urgent").OrderBy("created DESC"),
+			`project = "TEST" AND (priority = Highest OR labels = urgent) ORDER BY created DESC`},
+	}
+
+	for _, c := range cases {
+		if got := c.query.String(); got != c.expect {
+			t.Errorf("expect %s, got %s", c.expect, got)
+		}
+	}
+}
+
+func TestJQLComponentsAndLabels(t *testing.T) {
+	q := NewJQL().Sprint(1).Components("API", "Web UI").Labels().Labels("backend")
+	if expect := `sprint = 1 AND component in ("API", "Web UI") AND labels in ("backend")`; q.String() != expect {
+		t.Fatalf("expect %s, got %s", expect, q)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// The JSON report is consumed by other tools, only add fields to it.
+
+// JSONReport is the machine readable sprint report.
+type JSONReport struct {
+	Project  string       `json:"project"`
+	Sprint   JSONSprint   `json:"sprint"`
+	Progress JSONProgress `json:"progress"`
+	// Assignees are sorted by name.
+	Assignees []JSONAssignee `json:"assignees"`
+	// StatusCategories counts all issues by status category name.
+	StatusCategories map[string]int `json:"status_categories"`
+	Totals           JSONTotals     `json:"totals"`
+	Carryover        JSONCarryover  `json:"carryover"`
+	// Unestimated are the issues without story points.
+	Unestimated []JSONIssue `json:"unestimated,omitempty"`
+	// Blocked are the flagged issues and the ones in a blocked status.
+	Blocked []JSONIssue `json:"blocked,omitempty"`
+	// Stale are the issues in progress without a recent update.
+	Stale []JSONIssue `json:"stale,omitempty"`
+}
+
+// JSONIssue refers to an issue of the report.
+type JSONIssue struct {
+	Key      string `json:"key"`
+	Summary  string `json:"summary,omitempty"`
+	Assignee string `json:"assignee"`
+}
+
+// JSONSprint is the sprint the report is about.
+type JSONSprint struct {
+	ID    int        `json:"id"`
+	Name  string     `json:"name"`
+	State string     `json:"state"`
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// JSONAssignee is the summary of the issues assigned to one person.
+type JSONAssignee struct {
+	Assignee         string         `json:"assignee"`
+	AvatarURL        string         `json:"avatar_url,omitempty"`
+	Issues           int            `json:"issues"`
+	StoryPoints      float64        `json:"story_points"`
+	Unestimated      int            `json:"unestimated"`
+	StatusCategories map[string]int `json:"status_categories"`
+	// Capacity and Overload, the story points over it, are only set with a
+	// configured capacity.
+	Capacity float64 `json:"capacity,omitempty"`
+	Overload float64 `json:"overload,omitempty"`
+	// Hours is only set with report logged-time.
+	Hours float64 `json:"hours,omitempty"`
+}
+
+// JSONProgress is how much of the sprint is done.
+type JSONProgress struct {
+	Issues          int     `json:"issues"`
+	Done            int     `json:"done"`
+	StoryPoints     float64 `json:"story_points"`
+	DoneStoryPoints float64 `json:"done_story_points"`
+	PointsPercent   int     `json:"points_percent"`
+}
+
+// JSONCarryover counts the issues carried over from the previous sprint.
+type JSONCarryover struct {
+	PreviousSprintID int     `json:"previous_sprint_id,omitempty"`
+	Issues           int     `json:"issues"`
+	Percentage       float64 `json:"percentage"`
+}
+
+// JSONTotals sums up all assignees.
+type JSONTotals struct {
+	Issues      int     `json:"issues"`
+	StoryPoints float64 `json:"story_points"`
+	Unestimated int     `json:"unestimated"`
+	Hours       float64 `json:"hours,omitempty"`
+}
+
+// renderJSON renders the sprint report of the project as indented JSON.
+func renderJSON(r projectReport) (string, error) {
+	data, err := json.MarshalIndent(buildJSONReport(r), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// renderJSONReports renders the reports as one JSON document: the report of
+// a single project as is, like renderJSON, and the reports of several
+// projects as an array.
+func renderJSONReports(reports []projectReport) (string, error) {
+	if len(reports) == 1 {
+		return renderJSON(reports[0])
+	}
+	out := make([]JSONReport, 0, len(reports))
+	for _, r := range reports {
+		out = append(out, buildJSONReport(r))
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// buildJSONReport converts the sprint report to its machine readable form.
+func buildJSONReport(r projectReport) JSONReport {
+	sprint, report := r.sprint, r.report
+	out := JSONReport{
+		Project: r.project,
+		Sprint: JSONSprint{
+			ID:    sprint.ID,
+			Name:  sprint.Name,
+			State: sprint.State,
+			Start: sprint.StartDate,
+			End:   sprint.EndDate,
+		},
+		Progress: JSONProgress{
+			Issues:          r.progress.Issues,
+			Done:            r.progress.Done,
+			StoryPoints:     r.progress.Points,
+			DoneStoryPoints: r.progress.DonePoints,
+			PointsPercent:   r.progress.pointsPercent(),
+		},
+		Assignees:        make([]JSONAssignee, 0, len(report)),
+		StatusCategories: make(map[string]int),
+		Carryover: JSONCarryover{
+			Issues:     r.carryover.Carryover,
+			Percentage: r.carryover.Percentage(),
+		},
+	}
+	if previous := r.carryover.PreviousSprint; previous != nil {
+		out.Carryover.PreviousSprintID = previous.ID
+	}
+
+	for _, assignee := range sortedAssignees(report) {
+		summary := report[assignee]
+		out.Assignees = append(out.Assignees, JSONAssignee{
+			Assignee:         summary.Assignee,
+			AvatarURL:        summary.AvatarURL,
+			Issues:           summary.Issues,
+			StoryPoints:      summary.StoryPoints,
+			Unestimated:      summary.Unestimated,
+			StatusCategories: summary.StatusCategories,
+			Capacity:         summary.Capacity,
+			Overload:         summary.Overload(),
+			Hours:            summary.Hours,
+		})
+		for category, n := range summary.StatusCategories {
+			out.StatusCategories[category] += n
+		}
+		out.Totals.Issues += summary.Issues
+		out.Totals.StoryPoints += summary.StoryPoints
+		out.Totals.Unestimated += summary.Unestimated
+		out.Totals.Hours += summary.Hours
+	}
+
+	for _, issue := range r.unestimated {
+		out.Unestimated = append(out.Unestimated, JSONIssue{Key: issue.Key, Assignee: issueAssignee(issue)})
+	}
+	for _, issue := range r.blocked {
+		out.Blocked = append(out.Blocked, JSONIssue{Key: issue.Key, Summary: issueSummary(issue), Assignee: issueAssignee(issue)})
+	}
+	for _, issue := range r.stale {
+		out.Stale = append(out.Stale, JSONIssue{Key: issue.Key, Summary: issueSummary(issue), Assignee: issueAssignee(issue)})
+	}
+
+	return out
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestRenderJSON(t *testing.T) {
+	config = &Config{}
+	config.Jira.StoryPointField = "customfield_10001"
+	defer func() { config = nil }()
+
+	report := buildAssigneeReport([]jira.Issue{
+		newReportIssue("TEST-1", "bob", "Done", 3),
+		newReportIssue("TEST-2", "alice", "To Do", -1),
+		newReportIssue("TEST-3", "alice", "Done", 2),
+	})
+	out, err := renderJSON(projectReport{
+		project:   "TEST",
+		sprint:    jira.Sprint{ID: 7, Name: "TEST 1", State: "active", StartDate: day(10, 5)},
+		report:    report,
+		carryover: CarryoverSummary{Issues: 3, Carryover: 1, PreviousSprint: &jira.Sprint{ID: 6}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded JSONReport
+	if err = json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Project != "TEST" || decoded.Sprint.ID != 7 || decoded.Sprint.End != nil || !decoded.Sprint.Start.Equal(*day(10, 5)) {
+		t.Fatalf("unexpected sprint %+v", decoded.Sprint)
+	}
+	if len(decoded.Assignees) != 2 || decoded.Assignees[0].Assignee != "alice" || decoded.Assignees[0].Unestimated != 1 {
+		t.Fatalf("unexpected assignees %+v", decoded.Assignees)
+	}
+	if decoded.StatusCategories["Done"] != 2 || decoded.Totals.Issues != 3 || decoded.Totals.StoryPoints != 5 {
+		t.Fatalf("unexpected totals %+v %+v", decoded.StatusCategories, decoded.Totals)
+	}
+	if decoded.Carryover.PreviousSprintID != 6 || decoded.Carryover.Issues != 1 {
+		t.Fatalf("unexpected carryover %+v", decoded.Carryover)
+	}
+}
+
+func TestWriteSprintReportsJSON(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	dir, err := ioutil.TempDir("", "reports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	oldOutput, oldFile := reportOutput, reportOutFile
+	defer func() { reportOutput, reportOutFile = oldOutput, oldFile }()
+	reportOutput, reportOutFile = "json", filepath.Join(dir, "reports.json")
+
+	issues := []jira.Issue{newReportIssue("TEST-1", "bob", "Done", -1)}
+	reports := []projectReport{
+		{project: "TEST", sprint: jira.Sprint{ID: 1, Name: "TEST 1"}, report: buildAssigneeReport(issues)},
+		{project: "PD", sprint: jira.Sprint{ID: 2, Name: "PD 1"}, report: buildAssigneeReport(issues)},
+	}
+	if err = writeSprintReports(reports); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(reportOutFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []JSONReport
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("expect one JSON document, got %v", err)
+	}
+	if len(decoded) != 2 || decoded[0].Project != "TEST" || decoded[1].Sprint.ID != 2 {
+		t.Fatalf("unexpected reports %+v", decoded)
+	}
+
+	// A single project is still one object.
+	if err = writeSprintReports(reports[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = ioutil.ReadFile(reportOutFile); err != nil {
+		t.Fatal(err)
+	}
+	var single JSONReport
+	if err = json.Unmarshal(data, &single); err != nil || single.Project != "TEST" {
+		t.Fatalf("expect the TEST report, got %+v, %v", single, err)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logger records what the Jira operations do. It discards everything
+// unless --log-level is given, so the normal output is not cluttered.
+var logger = slog.New(slog.DiscardHandler)
+
+// newLogger creates a logger writing to stderr at the level, which is one
+// of debug, info, warn and error. An empty level disables logging.
+func newLogger(level string) (*slog.Logger, error) {
+	if len(level) == 0 {
+		return slog.New(slog.DiscardHandler), nil
+	}
+
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.ToLower(level))); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: l})), nil
+}
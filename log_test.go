@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	l, err := newLogger("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Enabled(context.Background(), slog.LevelError) {
+		t.Fatal("expect logging to be disabled without a level")
+	}
+
+	if l, err = newLogger("WARN"); err != nil {
+		t.Fatal(err)
+	}
+	if l.Enabled(context.Background(), slog.LevelInfo) || !l.Enabled(context.Background(), slog.LevelWarn) {
+		t.Fatal("expect warn level logger")
+	}
+
+	if _, err = newLogger("loud"); err == nil {
+		t.Fatal("expect error for invalid level")
+	}
+}
@@ -19,7 +19,9 @@ func perror(err error) {
 	}
 
 	println(err.Error())
-	os.Exit(1)
+	writeMetricsFile()
+	writeSummaryFile(exitFailure, err)
+	os.Exit(exitFailure)
 }
 
 func perrmsg(msg string) {
@@ -29,10 +31,17 @@ func perrmsg(msg string) {
 var (
 	token           string
 	configFile      string
+	dryRun          bool
+	logLevel        string
+	boardTypeFlag   string
+	jiraEnv         string
+	configSets      []string
+	summaryFile     string
 	globalCtx       context.Context
+	globalCancel    context.CancelFunc
 	config          *Config
 	githubClient    *github.Client
-	jiraClient      *jira.Client
+	jiraClient      jiraAPI
 	conflunceClient *jira.Client
 )
 
@@ -43,10 +52,18 @@ func main() {
 	}
 
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "C", "", "Config File, default ~/.work-reporter/config.toml")
+	rootCmd.PersistentFlags().StringArrayVar(&configSets, "set", nil, "Override a config option like jira.project=TIKV, after the config file and the environment")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the changes to Jira and Slack instead of making them")
+	rootCmd.PersistentFlags().StringVar(&jiraEnv, "env", "", "Use this Jira environment of jira environments instead of the jira endpoint")
+	rootCmd.PersistentFlags().StringVar(&boardTypeFlag, "board-type", "", "Board type to work on: scrum, kanban or simple, overrides the config")
+	rootCmd.PersistentFlags().StringVar(&summaryFile, "summary-file", "", "Write a JSON summary of the run to this file: the created sprints, the moved issues and the errors")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Log the Jira operations to stderr at this level: debug, info, warn or error")
 
 	rootCmd.AddCommand(
 		newDailyCommand(),
 		newWeeklyCommand(),
+		newScheduleCommand(),
+		newDoctorCommand(),
 	)
 
 	cobra.OnInitialize(initGlobal)
@@ -55,20 +72,48 @@ func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(rootCmd.UsageString())
 	}
+
+	if globalCancel != nil {
+		globalCancel()
+	}
+	writeMetricsFile()
+
+	if summary := runStatus.summary(); len(summary) > 0 {
+		fmt.Fprint(os.Stderr, summary)
+	}
+	code := runStatus.exitCode()
+	writeSummaryFile(code, nil)
+	os.Exit(code)
 }
 
 func initGlobal() {
 	usr, err := user.Current()
 	perror(err)
 
-	if len(configFile) == 0 {
+	// Without --config the default file may be missing, the config then
+	// comes from the environment.
+	required := len(configFile) > 0
+	if !required {
 		configFile = path.Join(usr.HomeDir, ".work-reporter/config.toml")
 	}
-	cfg, err := NewConfigFromFile(configFile)
+	cfg, err := loadConfig(configFile, required, jiraEnv, configSets)
+	perror(err)
+	if len(boardTypeFlag) > 0 {
+		cfg.Jira.BoardType = boardTypeFlag
+	}
+	perror(cfg.Validate())
+
+	logger, err = newLogger(logLevel)
 	perror(err)
 
 	globalCtx = context.Background()
+	if cfg.Timeout.Duration > 0 {
+		globalCtx, globalCancel = context.WithTimeout(globalCtx, cfg.Timeout.Duration)
+	}
 	config = cfg
+	if dryRun {
+		config.DryRun = true
+	}
 
 	initRepoQuery()
 
@@ -81,14 +126,12 @@ func initGlobal() {
 
 	initTeamMembers()
 
-	jiraTransport := jira.BasicAuthTransport{
-		Username: config.Jira.User,
-		Password: config.Jira.Password,
+	perror(initJiraClient(config))
+	jiraLimiter = newRateLimiter(config.Jira.RequestsPerSecond)
+	if id := config.Jira.BoardID; id > 0 {
+		perror(checkBoardID(id))
 	}
 
-	jiraClient, err = jira.NewClient(jiraTransport.Client(), config.Jira.Endpoint)
-	perror(err)
-
 	// In our company, we use same user and password for Jira and Confluence.
 	if len(config.Confluence.User) == 0 {
 		config.Confluence.User = config.Jira.User
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// The usual Jira status categories, rendered first and in this order.
+var knownStatusCategories = []string{"To Do", "In Progress", "Done"}
+
+// statusCategoryColumns returns the status categories present in the
+// report, the known ones first and the custom ones sorted by name.
+func statusCategoryColumns(report map[string]AssigneeSummary) []string {
+	present := make(map[string]bool)
+	for _, summary := range report {
+		for category := range summary.StatusCategories {
+			present[category] = true
+		}
+	}
+
+	var columns, custom []string
+	for _, category := range knownStatusCategories {
+		if present[category] {
+			columns = append(columns, category)
+			delete(present, category)
+		}
+	}
+	for category := range present {
+		custom = append(custom, category)
+	}
+	sort.Strings(custom)
+
+	return append(columns, custom...)
+}
+
+func sortedAssignees(report map[string]AssigneeSummary) []string {
+	assignees := make([]string, 0, len(report))
+	for assignee := range report {
+		assignees = append(assignees, assignee)
+	}
+	sort.Strings(assignees)
+	return assignees
+}
+
+func formatPoints(points float64) string {
+	return strconv.FormatFloat(points, 'f', -1, 64)
+}
+
+// defaultDisplayDateFormat is the layout of the dates shown to readers, the
+// API always uses dateFormat.
+const defaultDisplayDateFormat = "Jan 2"
+
+func displayDateFormat() string {
+	if layout := config.Report.DateFormat; len(layout) > 0 {
+		return layout
+	}
+	return defaultDisplayDateFormat
+}
+
+// formatSprintWindow formats the days of the sprint for readers, like
+// "Oct 5 – Oct 11", in the sprint timezone. The end is exclusive, so the
+// last day shown is the one before its calendar date. It is empty without dates.
+func formatSprintWindow(sprint jira.Sprint) string {
+	if sprint.StartDate == nil || sprint.EndDate == nil {
+		return ""
+	}
+	layout := displayDateFormat()
+	loc, err := sprintLocation(sprint.StartDate.Location())
+	if err != nil {
+		loc = sprint.StartDate.Location()
+	}
+
+	start := sprint.StartDate.In(loc)
+	end := sprintLastDay(sprint.EndDate.In(loc))
+	if end.Before(start) {
+		end = start
+	}
+	return start.Format(layout) + " – " + end.Format(layout)
+}
+
+// sprintTitle is the sprint name followed by its window if it has dates.
+func sprintTitle(sprint jira.Sprint) string {
+	if window := formatSprintWindow(sprint); len(window) > 0 {
+		return fmt.Sprintf("%s (%s)", sprint.Name, window)
+	}
+	return sprint.Name
+}
+
+func escapeMarkdownCell(s string) string {
+	return strings.Replace(s, "|", `\|`, -1)
+}
+
+// reportTable lays out the assignee report as a table with a column per
+// status category, a row per assignee and a totals row.
+func reportTable(report map[string]AssigneeSummary) (header []string, rows [][]string, totals []string) {
+	return groupedReportTable(report, "Assignee")
+}
+
+// groupedReportTable is reportTable with title as the group column.
+func groupedReportTable(report map[string]AssigneeSummary, title string) (header []string, rows [][]string, totals []string) {
+	columns := statusCategoryColumns(report)
+
+	header = append([]string{title}, columns...)
+	header = append(header, "Issues")
+	// The story points, the logged hours or both, see report logged-time.
+	units := func(s AssigneeSummary) []string {
+		var cells []string
+		if reportsStoryPoints() {
+			cells = append(cells, formatPoints(s.StoryPoints))
+		}
+		if reportsLoggedTime() {
+			cells = append(cells, formatHours(s.Hours))
+		}
+		return cells
+	}
+	if reportsStoryPoints() {
+		header = append(header, "Story Points")
+	}
+	if reportsLoggedTime() {
+		header = append(header, "Hours")
+	}
+
+	total := AssigneeSummary{StatusCategories: make(map[string]int)}
+	for _, assignee := range sortedAssignees(report) {
+		summary := report[assignee]
+		row := []string{assignee}
+		for _, category := range columns {
+			row = append(row, strconv.Itoa(summary.StatusCategories[category]))
+			total.StatusCategories[category] += summary.StatusCategories[category]
+		}
+		row = append(row, strconv.Itoa(summary.Issues))
+		rows = append(rows, append(row, units(summary)...))
+
+		total.Issues += summary.Issues
+		total.StoryPoints += summary.StoryPoints
+		total.Hours += summary.Hours
+	}
+
+	totals = []string{"Total"}
+	for _, category := range columns {
+		totals = append(totals, strconv.Itoa(total.StatusCategories[category]))
+	}
+	totals = append(totals, strconv.Itoa(total.Issues))
+	totals = append(totals, units(total)...)
+
+	return header, rows, totals
+}
+
+// renderMarkdown renders the assignee report as a Markdown table with a
+// column per status category and a totals row.
+func renderMarkdown(report map[string]AssigneeSummary) string {
+	return renderGroupedMarkdown(report, "Assignee")
+}
+
+// renderGroupedMarkdown is renderMarkdown with title as the group column.
+func renderGroupedMarkdown(report map[string]AssigneeSummary, title string) string {
+	header, rows, totals := groupedReportTable(report, title)
+
+	var buf bytes.Buffer
+	writeRow := func(cells ...string) {
+		buf.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+
+	writeRow(header...)
+	separator := make([]string, len(header))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	writeRow(separator...)
+
+	for _, row := range rows {
+		row[0] = escapeMarkdownCell(row[0])
+		writeRow(row...)
+	}
+
+	for i := range totals {
+		totals[i] = fmt.Sprintf("**%s**", totals[i])
+	}
+	writeRow(totals...)
+
+	return buf.String()
+}
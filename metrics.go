@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// metricsFileEnv tells a scheduled command where to leave its metrics, so
+// that the scheduler serving them can add them up.
+const metricsFileEnv = "WORK_REPORTER_METRICS_FILE"
+
+// The upper bounds of the Jira request duration buckets in seconds.
+var requestDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// runMetrics are the counters of a run, served in the Prometheus text
+// format. They are marshalled to pass them from a scheduled command to the
+// scheduler.
+type runMetrics struct {
+	mu sync.Mutex
+
+	SprintsCreated float64 `json:"sprints_created"`
+	IssuesMoved    float64 `json:"issues_moved"`
+	// RunFailures is counted by the scheduler, a failed command can't.
+	RunFailures float64 `json:"run_failures"`
+	// RequestDurationCounts counts the requests in each bucket, the last
+	// one is +Inf. They are not cumulative.
+	RequestDurationCounts []uint64 `json:"request_duration_counts"`
+	RequestDurationSum    float64  `json:"request_duration_sum"`
+}
+
+var metrics = newRunMetrics()
+
+func newRunMetrics() *runMetrics {
+	return &runMetrics{RequestDurationCounts: make([]uint64, len(requestDurationBuckets)+1)}
+}
+
+func (m *runMetrics) addSprintsCreated(n int) {
+	m.mu.Lock()
+	m.SprintsCreated += float64(n)
+	m.mu.Unlock()
+}
+
+func (m *runMetrics) addIssuesMoved(n int) {
+	m.mu.Lock()
+	m.IssuesMoved += float64(n)
+	m.mu.Unlock()
+}
+
+func (m *runMetrics) addRunFailure() {
+	m.mu.Lock()
+	m.RunFailures++
+	m.mu.Unlock()
+}
+
+func (m *runMetrics) observeRequest(d time.Duration) {
+	seconds := d.Seconds()
+	i := 0
+	for i < len(requestDurationBuckets) && seconds > requestDurationBuckets[i] {
+		i++
+	}
+
+	m.mu.Lock()
+	m.RequestDurationCounts[i]++
+	m.RequestDurationSum += seconds
+	m.mu.Unlock()
+}
+
+// merge adds the counters of other.
+func (m *runMetrics) merge(other *runMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.SprintsCreated += other.SprintsCreated
+	m.IssuesMoved += other.IssuesMoved
+	m.RunFailures += other.RunFailures
+	for i := range m.RequestDurationCounts {
+		if i < len(other.RequestDurationCounts) {
+			m.RequestDurationCounts[i] += other.RequestDurationCounts[i]
+		}
+	}
+	m.RequestDurationSum += other.RequestDurationSum
+}
+
+// render writes the metrics in the Prometheus text exposition format.
+func (m *runMetrics) render() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var buf bytes.Buffer
+	counter := func(name, help string, value float64) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s counter\n%s %s\n", name, help, name, name, formatMetric(value))
+	}
+	counter("workreporter_sprints_created_total", "Sprints created in Jira.", m.SprintsCreated)
+	counter("workreporter_issues_moved_total", "Issues moved to another sprint.", m.IssuesMoved)
+	counter("workreporter_run_failures_total", "Runs which failed.", m.RunFailures)
+
+	const name = "workreporter_jira_request_duration_seconds"
+	fmt.Fprintf(&buf, "# HELP %s Duration of the Jira requests.\n# TYPE %s histogram\n", name, name)
+	var count uint64
+	for i, bound := range requestDurationBuckets {
+		count += m.RequestDurationCounts[i]
+		fmt.Fprintf(&buf, "%s_bucket{le=\"%s\"} %d\n", name, formatMetric(bound), count)
+	}
+	count += m.RequestDurationCounts[len(requestDurationBuckets)]
+	fmt.Fprintf(&buf, "%s_bucket{le=\"+Inf\"} %d\n", name, count)
+	fmt.Fprintf(&buf, "%s_sum %s\n%s_count %d\n", name, formatMetric(m.RequestDurationSum), name, count)
+	return buf.String()
+}
+
+func formatMetric(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func (m *runMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(m.render()))
+}
+
+// serveMetrics serves the metrics on /metrics at addr in the background.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Error("serving metrics failed", "listen", addr, "err", err)
+		}
+	}()
+}
+
+// writeMetricsFile leaves the metrics of a scheduled command in the file
+// of metricsFileEnv, if it is set.
+func writeMetricsFile() {
+	path := os.Getenv(metricsFileEnv)
+	if len(path) == 0 {
+		return
+	}
+
+	metrics.mu.Lock()
+	data, err := json.Marshal(metrics)
+	metrics.mu.Unlock()
+	if err == nil {
+		err = ioutil.WriteFile(path, data, 0600)
+	}
+	if err != nil {
+		logger.Warn("writing metrics failed", "path", path, "err", err)
+	}
+}
+
+// readMetricsFile adds the metrics left by a scheduled command and removes
+// the file.
+func readMetricsFile(path string) error {
+	defer os.Remove(path)
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+		// The command died before it could write them.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	other := newRunMetrics()
+	if err = json.Unmarshal(data, other); err != nil {
+		return err
+	}
+	metrics.merge(other)
+	return nil
+}
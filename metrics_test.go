@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunMetricsRender(t *testing.T) {
+	m := newRunMetrics()
+	m.addSprintsCreated(1)
+	m.addIssuesMoved(12)
+	m.observeRequest(30 * time.Millisecond)
+	m.observeRequest(3 * time.Second)
+	m.observeRequest(time.Minute)
+
+	text := m.render()
+	for _, line := range []string{
+		"# TYPE workreporter_sprints_created_total counter",
+		"workreporter_sprints_created_total 1",
+		"workreporter_issues_moved_total 12",
+		"workreporter_run_failures_total 0",
+		"# TYPE workreporter_jira_request_duration_seconds histogram",
+		`workreporter_jira_request_duration_seconds_bucket{le="0.05"} 1`,
+		`workreporter_jira_request_duration_seconds_bucket{le="2.5"} 1`,
+		`workreporter_jira_request_duration_seconds_bucket{le="5"} 2`,
+		`workreporter_jira_request_duration_seconds_bucket{le="+Inf"} 3`,
+		"workreporter_jira_request_duration_seconds_count 3",
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("expect line %q in\n%s", line, text)
+		}
+	}
+}
+
+func TestMetricsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metrics.json")
+
+	oldMetrics := metrics
+	defer func() { metrics = oldMetrics }()
+
+	// The scheduled command leaves its metrics.
+	metrics = newRunMetrics()
+	metrics.addSprintsCreated(2)
+	metrics.observeRequest(time.Second)
+	os.Setenv(metricsFileEnv, path)
+	writeMetricsFile()
+	os.Unsetenv(metricsFileEnv)
+
+	// The scheduler adds them to its own.
+	metrics = newRunMetrics()
+	metrics.addSprintsCreated(1)
+	if err = readMetricsFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if metrics.SprintsCreated != 3 || metrics.RequestDurationCounts[4] != 1 {
+		t.Fatalf("expect the merged metrics, got %+v", metrics)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expect the metrics file to be removed")
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// sprintWindowTolerance is how far apart the starts or the ends of the
+// sprints of a multi-board report may be, e.g. when the boards rotate at
+// different times of the same day.
+const sprintWindowTolerance = 24 * time.Hour
+
+// boardSprint is the active sprint of one of the boards of a report.
+type boardSprint struct {
+	boardID int
+	sprint  jira.Sprint
+}
+
+// activeBoardSprints returns the current sprint of the project on each of
+// the boards, see getCurrentSprint. A sprint shared by several boards is only returned for the first.
+func activeBoardSprints(project string, boardIDs []int) ([]boardSprint, error) {
+	var sprints []boardSprint
+	seen := make(map[int]bool)
+	for _, boardID := range boardIDs {
+		sprint, err := getCurrentSprint(project, boardID)
+		if err != nil {
+			return nil, err
+		}
+		if seen[sprint.ID] {
+			continue
+		}
+		seen[sprint.ID] = true
+		sprints = append(sprints, boardSprint{boardID: boardID, sprint: *sprint})
+	}
+	return sprints, nil
+}
+
+// checkSprintWindows returns a warning for every sprint whose dates differ
+// from the ones of the first sprint by more than sprintWindowTolerance,
+// their issues are reported as one sprint.
+func checkSprintWindows(sprints []boardSprint) []string {
+	if len(sprints) < 2 {
+		return nil
+	}
+	first := sprints[0].sprint
+	if first.StartDate == nil || first.EndDate == nil {
+		return []string{fmt.Sprintf("sprint %s on board %d has no dates to compare", first.Name, sprints[0].boardID)}
+	}
+
+	var warnings []string
+	for _, s := range sprints[1:] {
+		sprint := s.sprint
+		if sprint.StartDate == nil || sprint.EndDate == nil {
+			warnings = append(warnings, fmt.Sprintf("sprint %s on board %d has no dates to compare", sprint.Name, s.boardID))
+			continue
+		}
+		if absDuration(sprint.StartDate.Sub(*first.StartDate)) > sprintWindowTolerance ||
+			absDuration(sprint.EndDate.Sub(*first.EndDate)) > sprintWindowTolerance {
+			warnings = append(warnings, fmt.Sprintf("sprint %s on board %d (%s) is not aligned with sprint %s on board %d (%s)",
+				sprint.Name, s.boardID, formatSprintWindow(sprint), first.Name, sprints[0].boardID, formatSprintWindow(first)))
+		}
+	}
+	return warnings
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// uniqueIssues returns the issues without the ones whose key came before,
+// an issue shown on several boards is reported once.
+func uniqueIssues(issues []jira.Issue) []jira.Issue {
+	seen := make(map[string]bool, len(issues))
+	unique := issues[:0]
+	for _, issue := range issues {
+		if seen[issue.Key] {
+			continue
+		}
+		seen[issue.Key] = true
+		unique = append(unique, issue)
+	}
+	return unique
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SprintReport is the sprint report of one run, for one or more projects. Every
+// Notifier renders it in the format of its channel.
+type SprintReport struct {
+	Projects []projectReport
+}
+
+// Subject names the report, like the subject of a mail.
+func (r SprintReport) Subject() string {
+	if len(r.Projects) == 1 {
+		return fmt.Sprintf("Sprint report: %s", sprintTitle(r.Projects[0].sprint))
+	}
+	names := make([]string, 0, len(r.Projects))
+	for _, p := range r.Projects {
+		names = append(names, p.project)
+	}
+	return fmt.Sprintf("Sprint report: %s", strings.Join(names, ", "))
+}
+
+// Notifier delivers the sprint report to a channel.
+type Notifier interface {
+	// Name names the channel in the run summary.
+	Name() string
+	Send(ctx context.Context, report SprintReport) error
+}
+
+// notifierNames are the channels of report notify, in the order they are
+// sent to by default.
+var notifierNames = []string{"slack", "teams", "email"}
+
+func isNotifierName(name string) bool {
+	for _, n := range notifierNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// notifierConfigured tells whether the channel is set up in c.
+func notifierConfigured(c *Config, name string) bool {
+	switch name {
+	case "slack":
+		return len(c.Slack.Webhook) > 0 || len(c.Slack.Channel) > 0
+	case "teams":
+		return len(c.MSTeams.Webhook) > 0
+	case "email":
+		return len(c.Email.To) > 0
+	}
+	return false
+}
+
+func newNotifier(name string) Notifier {
+	switch name {
+	case "slack":
+		return slackNotifier{}
+	case "teams":
+		return teamsNotifier{webhook: config.MSTeams.Webhook}
+	case "email":
+		return emailNotifier{}
+	}
+	return nil
+}
+
+// configuredNotifiers returns the notifiers of the channels in report
+// notify, or of every configured channel without it.
+func configuredNotifiers() []Notifier {
+	names := config.Report.Notify
+	if len(names) == 0 {
+		for _, name := range notifierNames {
+			if notifierConfigured(config, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	notifiers := make([]Notifier, 0, len(names))
+	for _, name := range names {
+		notifiers = append(notifiers, newNotifier(name))
+	}
+	return notifiers
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func notifierNamesOf(notifiers []Notifier) []string {
+	var names []string
+	for _, n := range notifiers {
+		names = append(names, n.Name())
+	}
+	return names
+}
+
+func TestConfiguredNotifiers(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	if notifiers := configuredNotifiers(); len(notifiers) != 0 {
+		t.Fatalf("expect no channel, got %v", notifierNamesOf(notifiers))
+	}
+
+	config.Slack.Webhook = "https://hooks.slack.com/services/x"
+	config.MSTeams.Webhook = "https://example.webhook.office.com/x"
+	config.Email.To = []string{"team@example.com"}
+	if names := notifierNamesOf(configuredNotifiers()); !reflect.DeepEqual(names, []string{"slack", "teams", "email"}) {
+		t.Fatalf("expect every configured channel, got %v", names)
+	}
+
+	config.Report.Notify = []string{"email", "slack"}
+	if names := notifierNamesOf(configuredNotifiers()); !reflect.DeepEqual(names, config.Report.Notify) {
+		t.Fatalf("expect the channels of report notify, got %v", names)
+	}
+}
+
+func TestValidateNotify(t *testing.T) {
+	c := &Config{}
+	c.Jira.Endpoint = "https://jira.example.com"
+	c.Jira.Project = "TEST"
+	c.Report.Notify = []string{"slack", "pager"}
+	err := c.Validate()
+	if err == nil || !strings.Contains(err.Error(), `report notify "slack" is not configured`) ||
+		!strings.Contains(err.Error(), `report notify "pager" must be one of slack, teams, email`) {
+		t.Fatalf("expect the notify problems, got %v", err)
+	}
+}
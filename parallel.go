@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+const defaultWorkers = 4
+
+// projectError is the failure of one project in a parallel run.
+type projectError struct {
+	project string
+	err     error
+}
+
+func (e projectError) Error() string {
+	return fmt.Sprintf("[%s] %v", e.project, e.err)
+}
+
+// forEachProject calls fn for every project on at most workers goroutines.
+// A failed project does not stop the others, the errors are returned in
+// the order of the projects. fn gets the index of the project so it can
+// store its result without locking.
+//
+// The goroutines share jiraClient and jiraLimiter, so the limiter throttles
+// the aggregate request rate.
+func forEachProject(projects []string, workers int, fn func(i int, project string) error) []error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	errs := make([]error, len(projects))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, project := range projects {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, project string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fn(i, project); err != nil {
+				errs[i] = projectError{project: project, err: err}
+			}
+		}(i, project)
+	}
+	wg.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestForEachProject(t *testing.T) {
+	projects := []string{"A", "B", "C", "D", "E"}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	results := make([]string, len(projects))
+	errs := forEachProject(projects, 2, func(i int, project string) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		if project == "B" || project == "D" {
+			return fmt.Errorf("board not found")
+		}
+		results[i] = project
+		return nil
+	})
+
+	if maxRunning > 2 {
+		t.Fatalf("expect at most 2 workers, got %d", maxRunning)
+	}
+	if len(errs) != 2 || errs[0].Error() != "[B] board not found" || errs[1].Error() != "[D] board not found" {
+		t.Fatalf("expect the errors of B and D, got %v", errs)
+	}
+	if results[0] != "A" || results[2] != "C" || results[4] != "E" {
+		t.Fatalf("expect the other projects to finish, got %v", results)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// sprintProgress is how much of the sprint is done, the first line of the
+// reports.
+type sprintProgress struct {
+	Sprint     string
+	Issues     int
+	Done       int
+	Points     float64
+	DonePoints float64
+}
+
+// newSprintProgress counts the done issues and their story points, the
+// subtasks are left out like in the report totals.
+func newSprintProgress(sprint jira.Sprint, issues []jira.Issue) sprintProgress {
+	p := sprintProgress{Sprint: sprint.Name}
+	for _, issue := range issues {
+		if isSubtask(issue) && !config.Report.IncludeSubtasks {
+			continue
+		}
+		points, _ := storyPoints(issue)
+		p.Issues++
+		p.Points += points
+		if isDone(issue) {
+			p.Done++
+			p.DonePoints += points
+		}
+	}
+	return p
+}
+
+// pointsPercent is the share of the story points done, 0 without points.
+func (p sprintProgress) pointsPercent() int {
+	if p.Points <= 0 {
+		return 0
+	}
+	return int(p.DonePoints * 100 / p.Points)
+}
+
+// String renders the progress like "Sprint TIKV 12: 3 of 8 issues done (40%
+// of points)".
+func (p sprintProgress) String() string {
+	return fmt.Sprintf("Sprint %s: %d of %d issues done (%d%% of points)", p.Sprint, p.Done, p.Issues, p.pointsPercent())
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestSprintProgress(t *testing.T) {
+	config = &Config{}
+	config.Jira.StoryPointField = "customfield_10001"
+	defer func() { config = nil }()
+
+	sprint := jira.Sprint{Name: "TEST 1"}
+	subtask := newReportIssue("TEST-4", "alice", "Done", 5)
+	subtask.Fields.Type.Subtask = true
+	p := newSprintProgress(sprint, []jira.Issue{
+		newReportIssue("TEST-1", "alice", "Done", 3),
+		newReportIssue("TEST-2", "alice", "In Progress", 5),
+		newReportIssue("TEST-3", "bob", "To Do", -1),
+		subtask,
+	})
+	if expect := "Sprint TEST 1: 1 of 3 issues done (37% of points)"; p.String() != expect {
+		t.Fatalf("expect %q, got %q", expect, p)
+	}
+
+	empty := newSprintProgress(sprint, nil)
+	if expect := "Sprint TEST 1: 0 of 0 issues done (0% of points)"; empty.String() != expect {
+		t.Fatalf("expect %q, got %q", expect, empty)
+	}
+
+	r := projectReport{project: "TEST", sprint: sprint, report: map[string]AssigneeSummary{}, progress: p}
+	if out := renderProjectMarkdown(r); !strings.HasPrefix(out, p.String()+"\n") {
+		t.Fatalf("expect the progress first, got %q", out)
+	}
+	r.carryover = CarryoverSummary{Issues: 4, Carryover: 1}
+	if out := renderProjectText(r); !strings.HasPrefix(out, p.String()+"\n"+r.carryover.String()+"\n") {
+		t.Fatalf("expect the progress, then the carryover, got %q", out)
+	}
+	if progress := buildJSONReport(r).Progress; progress.Done != 1 || progress.StoryPoints != 8 || progress.PointsPercent != 37 {
+		t.Fatalf("unexpected JSON progress %+v", progress)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket, it lets rate requests per second pass
+// with bursts up to the bucket size.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// jiraLimiter limits all requests sent by doWithRetry, nil means no limit.
+var jiraLimiter *rateLimiter
+
+// newRateLimiter creates a limiter for rate requests per second, it
+// returns nil if rate is not positive.
+func newRateLimiter(rate float64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	burst := math.Max(1, math.Ceil(rate))
+	return &rateLimiter{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long to wait before using it.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	// The token may be borrowed from the future, the waiters queue up.
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// Wait blocks until the request may be sent or ctx is done.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	if l := newRateLimiter(0); l != nil {
+		t.Fatal("expect no limiter without a rate")
+	}
+	if err := (*rateLimiter)(nil).Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	l := newRateLimiter(2)
+	for i := 0; i < 2; i++ {
+		if delay := l.reserve(); delay != 0 {
+			t.Fatalf("expect the burst to pass, got delay %s", delay)
+		}
+	}
+	if delay := l.reserve(); delay < 400*time.Millisecond || delay > 500*time.Millisecond {
+		t.Fatalf("expect about 500ms delay, got %s", delay)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Fatalf("expect context canceled, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// SprintRealignment is a sprint whose dates drifted off the cadence, with
+// the dates it gets back.
+type SprintRealignment struct {
+	Sprint jira.Sprint
+	Start  time.Time
+	End    time.Time
+}
+
+func (r SprintRealignment) String() string {
+	return fmt.Sprintf("%s (%d): %s - %s -> %s - %s", r.Sprint.Name, r.Sprint.ID,
+		r.Sprint.StartDate.Format(dateFormat), r.Sprint.EndDate.Format(dateFormat),
+		r.Start.Format(dateFormat), r.End.Format(dateFormat))
+}
+
+// cadenceStart returns the start of the sprint of the cadence from anchor
+// which is nearest to t. Whole days are stepped on the calendar of the
+// anchor, like sprintEnd does.
+func cadenceStart(anchor, t time.Time) time.Time {
+	d := sprintDuration()
+	n := int(t.Sub(anchor).Round(d) / d)
+	if d%(24*time.Hour) == 0 {
+		return anchor.AddDate(0, 0, n*int(d/(24*time.Hour)))
+	}
+	return anchor.Add(time.Duration(n) * d)
+}
+
+// realignSprints moves the future sprints of the project on the board, and
+// the active ones too if includeActive is set, back on the cadence of
+// sprint-duration sprints starting at anchor. Closed sprints, sprints
+// without dates and the sprints of the other projects sharing the board are
+// left alone, and so is a sprint whose slot is taken by an earlier one. It
+// returns the sprints it realigned, or would realign in dry-run mode.
+func realignSprints(project string, boardID int, anchor time.Time, includeActive bool) ([]SprintRealignment, error) {
+	states := []string{"future"}
+	if includeActive {
+		states = append(states, "active")
+	}
+	sprints, err := getSprintsInStates(boardID, states...)
+	if err != nil {
+		return nil, err
+	}
+
+	var realigned []SprintRealignment
+	taken := make(map[int64]bool)
+	for _, sprint := range sprints {
+		// Don't trust the state filter blindly, the dates of a closed
+		// sprint are its history.
+		if !containsState(states, sprint.State) || sprint.StartDate == nil || sprint.EndDate == nil {
+			continue
+		}
+		if !sprintBelongsToProject(sprint, project) {
+			continue
+		}
+
+		start := cadenceStart(anchor, *sprint.StartDate)
+		if taken[start.Unix()] {
+			logger.Warn("sprint not realigned, its slot is taken", "sprint_id", sprint.ID, "start", start)
+			continue
+		}
+		taken[start.Unix()] = true
+
+		end := sprintEnd(start)
+		// Jira keeps the dates to the second at best.
+		if absDuration(sprint.StartDate.Sub(start)) < time.Minute && absDuration(sprint.EndDate.Sub(end)) < time.Minute {
+			continue
+		}
+		if _, err = updateSprintTime(sprint.ID, start.Format(dateFormat), end.Format(dateFormat)); err != nil {
+			return realigned, err
+		}
+		realigned = append(realigned, SprintRealignment{Sprint: sprint, Start: start, End: end})
+	}
+	return realigned, nil
+}
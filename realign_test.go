@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestRealignSprints(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	anchor := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+	sprint := func(id int, state string, start time.Time) jira.Sprint {
+		end := start.Add(week)
+		return jira.Sprint{ID: id, Name: "TEST", State: state, StartDate: &start, EndDate: &end}
+	}
+	f.addSprint(sprint(1, "closed", anchor.Add(2*time.Hour)))
+	f.addSprint(sprint(2, "active", anchor.Add(week+3*time.Hour)))
+	// Drifted a day early and a day late.
+	f.addSprint(sprint(3, "future", anchor.Add(2*week-24*time.Hour)))
+	f.addSprint(sprint(4, "future", anchor.Add(3*week+24*time.Hour)))
+	f.addSprint(sprint(5, "future", anchor.Add(4*week)))
+	// Another project shares the board.
+	other := sprint(6, "future", anchor.Add(5*week+24*time.Hour))
+	other.Name = "API"
+	f.addSprint(other)
+
+	realigned, err := realignSprints("TEST", 1, anchor, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(realigned) != 2 || realigned[0].Sprint.ID != 3 || realigned[1].Sprint.ID != 4 {
+		t.Fatalf("expect sprints 3 and 4 realigned, got %v", realigned)
+	}
+	for id, start := range map[int]time.Time{3: anchor.Add(2 * week), 4: anchor.Add(3 * week)} {
+		if s := f.sprint(id); !s.StartDate.Equal(start) || !s.EndDate.Equal(start.Add(week)) {
+			t.Fatalf("expect sprint %d on %s, got %s - %s", id, start, s.StartDate, s.EndDate)
+		}
+	}
+	if s := f.sprint(1); !s.StartDate.Equal(anchor.Add(2 * time.Hour)) {
+		t.Fatalf("expect the closed sprint untouched, got %s", s.StartDate)
+	}
+	if s := f.sprint(2); !s.StartDate.Equal(anchor.Add(week + 3*time.Hour)) {
+		t.Fatalf("expect the active sprint untouched, got %s", s.StartDate)
+	}
+	if n := f.countRequests("POST"); n != 2 {
+		t.Fatalf("expect 2 sprint updates, got %d", n)
+	}
+	if s := f.sprint(6); !s.StartDate.Equal(anchor.Add(5*week + 24*time.Hour)) {
+		t.Fatalf("expect the sprint of the other project untouched, got %s", s.StartDate)
+	}
+
+	realigned, err = realignSprints("TEST", 1, anchor, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(realigned) != 1 || realigned[0].Sprint.ID != 2 {
+		t.Fatalf("expect the active sprint realigned, got %v", realigned)
+	}
+}
+
+func TestRealignSprintsDryRun(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	config.DryRun = true
+
+	anchor := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+	start := anchor.Add(30 * time.Hour)
+	end := start.Add(7 * 24 * time.Hour)
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST", State: "future", StartDate: &start, EndDate: &end})
+
+	realigned, err := realignSprints("TEST", 1, anchor, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(realigned) != 1 || !realigned[0].Start.Equal(anchor) {
+		t.Fatalf("expect the intended realignment, got %v", realigned)
+	}
+	if n := f.countRequests("POST"); n != 0 {
+		t.Fatalf("expect no writes in dry-run, got %d", n)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+const (
+	defaultUnassignedLabel = "Unassigned"
+	noComponentLabel       = "No Component"
+	doneStatusCategory     = "Done"
+)
+
+// AssigneeSummary aggregates the issues assigned to one person.
+type AssigneeSummary struct {
+	Assignee    string
+	Issues      int
+	StoryPoints float64
+	// Unestimated counts the issues without story points, they are not
+	// included in StoryPoints.
+	Unestimated int
+	// StatusCategories counts the issues by status category name, like
+	// "To Do", "In Progress" and "Done".
+	StatusCategories map[string]int
+	// Done counts the completed issues, see isDone.
+	Done int
+	// AvatarURL is only set in the assignee report, when Jira has one.
+	AvatarURL string
+	// Capacity is the story points the assignee can take on, only set in
+	// the assignee report when configured, see assigneeCapacity.
+	Capacity float64
+	// Hours is the time logged on the issues, see loggedHours.
+	Hours float64
+}
+
+// buildAssigneeReport groups the issues by assignee. Unassigned issues are
+// put under the configured unassigned label.
+func buildAssigneeReport(issues []jira.Issue) map[string]AssigneeSummary {
+	report := buildGroupedReport(issues, func(issue jira.Issue) []string {
+		return []string{issueAssignee(issue)}
+	})
+	for _, issue := range issues {
+		assignee := issueAssigneeUser(issue)
+		if assignee == nil {
+			continue
+		}
+		name := issueAssignee(issue)
+		summary, ok := report[name]
+		if !ok {
+			// Only subtasks, which are left out.
+			continue
+		}
+		if len(assignee.AvatarUrls.Four8X48) > 0 {
+			summary.AvatarURL = assignee.AvatarUrls.Four8X48
+		}
+		if summary.Capacity == 0 {
+			summary.Capacity = assigneeCapacity(assignee)
+		}
+		report[name] = summary
+	}
+	return report
+}
+
+// buildComponentReport groups the issues by component, the summaries keep
+// the component in Assignee. An issue with several components is counted
+// in each of them.
+func buildComponentReport(issues []jira.Issue) map[string]AssigneeSummary {
+	return buildGroupedReport(issues, issueComponents)
+}
+
+// excludeIssueTypes returns the issues without the ones of the report
+// exclude-types, matched by name ignoring the case.
+func excludeIssueTypes(issues []jira.Issue) []jira.Issue {
+	if len(config.Report.ExcludeTypes) == 0 {
+		return issues
+	}
+	var kept []jira.Issue
+	for _, issue := range issues {
+		if !isExcludedType(issue) {
+			kept = append(kept, issue)
+		}
+	}
+	return kept
+}
+
+func isExcludedType(issue jira.Issue) bool {
+	if issue.Fields == nil {
+		return false
+	}
+	for _, name := range config.Report.ExcludeTypes {
+		if strings.EqualFold(name, issue.Fields.Type.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildGroupedReport sums the issues of each group. Subtasks are left out
+// unless report include-subtasks is set, their points are usually counted
+// in their parent too.
+func buildGroupedReport(issues []jira.Issue, groups func(jira.Issue) []string) map[string]AssigneeSummary {
+	report := make(map[string]AssigneeSummary)
+
+	for _, issue := range issues {
+		if isSubtask(issue) && !config.Report.IncludeSubtasks {
+			continue
+		}
+		for _, group := range groups(issue) {
+			summary, ok := report[group]
+			if !ok {
+				summary = AssigneeSummary{
+					Assignee:         group,
+					StatusCategories: make(map[string]int),
+				}
+			}
+
+			summary.Issues++
+			summary.Hours += loggedHours(issue)
+			if points, ok := storyPoints(issue); ok {
+				summary.StoryPoints += points
+			} else {
+				summary.Unestimated++
+			}
+			summary.StatusCategories[issueStatusCategory(issue)]++
+			if isDone(issue) {
+				summary.Done++
+			}
+
+			report[group] = summary
+		}
+	}
+
+	return report
+}
+
+func issueComponents(issue jira.Issue) []string {
+	if issue.Fields == nil || len(issue.Fields.Components) == 0 {
+		return []string{noComponentLabel}
+	}
+
+	components := make([]string, 0, len(issue.Fields.Components))
+	for _, component := range issue.Fields.Components {
+		if component != nil {
+			components = append(components, component.Name)
+		}
+	}
+	return components
+}
+
+func issueAssignee(issue jira.Issue) string {
+	if issue.Fields == nil || issue.Fields.Assignee == nil {
+		if label := config.Report.UnassignedLabel; len(label) > 0 {
+			return label
+		}
+		return defaultUnassignedLabel
+	}
+
+	assignee := issue.Fields.Assignee
+	if len(assignee.DisplayName) > 0 {
+		return assignee.DisplayName
+	}
+	// See resolveAssignees, the users Jira doesn't know keep their ID.
+	if len(assignee.Name) > 0 {
+		return assignee.Name
+	}
+	return userID(assignee)
+}
+
+// issueStatusCategory returns the status category name of the issue, an
+// issue in one of the configured done statuses is put under Done.
+func issueStatusCategory(issue jira.Issue) string {
+	if isDone(issue) {
+		return doneStatusCategory
+	}
+	if issue.Fields == nil || issue.Fields.Status == nil || len(issue.Fields.Status.StatusCategory.Name) == 0 {
+		return "Unknown"
+	}
+	return issue.Fields.Status.StatusCategory.Name
+}
+
+// isDone tells if the issue is completed: it is in the Done status category
+// or its status is listed in DoneStatuses, for workflows where statuses like
+// "Released" are done though they are not in the category. The category is
+// compared by key, its name is translated.
+func isDone(issue jira.Issue) bool {
+	if issue.Fields == nil || issue.Fields.Status == nil {
+		return false
+	}
+	status := issue.Fields.Status
+	if status.StatusCategory.Key == jira.StatusCategoryComplete {
+		return true
+	}
+	for _, name := range config.Jira.DoneStatuses {
+		if strings.EqualFold(name, status.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// storyPoints reads the configured story point field of the issue, ok is
+// false if the field is not configured, missing or not a number.
+func storyPoints(issue jira.Issue) (float64, bool) {
+	field := config.Jira.StoryPointField
+	if len(field) == 0 || issue.Fields == nil {
+		return 0, false
+	}
+
+	switch value := issue.Fields.Unknowns[field].(type) {
+	case float64:
+		return value, true
+	case string:
+		points, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, false
+		}
+		return points, true
+	}
+	return 0, false
+}
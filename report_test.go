@@ -0,0 +1,227 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/trivago/tgo/tcontainer"
+)
+
+// reportCategoryKeys are the keys of the English status category names.
+var reportCategoryKeys = map[string]string{
+	"To Do":       jira.StatusCategoryToDo,
+	"In Progress": jira.StatusCategoryInProgress,
+	"Done":        jira.StatusCategoryComplete,
+}
+
+// newReportIssue creates an issue for report tests, an empty assignee
+// means unassigned and negative points mean no estimate.
+func newReportIssue(key, assignee, category string, points float64) jira.Issue {
+	fields := &jira.IssueFields{
+		Status: &jira.Status{
+			StatusCategory: jira.StatusCategory{Key: reportCategoryKeys[category], Name: category},
+		},
+		Unknowns: tcontainer.MarshalMap{},
+	}
+	if len(assignee) > 0 {
+		fields.Assignee = &jira.User{Name: assignee, DisplayName: assignee}
+	}
+	if points >= 0 {
+		fields.Unknowns["customfield_10001"] = points
+	}
+	return jira.Issue{ID: key, Key: key, Fields: fields}
+}
+
+func TestBuildAssigneeReport(t *testing.T) {
+	config = &Config{}
+	config.Jira.StoryPointField = "customfield_10001"
+	config.Report.UnassignedLabel = "Nobody"
+	defer func() { config = nil }()
+
+	report := buildAssigneeReport([]jira.Issue{
+		newReportIssue("TEST-1", "alice", "Done", 3),
+		newReportIssue("TEST-2", "alice", "In Progress", 2),
+		newReportIssue("TEST-3", "bob", "To Do", -1),
+		newReportIssue("TEST-4", "", "To Do", 1),
+	})
+
+	if len(report) != 3 {
+		t.Fatalf("expect 3 assignees, got %v", report)
+	}
+	alice := report["alice"]
+	if alice.Issues != 2 || alice.StoryPoints != 5 || alice.StatusCategories["Done"] != 1 || alice.StatusCategories["In Progress"] != 1 {
+		t.Fatalf("unexpected summary for alice %+v", alice)
+	}
+	if bob := report["bob"]; bob.Issues != 1 || bob.StoryPoints != 0 || bob.Unestimated != 1 {
+		t.Fatalf("unexpected summary for bob %+v", bob)
+	}
+	if nobody := report["Nobody"]; nobody.Issues != 1 || nobody.StoryPoints != 1 {
+		t.Fatalf("expect unassigned issue under the configured label, got %+v", report)
+	}
+}
+
+func TestBuildAssigneeReportSubtasks(t *testing.T) {
+	config = &Config{}
+	config.Jira.StoryPointField = "customfield_10001"
+	defer func() { config = nil }()
+
+	issues := []jira.Issue{newReportIssue("TEST-1", "alice", "To Do", 5)}
+	for _, key := range []string{"TEST-2", "TEST-3"} {
+		subtask := newReportIssue(key, "alice", "To Do", 2)
+		subtask.Fields.Type.Subtask = true
+		issues = append(issues, subtask)
+	}
+	// bob has only a subtask, so there is no row for bob without the subtasks.
+	bobSubtask := newReportIssue("TEST-4", "bob", "To Do", 1)
+	bobSubtask.Fields.Type.Subtask = true
+	issues = append(issues, bobSubtask)
+
+	report := buildAssigneeReport(issues)
+	if alice := report["alice"]; alice.Issues != 1 || alice.StoryPoints != 5 {
+		t.Fatalf("expect the subtasks left out, got %+v", alice)
+	}
+	if bob, ok := report["bob"]; ok {
+		t.Fatalf("expect no row for an assignee with only subtasks, got %+v", bob)
+	}
+	config.Report.IncludeSubtasks = true
+	if alice := buildAssigneeReport(issues)["alice"]; alice.Issues != 3 || alice.StoryPoints != 9 {
+		t.Fatalf("expect the subtasks counted, got %+v", alice)
+	}
+}
+
+func TestBuildComponentReport(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	both := newReportIssue("TEST-1", "alice", "Done", -1)
+	both.Fields.Components = []*jira.Component{{Name: "API"}, {Name: "Web"}}
+	api := newReportIssue("TEST-2", "bob", "To Do", -1)
+	api.Fields.Components = []*jira.Component{{Name: "API"}}
+
+	report := buildComponentReport([]jira.Issue{both, api, newReportIssue("TEST-3", "bob", "To Do", -1)})
+	if report["API"].Issues != 2 || report["Web"].Issues != 1 || report[noComponentLabel].Issues != 1 {
+		t.Fatalf("unexpected component report %+v", report)
+	}
+	if out := renderGroupedMarkdown(report, "Component"); !strings.HasPrefix(out, "| Component |") {
+		t.Fatalf("expect component column, got %s", out)
+	}
+}
+
+func TestStoryPoints(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	issue := newReportIssue("TEST-1", "alice", "Done", 3)
+	if _, ok := storyPoints(issue); ok {
+		t.Fatal("expect no story points without a configured field")
+	}
+
+	config.Jira.StoryPointField = "customfield_10001"
+	if points, ok := storyPoints(issue); !ok || points != 3 {
+		t.Fatalf("expect 3 points, got %v %v", points, ok)
+	}
+
+	issue.Fields.Unknowns["customfield_10001"] = "2.5"
+	if points, ok := storyPoints(issue); !ok || points != 2.5 {
+		t.Fatalf("expect 2.5 points, got %v %v", points, ok)
+	}
+
+	for _, value := range []interface{}{nil, "", "n/a"} {
+		issue.Fields.Unknowns["customfield_10001"] = value
+		if _, ok := storyPoints(issue); ok {
+			t.Fatalf("expect no story points for %#v", value)
+		}
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	config = &Config{}
+	config.Jira.StoryPointField = "customfield_10001"
+	defer func() { config = nil }()
+
+	report := buildAssigneeReport([]jira.Issue{
+		newReportIssue("TEST-1", "bob", "Done", 3),
+		newReportIssue("TEST-2", "alice", "In Progress", 2.5),
+		newReportIssue("TEST-3", "alice", "Review", 1),
+		newReportIssue("TEST-4", "a|b", "To Do", -1),
+	})
+
+	expect := `| Assignee | To Do | In Progress | Done | Review | Issues | Story Points |
+| --- | --- | --- | --- | --- | --- | --- |
+| alice | 0 | 1 | 0 | 1 | 2 | 3.5 |
+| a\|b | 1 | 0 | 0 | 0 | 1 | 0 |
+| bob | 0 | 0 | 1 | 0 | 1 | 3 |
+| **Total** | **1** | **1** | **1** | **1** | **4** | **6.5** |
+`
+	if got := renderMarkdown(report); got != expect {
+		t.Fatalf("expect\n%s\ngot\n%s", expect, got)
+	}
+}
+
+func TestIsDoneCustomStatus(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	released := newReportIssue("TEST-1", "alice", "In Progress", -1)
+	released.Fields.Status.Name = "Released"
+	done := newReportIssue("TEST-2", "alice", "Done", -1)
+
+	if isDone(released) || !isDone(done) {
+		t.Fatal("expect only the Done category to be done by default")
+	}
+
+	config.Jira.DoneStatuses = []string{"released"}
+	if !isDone(released) {
+		t.Fatal("expect a listed status to be done")
+	}
+	report := buildAssigneeReport([]jira.Issue{released, done})
+	if n := report["alice"].StatusCategories["Done"]; n != 2 {
+		t.Fatalf("expect 2 done issues, got %d", n)
+	}
+}
+
+func TestIsDoneLocalized(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	erledigt := newReportIssue("TEST-1", "alice", "Done", -1)
+	erledigt.Fields.Status.StatusCategory.Name = "Erledigt"
+	notDone := newReportIssue("TEST-2", "alice", "In Progress", -1)
+	notDone.Fields.Status.StatusCategory.Name = "Done"
+
+	if !isDone(erledigt) || isDone(notDone) {
+		t.Fatal("expect the status category compared by key, not by its name")
+	}
+	if alice := buildAssigneeReport([]jira.Issue{erledigt, notDone})["alice"]; alice.Done != 1 {
+		t.Fatalf("expect 1 done issue, got %+v", alice)
+	}
+}
+
+func TestFormatSprintWindow(t *testing.T) {
+	config = &Config{}
+	config.Jira.Timezone = "UTC"
+	defer func() { config = nil }()
+
+	sprint := jira.Sprint{Name: "TEST 1", StartDate: day(10, 5), EndDate: day(10, 12)}
+	if got, expect := sprintTitle(sprint), "TEST 1 (Oct 5 – Oct 11)"; got != expect {
+		t.Fatalf("expect %q, got %q", expect, got)
+	}
+
+	config.Report.DateFormat = "02.01.2006"
+	if got, expect := formatSprintWindow(sprint), "05.10.2018 – 11.10.2018"; got != expect {
+		t.Fatalf("expect %q, got %q", expect, got)
+	}
+
+	start := time.Date(2018, 10, 5, 9, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+	sprint = jira.Sprint{Name: "TEST 1", StartDate: &start, EndDate: &end}
+	if got, expect := formatSprintWindow(sprint), "05.10.2018 – 11.10.2018"; got != expect {
+		t.Fatalf("expect %q for a sprint starting at 09:00, got %q", expect, got)
+	}
+
+	if got := sprintTitle(jira.Sprint{Name: "TEST 2"}); got != "TEST 2" {
+		t.Fatalf("expect only the name without dates, got %q", got)
+	}
+}
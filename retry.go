@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// doWithRetry sends the request like jiraClient.Do, but retries it with
+// exponential backoff when Jira is rate limiting us or temporarily
+// unavailable. A Retry-After header from the server takes precedence over
+// the computed backoff. Requests are throttled by jiraLimiter. A failed
+// request returns a jiraAPIError with the messages from Jira.
+func doWithRetry(req *http.Request, v interface{}) (*jira.Response, error) {
+	return doWithRetryCheck(req, v, nil)
+}
+
+// doWithRetryCheck is doWithRetry for the requests which must not be sent
+// twice. A failed attempt may have taken effect with only its response
+// lost, so before each retry applied tells whether it did, in which case
+// the request is not sent again and no response is returned.
+func doWithRetryCheck(req *http.Request, v interface{}, applied func() (bool, error)) (*jira.Response, error) {
+	if skipInDryRun(req) {
+		return nil, nil
+	}
+
+	retry := config.Jira.Retry
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && applied != nil {
+			ok, err := applied()
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				logger.Info("Jira request took effect, not retrying", "method", req.Method, "url", req.URL.String())
+				return nil, nil
+			}
+		}
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		// The limiter applies to retries too, after any Retry-After wait.
+		if err := jiraLimiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		resp, err := jiraClient.Do(req, v)
+		metrics.observeRequest(time.Since(start))
+		if err == nil || attempt >= retry.MaxRetries || !shouldRetry(resp) {
+			return resp, wrapJiraError(req, resp, err)
+		}
+
+		delay, ok := retryAfter(resp.Response)
+		if !ok {
+			delay = retry.BaseDelay.Duration << uint(attempt)
+		}
+		resp.Body.Close()
+		logger.Warn("retrying Jira request", "method", req.Method, "url", req.URL.String(),
+			"status", resp.StatusCode, "attempt", attempt+1, "delay", delay)
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return resp, req.Context().Err()
+		}
+	}
+}
+
+func shouldRetry(resp *jira.Response) bool {
+	if resp == nil || resp.Response == nil {
+		return false
+	}
+
+	code := resp.StatusCode
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// retryAfter parses the Retry-After header, which is either a number of
+// seconds or an HTTP date. A zero or a past date means to retry at once, ok
+// is false if the header is absent or invalid.
+func retryAfter(resp *http.Response) (delay time.Duration, ok bool) {
+	value := resp.Header.Get("Retry-After")
+	if len(value) == 0 {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if delay = time.Until(t); delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
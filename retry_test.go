@@ -0,0 +1,165 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestDoWithRetry(t *testing.T) {
+	attempts := 0
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch attempts {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			writeJSON(t, w, map[string]string{"name": "TEST Sprint"})
+		}
+	})()
+
+	config.Jira.Retry = Retry{MaxRetries: 3, BaseDelay: Duration{time.Millisecond}}
+
+	sprint, err := updateSprintState(jira.Sprint{ID: 1, State: "future"}, "active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 || sprint.Name != "TEST Sprint" {
+		t.Fatalf("expect success after 3 attempts, got %d attempts, sprint %q", attempts, sprint.Name)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for _, c := range []struct {
+		header string
+		delay  time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"2", 2 * time.Second, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{"Mon, 01 Oct 2018 00:00:00 GMT", 0, true},
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		if len(c.header) > 0 {
+			resp.Header.Set("Retry-After", c.header)
+		}
+		if delay, ok := retryAfter(resp); delay != c.delay || ok != c.ok {
+			t.Errorf("Retry-After %q: expect %s, %v, got %s, %v", c.header, c.delay, c.ok, delay, ok)
+		}
+	}
+}
+
+func TestDoWithRetryGivesUp(t *testing.T) {
+	attempts := 0
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	})()
+
+	config.Jira.Retry = Retry{MaxRetries: 2, BaseDelay: Duration{time.Millisecond}}
+
+	if _, err := updateSprintState(jira.Sprint{ID: 1, State: "future"}, "active"); err == nil {
+		t.Fatal("expect error after exhausting retries")
+	}
+	if attempts != 3 {
+		t.Fatalf("expect 3 attempts, got %d", attempts)
+	}
+}
+
+func TestDoWithRetrySkipsClientErrors(t *testing.T) {
+	attempts := 0
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	})()
+
+	config.Jira.Retry = Retry{MaxRetries: 3, BaseDelay: Duration{time.Millisecond}}
+
+	if _, err := updateSprintState(jira.Sprint{ID: 1, State: "future"}, "active"); err == nil {
+		t.Fatal("expect error for a bad request")
+	}
+	if attempts != 1 {
+		t.Fatalf("expect no retry for a bad request, got %d attempts", attempts)
+	}
+}
+
+func TestDoWithRetryJiraErrorMessages(t *testing.T) {
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			writeJSON(t, w, jira.SprintsList{IsLast: true})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errorMessages":["Sprint name is too long"],"errors":{"originBoardId":"Field 'originBoardId' is required"}}`))
+	})()
+
+	_, err := createSprint(1, "TEST Sprint", "", "2018-01-01", "2018-01-08")
+	apiErr, ok := err.(*jiraAPIError)
+	if !ok {
+		t.Fatalf("expect a jira API error, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expect status 400, got %d", apiErr.StatusCode)
+	}
+	expect := "jira POST /rest/agile/1.0/sprint failed with status 400: Sprint name is too long; originBoardId: Field 'originBoardId' is required"
+	if err.Error() != expect {
+		t.Fatalf("expect %q, got %q", expect, err.Error())
+	}
+}
+
+func TestDoWithRetryPlainErrorBody(t *testing.T) {
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Bad Gateway from proxy", http.StatusBadGateway)
+	})()
+
+	_, err := updateSprintState(jira.Sprint{ID: 1, State: "future"}, "active")
+	if err == nil || !strings.Contains(err.Error(), "status 502: Bad Gateway from proxy") {
+		t.Fatalf("expect the body in the error, got %v", err)
+	}
+}
+
+func TestCreateSprintRetryAfterLostResponse(t *testing.T) {
+	f, cleanup := newFakeJira(t)
+	defer cleanup()
+	config.Jira.Retry = Retry{MaxRetries: 3, BaseDelay: Duration{time.Millisecond}}
+
+	posts := 0
+	fake := f.serveHTTP
+	jiraClient = handlerJira{http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			fake(w, r)
+			return
+		}
+		posts++
+		// The sprint is created, but the proxy times out.
+		fake(httptest.NewRecorder(), r)
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})}
+
+	sprint, err := createSprint(1, "TEST 1", "", "2018-01-01T00:00:00Z", "2018-01-08T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posts != 1 || len(f.sprints) != 1 || sprint.ID != f.sprints[0].ID {
+		t.Fatalf("expect the sprint created once, got %d posts, sprints %v", posts, f.sprints)
+	}
+
+	// Running again finds it before posting.
+	if sprint, err = createSprint(1, "TEST 1", "", "2018-01-01T00:00:00Z", "2018-01-08T00:00:00Z"); err != nil {
+		t.Fatal(err)
+	}
+	if posts != 1 || sprint.ID != f.sprints[0].ID {
+		t.Fatalf("expect the existing sprint, got %d posts, sprint %d", posts, sprint.ID)
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// RolloverSummary describes what rolloverSprint did.
+type RolloverSummary struct {
+	ClosedSprint jira.Sprint
+	ActiveSprint jira.Sprint
+	MovedIssues  int
+	// FailedBatches are the batches of issues which could not be moved.
+	FailedBatches []BatchError
+	// LabeledIssues got the carryover label.
+	LabeledIssues int
+	// MissingIssues were accepted by Jira but are not in the next sprint
+	// afterwards. Subtasks can't be moved without their parent, so they
+	// end up here if the parent stays behind.
+	MissingIssues []jira.Issue
+	// Skipped is true if the active sprint was not due for rollover,
+	// e.g. the rollover has been done already.
+	Skipped bool
+	// Resumed is true if an earlier rollover closed the sprint but failed
+	// to activate the next one, and this one only activated it.
+	Resumed bool
+}
+
+// rolloverSprint closes the active sprint of the board, moves its
+// incomplete issues to the next sprint and activates the next sprint.
+//
+// Running it twice is safe: the next sprint is reused if it exists, the
+// issues moved already are in it, and a sprint which has not passed its
+// midpoint yet is considered just rolled over and left alone. A rollover
+// which failed after closing the sprint is resumed, see resumeRollover.
+func rolloverSprint(project string, boardID int) (RolloverSummary, error) {
+	var summary RolloverSummary
+
+	activeSprint, err := findActiveSprint(project, boardID)
+	if err != nil {
+		return summary, err
+	}
+	if activeSprint == nil {
+		return resumeRollover(project, boardID)
+	}
+	// Closing the sprint would fail after the issues were moved.
+	if activeSprint.State != "active" {
+		return summary, fmt.Errorf("sprint %s is %s, only an active sprint can be rolled over", activeSprint.Name, activeSprint.State)
+	}
+	if activeSprint.StartDate == nil || activeSprint.EndDate == nil {
+		return summary, fmt.Errorf("active sprint %s has no start or end date", activeSprint.Name)
+	}
+
+	midpoint := activeSprint.StartDate.Add(activeSprint.EndDate.Sub(*activeSprint.StartDate) / 2)
+	if nowFunc().Before(midpoint) {
+		summary.ActiveSprint = *activeSprint
+		summary.Skipped = true
+		logger.Info("sprint rollover skipped", "sprint_id", activeSprint.ID, "midpoint", midpoint)
+		return summary, nil
+	}
+
+	nextSprint, err := createNextSprint(project, boardID, *activeSprint.EndDate)
+	if err != nil {
+		return summary, err
+	}
+
+	incompleteIssues, err := incompleteIssuesInSprint(activeSprint.ID)
+	if err != nil {
+		return summary, err
+	}
+	summary.MovedIssues = len(incompleteIssues)
+	summary.FailedBatches = moveIssuesToSprint(nextSprint.ID, incompleteIssues)
+	if len(summary.FailedBatches) > 0 {
+		// Closing the sprint now would send the issues left behind to the
+		// backlog, so stop and let the caller retry.
+		for _, failure := range summary.FailedBatches {
+			summary.MovedIssues -= len(failure.IssueIDs)
+		}
+		return summary, fmt.Errorf("%d of %d issues failed to move to sprint %s: %v",
+			len(incompleteIssues)-summary.MovedIssues, len(incompleteIssues), nextSprint.Name, summary.FailedBatches[0])
+	}
+
+	if !config.DryRun {
+		if summary.MissingIssues, err = missingIssues(nextSprint.ID, incompleteIssues); err != nil {
+			return summary, err
+		}
+		summary.MovedIssues -= len(summary.MissingIssues)
+		if len(summary.MissingIssues) > 0 {
+			logger.Warn("issues missing after move", "sprint_id", nextSprint.ID, "issues", len(summary.MissingIssues))
+		}
+	}
+
+	// The project has one active sprint, check it before closing the old
+	// sprint and after activating the next one so it never has two. The
+	// check comes before closing, so an abort leaves the old sprint active.
+	if !config.DryRun {
+		if err = checkActiveSprints(project, boardID, activeSprint.ID); err != nil {
+			return summary, fmt.Errorf("can't activate sprint %s: %v", nextSprint.Name, err)
+		}
+	}
+	// Close the old sprint.
+	if summary.ClosedSprint, err = updateSprintState(*activeSprint, "closed"); err != nil {
+		return summary, err
+	}
+	if summary.ActiveSprint, err = activateNextSprint(project, boardID, nextSprint); err != nil {
+		return summary, err
+	}
+
+	logger.Info("sprint rolled over", "closed_sprint_id", activeSprint.ID,
+		"active_sprint_id", nextSprint.ID, "moved_issues", summary.MovedIssues)
+
+	// The labels come last, a failure must not leave the rollover half
+	// done, and the issues are in the next sprint anyway.
+	if config.Jira.LabelCarryover {
+		moved := withoutIssues(incompleteIssues, summary.MissingIssues)
+		var failures []BatchError
+		summary.LabeledIssues, failures = addIssueLabel(moved, carryoverLabel())
+		if len(failures) > 0 {
+			return summary, fmt.Errorf("sprint rolled over, but %d issues failed to get the %s label: %v",
+				len(failures), carryoverLabel(), failures[0])
+		}
+	}
+	return summary, nil
+}
+
+// activateNextSprint activates the sprint and checks that it is the only
+// active sprint of the project afterwards.
+func activateNextSprint(project string, boardID int, sprint jira.Sprint) (jira.Sprint, error) {
+	active, err := updateSprintState(sprint, "active")
+	if err != nil {
+		return active, err
+	}
+	if !config.DryRun {
+		if err = checkActiveSprints(project, boardID, sprint.ID); err != nil {
+			return active, fmt.Errorf("after activating sprint %s: %v", sprint.Name, err)
+		}
+	}
+	return active, nil
+}
+
+// resumeRollover finishes a rollover of the project without an active
+// sprint which closed the last sprint but didn't activate the next one. The
+// next sprint is activated if it was created already and the last sprint
+// was closed within the sprint duration, otherwise the project just has no
+// active sprint.
+func resumeRollover(project string, boardID int) (RolloverSummary, error) {
+	var summary RolloverSummary
+	closed, err := getSprints(boardID, jira.GetAllSprintsOptions{State: "closed"})
+	if err != nil {
+		return summary, err
+	}
+	var last *jira.Sprint
+	for i, sprint := range closed {
+		if !sprintBelongsToProject(sprint, project) || sprint.EndDate == nil {
+			continue
+		}
+		if last == nil || sprintFinishedAt(sprint).After(*sprintFinishedAt(*last)) {
+			last = &closed[i]
+		}
+	}
+	if last == nil || nowFunc().Sub(*sprintFinishedAt(*last)) > sprintDuration() {
+		return summary, noActiveSprintError(project, boardID)
+	}
+
+	plan, err := planNextSprint(project, boardID, *last.EndDate)
+	if err != nil {
+		return summary, err
+	}
+	if plan.existing == nil {
+		return summary, noActiveSprintError(project, boardID)
+	}
+	logger.Info("resuming the sprint rollover", "closed_sprint_id", last.ID, "active_sprint_id", plan.existing.ID)
+	summary.ClosedSprint, summary.Resumed = *last, true
+	summary.ActiveSprint, err = activateNextSprint(project, boardID, *plan.existing)
+	return summary, err
+}
+
+// checkActiveSprints re-reads the active sprints of the project on the
+// board and fails unless the sprint with activeID is the only one, or there
+// is none if activeID is 0. The sprints of the other projects sharing the
+// board are left out.
+func checkActiveSprints(project string, boardID int, activeID int) error {
+	active, err := getSprints(boardID, jira.GetAllSprintsOptions{State: "active"})
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, sprint := range active {
+		if !sprintBelongsToProject(sprint, project) {
+			continue
+		}
+		if sprint.ID == activeID {
+			found = true
+			continue
+		}
+		return fmt.Errorf("sprint %s (%d) is active on board %d", sprint.Name, sprint.ID, boardID)
+	}
+	if activeID > 0 && !found {
+		return fmt.Errorf("sprint %d is not active on board %d", activeID, boardID)
+	}
+	return nil
+}
+
+// missingIssues re-queries the sprint and returns the issues which are
+// not in it.
+func missingIssues(sprintID int, issues []jira.Issue) ([]jira.Issue, error) {
+	inSprint, err := queryJiraIssuesWithOptions(NewJQL().Sprint(sprintID).String(), &jira.SearchOptions{
+		Fields: []string{"key"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(inSprint))
+	for _, issue := range inSprint {
+		found[issue.ID] = true
+	}
+
+	var missing []jira.Issue
+	for _, issue := range issues {
+		if !found[issue.ID] {
+			missing = append(missing, issue)
+		}
+	}
+	return missing, nil
+}
+
+const defaultCarryoverLabel = "carryover"
+
+func carryoverLabel() string {
+	if label := config.Jira.CarryoverLabel; len(label) > 0 {
+		return label
+	}
+	return defaultCarryoverLabel
+}
+
+// withoutIssues returns the issues not in exclude.
+func withoutIssues(issues, exclude []jira.Issue) []jira.Issue {
+	excluded := make(map[string]bool, len(exclude))
+	for _, issue := range exclude {
+		excluded[issue.ID] = true
+	}
+	var left []jira.Issue
+	for _, issue := range issues {
+		if !excluded[issue.ID] {
+			left = append(left, issue)
+		}
+	}
+	return left
+}
+
+func isSubtask(issue jira.Issue) bool {
+	return issue.Fields != nil && issue.Fields.Type.Subtask
+}
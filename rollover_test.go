@@ -0,0 +1,267 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestRolloverSprint(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	start := time.Now().Add(-7 * 24 * time.Hour).Truncate(time.Second)
+	end := start.Add(7 * 24 * time.Hour)
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST old", State: "active", StartDate: &start, EndDate: &end})
+	f.addIssue(1, newFakeIssue(1, jira.StatusCategoryComplete))
+	f.addIssue(1, newFakeIssue(2, jira.StatusCategoryInProgress))
+	f.addIssue(1, newFakeIssue(3, jira.StatusCategoryToDo))
+
+	summary, err := rolloverSprint("TEST", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Skipped || summary.MovedIssues != 2 {
+		t.Fatalf("expect 2 issues moved, got %+v", summary)
+	}
+	if summary.ClosedSprint.State != "closed" || summary.ActiveSprint.State != "active" {
+		t.Fatalf("expect old sprint closed and new one active, got %+v", summary)
+	}
+	if keys := f.issuesIn(summary.ActiveSprint.ID); !reflect.DeepEqual(keys, []string{"TEST-2", "TEST-3"}) {
+		t.Fatalf("expect incomplete issues in the new sprint, got %v", keys)
+	}
+
+	// A second run must leave the new sprint alone.
+	summary, err = rolloverSprint("TEST", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.Skipped || len(f.sprints) != 2 {
+		t.Fatalf("expect second rollover to be skipped, got %+v with %d sprints", summary, len(f.sprints))
+	}
+}
+
+func TestRolloverSprintDryRun(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	config.DryRun = true
+
+	start := time.Now().Add(-7 * 24 * time.Hour).Truncate(time.Second)
+	end := start.Add(7 * 24 * time.Hour)
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST old", State: "active", StartDate: &start, EndDate: &end})
+	f.addIssue(1, newFakeIssue(1, jira.StatusCategoryToDo))
+
+	summary, err := rolloverSprint("TEST", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.MovedIssues != 1 || summary.ClosedSprint.State != "closed" {
+		t.Fatalf("expect the intended rollover in the summary, got %+v", summary)
+	}
+	if n := f.countRequests("POST"); n != 0 {
+		t.Fatalf("expect no writes in dry-run, got %d", n)
+	}
+	if f.sprint(1).State != "active" || len(f.issuesIn(1)) != 1 {
+		t.Fatal("expect the board to be untouched in dry-run")
+	}
+}
+
+func TestRolloverSprintMissingSubtask(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	start := time.Now().Add(-7 * 24 * time.Hour).Truncate(time.Second)
+	end := start.Add(7 * 24 * time.Hour)
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST old", State: "active", StartDate: &start, EndDate: &end})
+	f.addIssue(1, newFakeIssue(1, jira.StatusCategoryToDo))
+	subtask := newFakeIssue(2, jira.StatusCategoryInProgress)
+	subtask.Fields.Type.Subtask = true
+	f.addIssue(1, subtask)
+
+	summary, err := rolloverSprint("TEST", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.MovedIssues != 1 || len(summary.MissingIssues) != 1 || !isSubtask(summary.MissingIssues[0]) {
+		t.Fatalf("expect the subtask to be reported missing, got %+v", summary)
+	}
+}
+
+func TestRolloverSprintOtherActiveSprint(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	start := time.Now().Add(-7 * 24 * time.Hour).Truncate(time.Second)
+	end := start.Add(7 * 24 * time.Hour)
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST old", State: "active", StartDate: &start, EndDate: &end})
+	// Left active on the same board by someone else.
+	f.addSprint(jira.Sprint{ID: 2, Name: "TEST hotfix", State: "active", StartDate: &start, EndDate: &end})
+
+	_, err := rolloverSprint("TEST", 1)
+	if err == nil || !strings.Contains(err.Error(), "sprint TEST hotfix (2) is active on board 1") {
+		t.Fatalf("expect the other active sprint to abort the rollover, got %v", err)
+	}
+	if state := f.sprint(1).State; state != "active" {
+		t.Fatalf("expect the old sprint to stay active, got %s", state)
+	}
+	for _, sprint := range f.sprints {
+		if sprint.ID > 2 && sprint.State != "future" {
+			t.Fatalf("expect the next sprint not to be activated, got %s", sprint.State)
+		}
+	}
+}
+
+func TestRolloverSprintSharedBoard(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	start := time.Now().Add(-7 * 24 * time.Hour).Truncate(time.Second)
+	end := start.Add(7 * 24 * time.Hour)
+	f.addSprint(jira.Sprint{ID: 1, Name: "API 1", State: "active", StartDate: &start, EndDate: &end})
+	f.addSprint(jira.Sprint{ID: 2, Name: "TEST old", State: "active", StartDate: &start, EndDate: &end})
+
+	// The active sprint of the other project doesn't count.
+	summary, err := rolloverSprint("TEST", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.ClosedSprint.ID != 2 || summary.ActiveSprint.State != "active" || f.sprint(1).State != "active" {
+		t.Fatalf("expect TEST old rolled over and API 1 left active, got %+v", summary)
+	}
+}
+
+func TestRolloverSprintCarryoverLabel(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+	config.Jira.LabelCarryover = true
+
+	start := time.Now().Add(-7 * 24 * time.Hour).Truncate(time.Second)
+	end := start.Add(7 * 24 * time.Hour)
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST old", State: "active", StartDate: &start, EndDate: &end})
+	f.addIssue(1, newFakeIssue(1, jira.StatusCategoryComplete))
+	f.addIssue(1, newFakeIssue(2, jira.StatusCategoryInProgress))
+	labeled := newFakeIssue(3, jira.StatusCategoryToDo)
+	labeled.Fields.Labels = []string{"backend", "carryover"}
+	f.addIssue(1, labeled)
+
+	summary, err := rolloverSprint("TEST", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.LabeledIssues != 1 || f.countRequests("PUT /rest/api/2/issue/") != 1 {
+		t.Fatalf("expect only the unlabeled issue to be edited, got %d labeled", summary.LabeledIssues)
+	}
+	for _, issue := range f.issues {
+		switch issue.ID {
+		case "1":
+			if len(issue.Fields.Labels) != 0 {
+				t.Fatalf("expect the done issue not to be labeled, got %v", issue.Fields.Labels)
+			}
+		case "2":
+			if !reflect.DeepEqual(issue.Fields.Labels, []string{"carryover"}) {
+				t.Fatalf("expect the carryover label, got %v", issue.Fields.Labels)
+			}
+		case "3":
+			if !reflect.DeepEqual(issue.Fields.Labels, []string{"backend", "carryover"}) {
+				t.Fatalf("expect the labels unchanged, got %v", issue.Fields.Labels)
+			}
+		}
+	}
+}
+
+func TestRolloverSprintMidpoint(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+	config.Jira.Timezone = "UTC"
+
+	start := time.Date(2018, time.October, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(7 * 24 * time.Hour)
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST old", State: "active", StartDate: &start, EndDate: &end})
+	f.addIssue(1, newFakeIssue(1, jira.StatusCategoryToDo))
+
+	restore := freezeNow(start.Add(3 * 24 * time.Hour))
+	summary, err := rolloverSprint("TEST", 1)
+	restore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.Skipped || len(f.sprints) != 1 {
+		t.Fatalf("expect the rollover skipped before the midpoint, got %+v", summary)
+	}
+
+	defer freezeNow(start.Add(4 * 24 * time.Hour))()
+	if summary, err = rolloverSprint("TEST", 1); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Skipped || summary.ActiveSprint.Name != "TEST 2018-10-08 - 2018-10-14" {
+		t.Fatalf("expect the sprint after the old one, got %+v", summary)
+	}
+}
+
+func TestRolloverSprintNotStarted(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	// The reports use the future sprint containing now, the rollover must
+	// not.
+	start := time.Now().Add(-5 * 24 * time.Hour).Truncate(time.Second)
+	end := start.Add(7 * 24 * time.Hour)
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST current", State: "future", StartDate: &start, EndDate: &end})
+	f.addIssue(1, newFakeIssue(1, jira.StatusCategoryToDo))
+
+	if _, err := rolloverSprint("TEST", 1); err == nil || !strings.Contains(err.Error(), "no active sprint") {
+		t.Fatalf("expect no active sprint, got %v", err)
+	}
+	if len(f.sprints) != 1 || !reflect.DeepEqual(f.issuesIn(1), []string{"TEST-1"}) {
+		t.Fatalf("expect nothing created or moved, got %d sprints and %v", len(f.sprints), f.issuesIn(1))
+	}
+}
+
+func TestRolloverSprintResume(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	start := time.Now().Add(-7 * 24 * time.Hour).Truncate(time.Second)
+	end := start.Add(7 * 24 * time.Hour)
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST old", State: "active", StartDate: &start, EndDate: &end})
+	// An earlier run created the next sprint, moved the issues and closed
+	// the old sprint, then failed to activate the next one.
+	next, err := createNextSprint("TEST", 1, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.addIssue(next.ID, newFakeIssue(1, jira.StatusCategoryToDo))
+	completed := time.Now()
+	f.sprint(1).State, f.sprint(1).CompleteDate = "closed", &completed
+
+	summary, err := rolloverSprint("TEST", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.Resumed || summary.ClosedSprint.ID != 1 || summary.ActiveSprint.ID != next.ID || f.sprint(next.ID).State != "active" {
+		t.Fatalf("expect the next sprint activated, got %+v", summary)
+	}
+	if len(f.sprints) != 2 {
+		t.Fatalf("expect no other sprint created, got %d sprints", len(f.sprints))
+	}
+
+	// A sprint closed long ago isn't a failed rollover.
+	f.sprint(next.ID).State = "closed"
+	long := time.Now().Add(-30 * 24 * time.Hour)
+	f.sprint(next.ID).CompleteDate = &long
+	f.sprint(1).CompleteDate = &long
+	later, err := createNextSprint("TEST", 1, *f.sprint(next.ID).EndDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = rolloverSprint("TEST", 1); err == nil || !strings.Contains(err.Error(), "no active sprint") {
+		t.Fatalf("expect no active sprint, got %v", err)
+	}
+	if state := f.sprint(later.ID).State; state != "future" {
+		t.Fatalf("expect the future sprint left alone, got %s", state)
+	}
+}
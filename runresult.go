@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// The exit codes of a run. perror exits with exitFailure too, when the run
+// can't go on at all. exitSkipped tells that nothing failed, but a step had
+// nothing to do, like a sprint which isn't due for rotation.
+const (
+	exitOK             = 0
+	exitFailure        = 1
+	exitPartialFailure = 2
+	exitSkipped        = 3
+)
+
+// runResult accounts for the steps of a run, like the report of a project
+// or posting it to Slack, so that a failed step doesn't stop the others and
+// the run still exits with an error.
+type runResult struct {
+	mu        sync.Mutex
+	succeeded int
+	failures  []runFailure
+	skipped   []runSkip
+	// The changes to Jira, for the summary file.
+	sprintsCreated []createdSprint
+	issuesMoved    []movedIssues
+}
+
+type runFailure struct {
+	Step  string `json:"step,omitempty"`
+	Error string `json:"error"`
+}
+
+type runSkip struct {
+	Step   string `json:"step"`
+	Reason string `json:"reason"`
+}
+
+type createdSprint struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	BoardID int    `json:"board_id"`
+}
+
+// movedIssues counts the issues moved to a sprint.
+type movedIssues struct {
+	SprintID int `json:"sprint_id"`
+	Issues   int `json:"issues"`
+}
+
+var runStatus = new(runResult)
+
+// record counts the step as done, or as failed if err is set. The failures
+// are printed as they happen.
+func (r *runResult) record(step string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		r.succeeded++
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s failed: %v\n", step, err)
+	r.failures = append(r.failures, runFailure{Step: step, Error: err.Error()})
+}
+
+// skip records the step as skipped for the reason.
+func (r *runResult) skip(step, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(os.Stderr, "%s skipped: %s\n", step, reason)
+	r.skipped = append(r.skipped, runSkip{Step: step, Reason: reason})
+}
+
+// addSprintCreated records a sprint created on the board.
+func (r *runResult) addSprintCreated(boardID int, sprint jira.Sprint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sprintsCreated = append(r.sprintsCreated, createdSprint{ID: sprint.ID, Name: sprint.Name, BoardID: boardID})
+}
+
+// addIssuesMoved records n issues moved to the sprint.
+func (r *runResult) addIssuesMoved(sprintID int, n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.issuesMoved {
+		if r.issuesMoved[i].SprintID == sprintID {
+			r.issuesMoved[i].Issues += n
+			return
+		}
+	}
+	r.issuesMoved = append(r.issuesMoved, movedIssues{SprintID: sprintID, Issues: n})
+}
+
+// exitCode is exitOK if no step failed or was skipped, exitSkipped if some
+// were skipped but none failed, exitFailure if all of them failed and
+// exitPartialFailure otherwise.
+func (r *runResult) exitCode() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case len(r.failures) == 0 && len(r.skipped) == 0:
+		return exitOK
+	case len(r.failures) == 0:
+		return exitSkipped
+	case r.succeeded+len(r.skipped) == 0:
+		return exitFailure
+	default:
+		return exitPartialFailure
+	}
+}
+
+// summary lists the failed steps, or returns "" if there are none.
+func (r *runResult) summary() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.failures) == 0 {
+		return ""
+	}
+	items := make([]string, 0, len(r.failures))
+	for _, failure := range r.failures {
+		items = append(items, failure.Step+": "+failure.Error)
+	}
+	return fmt.Sprintf("%d of %d steps failed:\n- %s\n", len(r.failures), len(r.failures)+len(r.skipped)+r.succeeded,
+		strings.Join(items, "\n- "))
+}
+
+// runSummary is the summary file of a run, for the CI pipelines to check
+// what it did.
+type runSummary struct {
+	ExitCode       int             `json:"exit_code"`
+	DryRun         bool            `json:"dry_run"`
+	Steps          int             `json:"steps"`
+	SprintsCreated []createdSprint `json:"sprints_created"`
+	IssuesMoved    []movedIssues   `json:"issues_moved"`
+	Skipped        []runSkip       `json:"skipped"`
+	Errors         []runFailure    `json:"errors"`
+}
+
+// runSummary returns the summary of the run exiting with code. fatal is
+// the error which stopped the run, if any, it isn't one of the steps.
+func (r *runResult) runSummary(code int, fatal error) runSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// The lists are empty instead of null for the JSON.
+	s := runSummary{
+		ExitCode:       code,
+		DryRun:         config != nil && config.DryRun,
+		Steps:          len(r.failures) + len(r.skipped) + r.succeeded,
+		SprintsCreated: append([]createdSprint{}, r.sprintsCreated...),
+		IssuesMoved:    append([]movedIssues{}, r.issuesMoved...),
+		Skipped:        append([]runSkip{}, r.skipped...),
+		Errors:         append([]runFailure{}, r.failures...),
+	}
+	if fatal != nil {
+		s.Errors = append(s.Errors, runFailure{Error: fatal.Error()})
+	}
+	return s
+}
+
+// writeSummaryFile writes the summary of the run to the file of
+// --summary-file, if it is set.
+func writeSummaryFile(code int, fatal error) {
+	if len(summaryFile) == 0 {
+		return
+	}
+	data, err := json.MarshalIndent(runStatus.runSummary(code, fatal), "", "  ")
+	if err == nil {
+		err = writeFileAtomic(summaryFile, append(data, '\n'))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "writing the summary file %s failed: %v\n", summaryFile, err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestRunResultExitCode(t *testing.T) {
+	r := new(runResult)
+	if code := r.exitCode(); code != exitOK || len(r.summary()) > 0 {
+		t.Fatalf("expect a run without steps to be ok, got %d %q", code, r.summary())
+	}
+
+	r.record("[TIKV] sprint report", nil)
+	if code := r.exitCode(); code != exitOK {
+		t.Fatalf("expect exit code %d, got %d", exitOK, code)
+	}
+
+	r.record("post sprint report to slack", fmt.Errorf("channel_not_found"))
+	if code := r.exitCode(); code != exitPartialFailure {
+		t.Fatalf("expect exit code %d, got %d", exitPartialFailure, code)
+	}
+	summary := r.summary()
+	if !strings.HasPrefix(summary, "1 of 2 steps failed:") || !strings.Contains(summary, "- post sprint report to slack: channel_not_found") {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+
+	skipped := new(runResult)
+	skipped.skip("[TEST] rotate sprint", "sprint TEST 2 is not due for rotation")
+	if code := skipped.exitCode(); code != exitSkipped {
+		t.Fatalf("expect exit code %d, got %d", exitSkipped, code)
+	}
+	skipped.record("[PD] rotate sprint", fmt.Errorf("no active sprint"))
+	if code := skipped.exitCode(); code != exitPartialFailure {
+		t.Fatalf("expect exit code %d, got %d", exitPartialFailure, code)
+	}
+
+	failed := new(runResult)
+	failed.record("[TIKV] rotate sprint", fmt.Errorf("no active sprint"))
+	failed.record("[PD] rotate sprint", fmt.Errorf("no active sprint"))
+	if code := failed.exitCode(); code != exitFailure {
+		t.Fatalf("expect exit code %d, got %d", exitFailure, code)
+	}
+}
+
+func TestWriteSummaryFile(t *testing.T) {
+	oldStatus, oldFile := runStatus, summaryFile
+	defer func() { runStatus, summaryFile = oldStatus, oldFile }()
+	dir, err := ioutil.TempDir("", "summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	runStatus = new(runResult)
+	summaryFile = filepath.Join(dir, "summary.json")
+
+	runStatus.addSprintCreated(4, jira.Sprint{ID: 12, Name: "TEST Sprint 2"})
+	runStatus.addIssuesMoved(12, 50)
+	runStatus.addIssuesMoved(12, 3)
+	runStatus.record("[TEST] rotate sprint", nil)
+	runStatus.record("[TEST] post sprint rotation to slack", fmt.Errorf("channel_not_found"))
+	writeSummaryFile(runStatus.exitCode(), nil)
+
+	data, err := ioutil.ReadFile(summaryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary runSummary
+	if err = json.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+	expect := runSummary{
+		ExitCode:       exitPartialFailure,
+		Steps:          2,
+		SprintsCreated: []createdSprint{{ID: 12, Name: "TEST Sprint 2", BoardID: 4}},
+		IssuesMoved:    []movedIssues{{SprintID: 12, Issues: 53}},
+		Skipped:        []runSkip{},
+		Errors:         []runFailure{{Step: "[TEST] post sprint rotation to slack", Error: "channel_not_found"}},
+	}
+	if !reflect.DeepEqual(summary, expect) {
+		t.Fatalf("expect %+v, got %+v", expect, summary)
+	}
+
+	// A run stopped by an error, the lists are still there.
+	runStatus = new(runResult)
+	writeSummaryFile(exitFailure, fmt.Errorf("no active sprint"))
+	if data, err = ioutil.ReadFile(summaryFile); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"exit_code": 1`, `"sprints_created": []`, `"error": "no active sprint"`} {
+		if !strings.Contains(string(data), s) {
+			t.Fatalf("expect %s in %s", s, data)
+		}
+	}
+}
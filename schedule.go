@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newScheduleCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "schedule",
+		Short: "Run the Scheduled Commands on the Configured Cron Expression",
+		Run:   runScheduleCommandFunc,
+	}
+	return m
+}
+
+func runScheduleCommandFunc(cmd *cobra.Command, args []string) {
+	if len(config.Schedule.Cron) == 0 {
+		perrmsg("no schedule cron is configured")
+	}
+	loc := time.Local
+	if len(config.Schedule.Timezone) > 0 {
+		var err error
+		loc, err = time.LoadLocation(config.Schedule.Timezone)
+		perror(err)
+	}
+	schedule, err := parseCron(config.Schedule.Cron, loc)
+	perror(err)
+
+	// The run timeout applies to each scheduled command, not to the
+	// scheduler, so it gets its own context.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if len(config.Metrics.Listen) > 0 {
+		serveMetrics(config.Metrics.Listen)
+	}
+
+	s := &scheduler{run: runScheduledCommands}
+	fmt.Printf("Running %s on %q\n", strings.Join(config.Schedule.Commands, ", "), config.Schedule.Cron)
+	s.loop(ctx, schedule)
+}
+
+// scheduler triggers run at the times of a cron schedule. A trigger is
+// skipped while the previous run is still in progress.
+type scheduler struct {
+	run func(ctx context.Context) error
+
+	mu      sync.Mutex
+	running bool
+	wg      sync.WaitGroup
+}
+
+// loop triggers the runs until ctx is done, then waits for the current run.
+func (s *scheduler) loop(ctx context.Context, schedule *cronSchedule) {
+	defer s.wg.Wait()
+
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			logger.Error("schedule never matches", "cron", config.Schedule.Cron)
+			return
+		}
+		logger.Info("next scheduled run", "at", next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.trigger(ctx, next)
+		}
+	}
+}
+
+// trigger starts a run unless one is in progress, it returns whether the
+// run was started.
+func (s *scheduler) trigger(ctx context.Context, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		logger.Warn("scheduled run skipped, the previous one is still in progress", "at", at)
+		return false
+	}
+	logger.Info("scheduled run triggered", "at", at)
+	s.running = true
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+		err := s.run(ctx)
+		if err != nil {
+			logger.Error("scheduled run failed", "at", at, "err", err)
+		} else {
+			logger.Info("scheduled run finished", "at", at)
+		}
+
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+	return true
+}
+
+// runScheduledCommands runs the configured commands one after another, each
+// in its own process with the same config, environment and flags. The commands exit on
+// errors, so a failure can't take the scheduler down.
+func runScheduledCommands(ctx context.Context) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, command := range config.Schedule.Commands {
+		var args []string
+		// The environment is inherited, a missing default file is too.
+		if _, err := os.Stat(configFile); err == nil {
+			args = append(args, "--config", configFile)
+		}
+		for _, set := range configSets {
+			args = append(args, "--set", set)
+		}
+		if dryRun {
+			args = append(args, "--dry-run")
+		}
+		if len(jiraEnv) > 0 {
+			args = append(args, "--env", jiraEnv)
+		}
+		if len(boardTypeFlag) > 0 {
+			args = append(args, "--board-type", boardTypeFlag)
+		}
+		if len(logLevel) > 0 {
+			args = append(args, "--log-level", logLevel)
+		}
+		args = append(args, strings.Fields(command)...)
+
+		metricsFile, err := ioutil.TempFile("", "work-reporter-metrics-")
+		if err != nil {
+			return err
+		}
+		metricsFile.Close()
+
+		cmd := exec.CommandContext(ctx, executable, args...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		cmd.Env = append(os.Environ(), metricsFileEnv+"="+metricsFile.Name())
+		err = cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == exitSkipped {
+			// Like a sprint not due for rotation, nothing failed.
+			logger.Info("scheduled command skipped a step", "command", command)
+			err = nil
+		}
+		if err != nil {
+			if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == exitPartialFailure {
+				logger.Error("scheduled command partially failed", "command", command)
+			} else {
+				logger.Error("scheduled command failed", "command", command, "err", err)
+			}
+			metrics.addRunFailure()
+			failed = append(failed, command)
+		}
+		if err := readMetricsFile(metricsFile.Name()); err != nil {
+			logger.Warn("reading metrics failed", "command", command, "err", err)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s failed", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSchedulerSkipsRunInProgress(t *testing.T) {
+	release := make(chan struct{})
+	runs := 0
+	s := &scheduler{run: func(ctx context.Context) error {
+		runs++
+		<-release
+		return nil
+	}}
+
+	now := time.Now()
+	if !s.trigger(context.Background(), now) {
+		t.Fatal("expect the first run to start")
+	}
+	if s.trigger(context.Background(), now.Add(time.Minute)) {
+		t.Fatal("expect the second run to be skipped")
+	}
+	close(release)
+	s.wg.Wait()
+
+	if !s.trigger(context.Background(), now.Add(2*time.Minute)) {
+		t.Fatal("expect a run after the previous one finished")
+	}
+	s.wg.Wait()
+	if runs != 2 {
+		t.Fatalf("expect 2 runs, got %d", runs)
+	}
+}
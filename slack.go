@@ -2,8 +2,12 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/andygrunwald/go-jira"
 	"github.com/google/go-github/github"
@@ -49,25 +53,279 @@ func buildSlackMention(email string) string {
 	return fmt.Sprintf("<@%s>", id)
 }
 
-func sendToSlack(format string, args ...interface{}) {
+func slackChannelName() string {
 	channelName := config.Slack.Channel
+	if channelName != "" && channelName[0] != '#' {
+		channelName = "#" + channelName
+	}
+	return channelName
+}
+
+func sendToSlack(format string, args ...interface{}) error {
+	channelName := slackChannelName()
 	user := config.Slack.User
 
 	if channelName == "" {
 		println("no slack channel name")
-		return
+		return nil
 	}
 
-	if channelName[0] != '#' {
-		channelName = "#" + channelName
+	if config.DryRun {
+		fmt.Printf("[dry-run] post to slack %s: %s\n", channelName, fmt.Sprintf(format, args...))
+		return nil
 	}
 
 	_, _, err := getSlackClient().PostMessage(channelName,
 		slack.MsgOptionUser(user),
 		slack.MsgOptionText(fmt.Sprintf(format, args...), false))
 	if err != nil {
-		perror(fmt.Errorf("can not post msg to slack with err: %v", err))
+		return fmt.Errorf("can not post msg to slack with err: %v", err)
+	}
+	return nil
+}
+
+// Slack truncates messages longer than 40000 characters, but recommends
+// keeping them under 4000.
+const slackMessageLimit = 4000
+
+// slackFence opens and closes a code block in a Slack message.
+const slackFence = "```"
+
+// splitSlackMessage splits text into chunks of at most limit bytes,
+// breaking after a newline whenever possible. A code block split between
+// chunks is closed at the end of one and opened again in the next, so it
+// is still rendered as code.
+func splitSlackMessage(text string, limit int) []string {
+	// The room kept in every chunk to reopen and close a code block.
+	var reopen, closing string
+	if strings.Contains(text, slackFence) {
+		reopen, closing = slackFence+"\n", "\n"+slackFence
+	}
+
+	var chunks []string
+	open := false
+	for len(text) > 0 {
+		var prefix string
+		if open {
+			prefix = reopen
+		}
+		if len(prefix)+len(text) <= limit {
+			chunks = append(chunks, prefix+text)
+			break
+		}
+
+		size := limit - len(reopen) - len(closing)
+		cut := strings.LastIndex(text[:size], "\n") + 1
+		if cut == 0 {
+			cut = size
+			// Don't split a multi-byte character.
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		chunk := prefix + text[:cut]
+		text = text[cut:]
+		if open = strings.Count(chunk, slackFence)%2 == 1; open {
+			// A code block opened at the end of the chunk starts the next
+			// one instead, and one closed at the start of the next chunk is
+			// closed in this one, rather than left empty.
+			if i := strings.LastIndex(chunk, slackFence); i > len(prefix) && len(strings.TrimSpace(chunk[i:])) == len(slackFence) {
+				chunk, text = chunk[:i], chunk[i:]+text
+				open = false
+			} else if strings.HasSuffix(chunk, "\n") && strings.HasPrefix(text, slackFence+"\n") {
+				chunk, text = chunk+slackFence+"\n", text[len(slackFence)+1:]
+				open = false
+			}
+		}
+		if open {
+			if !strings.HasSuffix(chunk, "\n") {
+				chunk += "\n"
+			}
+			chunk += slackFence
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// The sprint health thresholds unless configured otherwise, in percent of
+// the issues done.
+const (
+	defaultHealthyPercent = 70
+	defaultAtRiskPercent  = 40
+)
+
+func healthyPercent() int {
+	if percent := config.Slack.HealthyPercent; percent > 0 {
+		return percent
+	}
+	return defaultHealthyPercent
+}
+
+func atRiskPercent() int {
+	if percent := config.Slack.AtRiskPercent; percent > 0 {
+		return percent
+	}
+	return defaultAtRiskPercent
+}
+
+// sprintHealthColor returns the Slack attachment color for the share of
+// done issues.
+func sprintHealthColor(donePercent int) string {
+	switch {
+	case donePercent >= healthyPercent():
+		return "good"
+	case donePercent >= atRiskPercent():
+		return "warning"
+	default:
+		return "danger"
+	}
+}
+
+// sprintHealth returns the number of issues of the report, the issue count
+// of each status category and the share of done issues.
+func sprintHealth(r projectReport) (total int, categories map[string]int, donePercent int) {
+	categories = make(map[string]int)
+	done := 0
+	for _, summary := range r.report {
+		total += summary.Issues
+		done += summary.Done
+		for category, n := range summary.StatusCategories {
+			categories[category] += n
+		}
+	}
+	if total > 0 {
+		donePercent = done * 100 / total
+	}
+	return total, categories, donePercent
+}
+
+// sprintHealthAttachment summarizes the report at a glance: colored by the
+// share of done issues, with the issue count of each status category.
+func sprintHealthAttachment(r projectReport) slack.Attachment {
+	total, categories, donePercent := sprintHealth(r)
+	title := fmt.Sprintf("%s: Sprint %s", r.project, sprintTitle(r.sprint))
+	text := fmt.Sprintf("%d%% of %d issues done", donePercent, total)
+	attachment := slack.Attachment{
+		Color:    sprintHealthColor(donePercent),
+		Fallback: title + ", " + text,
+		Title:    title,
+		Text:     text,
+	}
+	for _, category := range statusCategoryColumns(r.report) {
+		attachment.Fields = append(attachment.Fields, slack.AttachmentField{
+			Title: category,
+			Value: strconv.Itoa(categories[category]),
+			Short: true,
+		})
+	}
+	return attachment
+}
+
+// postReportToSlack posts the report to the configured webhook, or to the
+// configured channel with the bot token. Long reports are posted as several
+// messages, the attachments go with the first one. It returns the
+// timestamps of the posted messages so that they can be edited later,
+// webhooks don't tell them so there are none.
+func postReportToSlack(text string, attachments []slack.Attachment) ([]string, error) {
+	chunks := splitSlackMessage(text, slackMessageLimit)
+	chunkAttachments := func(i int) []slack.Attachment {
+		if i == 0 {
+			return attachments
+		}
+		return nil
+	}
+
+	if config.DryRun {
+		for i, chunk := range chunks {
+			payload, err := json.Marshal(slack.WebhookMessage{Text: chunk, Attachments: chunkAttachments(i)})
+			if err != nil {
+				return nil, err
+			}
+			fmt.Printf("[dry-run] post to slack: %s\n", payload)
+		}
+		return nil, nil
+	}
+
+	if len(config.Slack.Webhook) > 0 {
+		for i, chunk := range chunks {
+			msg := &slack.WebhookMessage{Text: chunk, Attachments: chunkAttachments(i)}
+			if err := slack.PostWebhook(config.Slack.Webhook, msg); err != nil {
+				return nil, fmt.Errorf("can not post report to slack webhook: %v", err)
+			}
+		}
+		return nil, nil
+	}
+
+	channelName := slackChannelName()
+	if channelName == "" {
+		return nil, fmt.Errorf("no slack channel name or webhook")
+	}
+
+	timestamps := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		_, ts, err := getSlackClient().PostMessage(channelName,
+			slack.MsgOptionUser(config.Slack.User),
+			slack.MsgOptionText(chunk, false),
+			slack.MsgOptionAttachments(chunkAttachments(i)...))
+		if err != nil {
+			return timestamps, fmt.Errorf("can not post report to slack: %v", err)
+		}
+		timestamps = append(timestamps, ts)
+	}
+	return timestamps, nil
+}
+
+// slackNotifier posts the report to Slack, see postReportToSlack.
+type slackNotifier struct{}
+
+func (slackNotifier) Name() string {
+	return "slack"
+}
+
+func (slackNotifier) Send(ctx context.Context, report SprintReport) error {
+	var text bytes.Buffer
+	var attachments []slack.Attachment
+	for _, r := range report.Projects {
+		attachments = append(attachments, sprintHealthAttachment(r))
+		fmt.Fprintf(&text, "*%s*\n```\n%s```\n", r.title(), renderProjectText(r))
+	}
+	if _, err := postReportToSlack(text.String(), attachments); err != nil {
+		return err
+	}
+	for _, r := range report.Projects {
+		if len(r.chart) > 0 {
+			if err := uploadChartToSlack(ctx, r.chartName(), r.chart); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// uploadChartToSlack posts the PNG chart to the configured channel, which
+// needs the bot token: a webhook can only post messages.
+func uploadChartToSlack(ctx context.Context, name string, chart []byte) error {
+	if config.DryRun {
+		fmt.Printf("[dry-run] upload to slack: %s (%d bytes)\n", name, len(chart))
+		return nil
+	}
+	channelName := slackChannelName()
+	if len(config.Slack.Token) == 0 || channelName == "" {
+		logger.Warn("velocity chart not posted, slack needs a token and a channel to upload it", "chart", name)
+		return nil
+	}
+	_, err := getSlackClient().UploadFileContext(ctx, slack.FileUploadParameters{
+		Reader:   bytes.NewReader(chart),
+		Filename: name,
+		Filetype: "png",
+		Title:    strings.TrimSuffix(name, ".png"),
+		Channels: []string{channelName},
+	})
+	if err != nil {
+		return fmt.Errorf("can not upload %s to slack: %v", name, err)
 	}
+	return nil
 }
 
 func formatSectionForSlackOutput(buf *bytes.Buffer, title string, description string) {
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/nlopes/slack"
+)
+
+func TestSplitSlackMessage(t *testing.T) {
+	text := strings.Repeat("line\n", 10)
+	chunks := splitSlackMessage(text, 12)
+	if len(chunks) != 5 || chunks[0] != "line\nline\n" {
+		t.Fatalf("expect chunks of two lines, got %q", chunks)
+	}
+	if strings.Join(chunks, "") != text {
+		t.Fatal("expect chunks to add up to the text")
+	}
+
+	// A line longer than the limit is cut without breaking characters.
+	chunks = splitSlackMessage("ééééé", 3)
+	if strings.Join(chunks, "") != "ééééé" || chunks[0] != "é" {
+		t.Fatalf("unexpected chunks %q", chunks)
+	}
+}
+
+func TestSplitSlackMessageCodeBlock(t *testing.T) {
+	text := "head\n```\n" + strings.Repeat("row\n", 6) + "```\ntail\n"
+	chunks := splitSlackMessage(text, 20)
+	if len(chunks) < 2 {
+		t.Fatalf("expect the code block split, got %q", chunks)
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > 20 {
+			t.Errorf("chunk %d is longer than the limit: %q", i, chunk)
+		}
+		if strings.Count(chunk, "```")%2 != 0 {
+			t.Errorf("expect chunk %d to close its code block: %q", i, chunk)
+		}
+	}
+	// The code block doesn't start at the end of the first chunk.
+	if chunks[0] != "head\n" || chunks[1] != "```\nrow\nrow\n```" || !strings.HasPrefix(chunks[2], "```\nrow\n") {
+		t.Fatalf("expect the code block closed and reopened, got %q", chunks)
+	}
+	if !strings.HasSuffix(chunks[len(chunks)-1], "```\ntail\n") {
+		t.Fatalf("expect the text after the code block in the last chunk, got %q", chunks)
+	}
+}
+
+func TestPostReportToSlackWebhook(t *testing.T) {
+	var messages []string
+	var attachments []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg slack.WebhookMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Error(err)
+		}
+		messages = append(messages, msg.Text)
+		attachments = append(attachments, len(msg.Attachments))
+	}))
+	defer server.Close()
+
+	config = &Config{}
+	config.Slack.Webhook = server.URL
+	defer func() { config = nil }()
+
+	text := strings.Repeat(strings.Repeat("x", 99)+"\n", 50)
+	if _, err := postReportToSlack(text, []slack.Attachment{{Title: "TEST"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || strings.Join(messages, "") != text {
+		t.Fatalf("expect the report in 2 messages, got %d", len(messages))
+	}
+	if attachments[0] != 1 || attachments[1] != 0 {
+		t.Fatalf("expect the attachment with the first message, got %v", attachments)
+	}
+}
+
+func TestSprintHealthAttachment(t *testing.T) {
+	config = &Config{}
+	config.Slack.HealthyPercent = 80
+	defer func() { config = nil }()
+
+	r := projectReport{project: "TEST", sprint: jira.Sprint{Name: "TEST 1"}, report: buildAssigneeReport([]jira.Issue{
+		newReportIssue("TEST-1", "alice", "Done", 3),
+		newReportIssue("TEST-2", "alice", "In Progress", 2),
+		newReportIssue("TEST-3", "bob", "Done", 1),
+		newReportIssue("TEST-4", "bob", "To Do", 1),
+	})}
+
+	attachment := sprintHealthAttachment(r)
+	if attachment.Color != "warning" || attachment.Text != "50% of 4 issues done" {
+		t.Fatalf("expect a yellow report half done, got %+v", attachment)
+	}
+	if len(attachment.Fields) != 3 || attachment.Fields[0].Title != "To Do" || attachment.Fields[2].Value != "2" {
+		t.Fatalf("expect the status categories as fields, got %+v", attachment.Fields)
+	}
+
+	for percent, color := range map[int]string{80: "good", 40: "warning", 39: "danger"} {
+		if got := sprintHealthColor(percent); got != color {
+			t.Errorf("%d%%: expect %s, got %s", percent, color, got)
+		}
+	}
+}
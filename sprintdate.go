@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// sprintDateFormats are the formats Jira is known to use for the sprint
+// dates, tried in order. Depending on the deployment and the endpoint the
+// dates have milliseconds and an offset with or without a colon, e.g.
+// 2018-01-02T10:00:00.000+0000, which time.Time does not decode.
+var sprintDateFormats = []string{
+	dateFormat,
+	"2006-01-02T15:04:05.000Z0700",
+	"2006-01-02T15:04:05Z0700",
+	// No offset at all, which Jira means as UTC.
+	"2006-01-02T15:04:05.000",
+	"2006-01-02T15:04:05",
+}
+
+// parseSprintDate parses a sprint date in any of sprintDateFormats.
+func parseSprintDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, format := range sprintDateFormats {
+		if t, err := time.Parse(format, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown sprint date format %q", s)
+}
+
+// sprintDate decodes a sprint date with parseSprintDate.
+type sprintDate struct {
+	time *time.Time
+}
+
+func (d *sprintDate) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		d.time = nil
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if len(s) == 0 {
+		d.time = nil
+		return nil
+	}
+	t, err := parseSprintDate(s)
+	if err != nil {
+		return err
+	}
+	d.time = &t
+	return nil
+}
+
+// jiraSprint decodes a jira.Sprint, its date fields shadow the ones of the
+// embedded sprint which can only decode RFC 3339.
+type jiraSprint struct {
+	jira.Sprint
+	CompleteDate sprintDate `json:"completeDate"`
+	EndDate      sprintDate `json:"endDate"`
+	StartDate    sprintDate `json:"startDate"`
+}
+
+func (s jiraSprint) sprint() jira.Sprint {
+	sprint := s.Sprint
+	sprint.CompleteDate = s.CompleteDate.time
+	sprint.EndDate = s.EndDate.time
+	sprint.StartDate = s.StartDate.time
+	return sprint
+}
+
+// jiraSprintsList decodes a jira.SprintsList with jiraSprint.
+type jiraSprintsList struct {
+	MaxResults int          `json:"maxResults"`
+	StartAt    int          `json:"startAt"`
+	Total      int          `json:"total"`
+	IsLast     bool         `json:"isLast"`
+	Values     []jiraSprint `json:"values"`
+}
+
+func (l jiraSprintsList) sprints() []jira.Sprint {
+	sprints := make([]jira.Sprint, 0, len(l.Values))
+	for _, s := range l.Values {
+		sprints = append(sprints, s.sprint())
+	}
+	return sprints
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestParseSprintDate(t *testing.T) {
+	expect := time.Date(2018, 1, 2, 10, 30, 0, 0, time.UTC)
+	cases := []string{
+		"2018-01-02T10:30:00Z",
+		"2018-01-02T10:30:00.000Z",
+		"2018-01-02T10:30:00.000+0000",
+		"2018-01-02T10:30:00+0000",
+		"2018-01-02T12:30:00.000+02:00",
+		"2018-01-02T12:30:00.000+0200",
+		"2018-01-02T05:30:00.000-0500",
+		"2018-01-02T10:30:00.000",
+		" 2018-01-02T10:30:00 ",
+	}
+	for _, c := range cases {
+		got, err := parseSprintDate(c)
+		if err != nil {
+			t.Errorf("%q: %v", c, err)
+			continue
+		}
+		if !got.Equal(expect) {
+			t.Errorf("%q: expect %v, got %v", c, expect, got)
+		}
+	}
+
+	if _, err := parseSprintDate("02/Jan/18 10:30 AM"); err == nil {
+		t.Fatal("expect error for an unknown format")
+	}
+}
+
+func TestGetSprintsMillisecondDates(t *testing.T) {
+	const format = "2006-01-02T15:04:05.000-0700"
+	now := time.Now()
+	at := func(days int) string {
+		return now.AddDate(0, 0, days).UTC().Format(format)
+	}
+
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"isLast": true, "values": [
+			{"id": 1, "name": "TEST 1", "state": "closed", "startDate": %q, "endDate": %q, "completeDate": %q},
+			{"id": 2, "name": "TEST 2", "state": "closed", "startDate": %q, "endDate": %q},
+			{"id": 3, "name": "TEST 3", "state": "future", "startDate": null, "endDate": ""}
+		]}`, at(-9), at(-6), at(-6), at(-6), at(-1))
+	})()
+
+	sprints, err := getSprints(1, jira.GetAllSprintsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sprints) != 3 {
+		t.Fatalf("expect 3 sprints, got %d", len(sprints))
+	}
+	if sprints[0].CompleteDate == nil || sprints[2].StartDate != nil || sprints[2].EndDate != nil {
+		t.Fatalf("unexpected dates %v", sprints)
+	}
+
+	sprint := getLatestPassedSprint("TEST", sprints)
+	if sprint == nil || sprint.ID != 2 {
+		t.Fatalf("expect sprint 2, got %v", sprint)
+	}
+}
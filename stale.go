@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// Issues in progress are stale without an update for this long unless
+// configured otherwise.
+const defaultStaleAfter = 3 * 24 * time.Hour
+
+func staleAfter() time.Duration {
+	if d := config.Report.StaleAfter.Duration; d > 0 {
+		return d
+	}
+	return defaultStaleAfter
+}
+
+// staleIssues returns the issues of the sprint in progress which were not
+// updated for threshold, they need attention.
+func staleIssues(sprintID int, threshold time.Duration) ([]jira.Issue, error) {
+	issues, err := issuesInSprint(sprintID)
+	if err != nil {
+		return nil, err
+	}
+	return filterStale(issues, threshold, nowFunc()), nil
+}
+
+// filterStale returns the issues in progress last updated before now minus
+// threshold, see staleIssues.
+func filterStale(issues []jira.Issue, threshold time.Duration, now time.Time) []jira.Issue {
+	var stale []jira.Issue
+	for _, issue := range issues {
+		if issue.Fields == nil || issue.Fields.Status == nil ||
+			issue.Fields.Status.StatusCategory.Key != jira.StatusCategoryInProgress || isDone(issue) {
+			continue
+		}
+		// Without the updated field there is nothing to tell.
+		updated := time.Time(issue.Fields.Updated)
+		if updated.IsZero() {
+			continue
+		}
+		if now.Sub(updated) > threshold {
+			stale = append(stale, issue)
+		}
+	}
+	return stale
+}
+
+// renderStale renders the "Stale / needs update" section with a line per
+// issue and its last update, or returns "" if there are none.
+func renderStale(issues []jira.Issue) string {
+	if len(issues) == 0 {
+		return ""
+	}
+	loc, err := sprintLocation(time.Local)
+	if err != nil {
+		loc = time.Local
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Stale / needs update (%d):\n", len(issues))
+	for _, issue := range issues {
+		updated := time.Time(issue.Fields.Updated).In(loc).Format(displayDateFormat())
+		fmt.Fprintf(&b, "- %s %s (%s, updated %s)\n", issue.Key, issueSummary(issue), issueAssignee(issue), updated)
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestFilterStale(t *testing.T) {
+	config = &Config{}
+	config.Jira.Timezone = "UTC"
+	defer func() { config = nil }()
+
+	now := time.Date(2018, 10, 10, 12, 0, 0, 0, time.UTC)
+	categoryKeys := map[string]string{
+		"To Do":       jira.StatusCategoryToDo,
+		"In Progress": jira.StatusCategoryInProgress,
+		"Done":        jira.StatusCategoryComplete,
+	}
+	issue := func(key, category string, updated time.Time) jira.Issue {
+		issue := newReportIssue(key, "alice", category, -1)
+		issue.Fields.Status.StatusCategory.Key = categoryKeys[category]
+		issue.Fields.Summary = "Fix " + key
+		issue.Fields.Updated = jira.Time(updated)
+		return issue
+	}
+	issues := []jira.Issue{
+		issue("TEST-1", "In Progress", now.Add(-4*24*time.Hour)),
+		issue("TEST-2", "In Progress", now.Add(-time.Hour)),
+		issue("TEST-3", "To Do", now.Add(-10*24*time.Hour)),
+		issue("TEST-4", "Done", now.Add(-10*24*time.Hour)),
+		issue("TEST-5", "In Progress", time.Time{}),
+	}
+
+	stale := filterStale(issues, staleAfter(), now)
+	if len(stale) != 1 || stale[0].Key != "TEST-1" {
+		t.Fatalf("expect only the idle issue in progress, got %v", stale)
+	}
+	if stale = filterStale(issues, 30*time.Minute, now); len(stale) != 2 {
+		t.Fatalf("expect 2 stale issues with a shorter threshold, got %v", stale)
+	}
+
+	expect := "Stale / needs update (1):\n- TEST-1 Fix TEST-1 (alice, updated Oct 6)\n"
+	if out := renderStale(filterStale(issues, staleAfter(), now)); out != expect {
+		t.Fatalf("expect %q, got %q", expect, out)
+	}
+	if out := renderStale(nil); out != "" {
+		t.Fatalf("expect nothing without stale issues, got %q", out)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// runState remembers when the report of each project was last delivered,
+// so the next run can report only the issues updated since.
+type runState struct {
+	LastRun map[string]time.Time `json:"last_run"`
+}
+
+// stateFilePath returns the configured state file, by default state.json
+// next to the config file.
+func stateFilePath() string {
+	if len(config.StateFile) > 0 {
+		return config.StateFile
+	}
+	return filepath.Join(filepath.Dir(configFile), "state.json")
+}
+
+// loadRunState reads the state file, a missing file is an empty state.
+func loadRunState(path string) (runState, error) {
+	state := runState{LastRun: make(map[string]time.Time)}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err = json.Unmarshal(data, &state); err != nil {
+		return state, err
+	}
+	if state.LastRun == nil {
+		state.LastRun = make(map[string]time.Time)
+	}
+	return state, nil
+}
+
+// saveRunState writes the state file atomically: a crash leaves either the
+// old or the new state, never a truncated file.
+func saveRunState(path string, state runState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces the file at path with data through a rename.
+func writeFileAtomic(path string, data []byte) error {
+	// The temporary file must be on the same file system for the rename.
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
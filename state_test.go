@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	state, err := loadRunState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.LastRun) != 0 {
+		t.Fatalf("expect an empty state without file, got %v", state.LastRun)
+	}
+
+	lastRun := time.Date(2018, time.January, 2, 9, 0, 0, 0, time.UTC)
+	state.LastRun["TEST"] = lastRun
+	if err = saveRunState(path, state); err != nil {
+		t.Fatal(err)
+	}
+	state, err = loadRunState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.LastRun["TEST"].Equal(lastRun) {
+		t.Fatalf("expect last run %v, got %v", lastRun, state.LastRun["TEST"])
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("expect only the state file to be left, got %d files", len(files))
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// addIssuesToActiveSprint moves the issues matching jql into the active
+// sprint of the project on the board, e.g. the ones created without a
+// sprint with "sprint is EMPTY AND resolution is EMPTY". It returns how many
+// issues were moved and the batches which failed.
+//
+// The query is limited to the project, so a broad query can't sweep in the
+// issues of the whole Jira. Nothing is moved if more than maxIssues match,
+// 0 means no limit.
+func addIssuesToActiveSprint(project string, boardID int, jql string, maxIssues int) (int, []BatchError, error) {
+	if len(project) == 0 {
+		return 0, nil, fmt.Errorf("a project is required to add issues to the active sprint")
+	}
+	sprint, err := getActiveSprint(project, boardID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if sprint.State != "active" {
+		return 0, nil, fmt.Errorf("sprint %s is %s, issues are only added to an active sprint", sprint.Name, sprint.State)
+	}
+
+	query := NewJQL().Project(project).Where(jql).String()
+	if maxIssues > 0 {
+		total, err := countJiraIssues(query)
+		if err != nil {
+			return 0, nil, err
+		}
+		if total > maxIssues {
+			return 0, nil, fmt.Errorf("%d issues match %q, more than the limit of %d", total, query, maxIssues)
+		}
+	}
+	issues, err := queryJiraIssuesWithOptions(query, &jira.SearchOptions{Fields: []string{"key"}})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	failures := moveIssuesToSprint(sprint.ID, issues)
+	moved := len(issues)
+	for _, failure := range failures {
+		moved -= len(failure.IssueIDs)
+	}
+	logger.Info("issues added to the active sprint", "sprint_id", sprint.ID, "issues", moved, "failed_batches", len(failures))
+	return moved, failures, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestAddIssuesToActiveSprint(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST 1", State: "active"})
+	f.addIssue(0, newFakeIssue(1, jira.StatusCategoryToDo))
+	f.addIssue(0, newFakeIssue(2, jira.StatusCategoryInProgress))
+	var queries []string
+	f.search = func(jql string) []jira.Issue {
+		if !strings.Contains(jql, "sprint is EMPTY") {
+			// The issues in the active sprint, see moveIssuesToSprint.
+			return nil
+		}
+		queries = append(queries, jql)
+		var issues []jira.Issue
+		for _, issue := range f.issues {
+			if issue.sprintID == 0 {
+				issues = append(issues, issue.Issue)
+			}
+		}
+		return issues
+	}
+
+	if _, _, err := addIssuesToActiveSprint("TEST", 1, "sprint is EMPTY", 1); err == nil || !strings.Contains(err.Error(), "limit of 1") {
+		t.Fatalf("expect the limit to be exceeded, got %v", err)
+	}
+	if n := f.countRequests("POST"); n != 0 {
+		t.Fatalf("expect nothing moved over the limit, got %d moves", n)
+	}
+
+	moved, failures, err := addIssuesToActiveSprint("TEST", 1, "sprint is EMPTY", 0)
+	if err != nil || moved != 2 || len(failures) > 0 {
+		t.Fatalf("expect 2 issues moved, got %d, %v, %v", moved, failures, err)
+	}
+	if keys := f.issuesIn(1); len(keys) != 2 {
+		t.Fatalf("expect the issues in the active sprint, got %v", keys)
+	}
+	for _, jql := range queries {
+		if !strings.HasPrefix(jql, `project = "TEST" AND (sprint is EMPTY)`) {
+			t.Fatalf("expect the query limited to the project, got %q", jql)
+		}
+	}
+
+	if _, _, err := addIssuesToActiveSprint("", 1, "sprint is EMPTY", 0); err == nil {
+		t.Fatal("expect an error without a project")
+	}
+}
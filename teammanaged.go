@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// Team-managed (next-gen) projects have a single board of type simple
+// instead of the scrum and kanban boards of the company-managed ones. The
+// board only has sprints if the Sprints feature is on in the project
+// settings, otherwise the agile API rejects or returns no sprints for it.
+const (
+	projectStyleClassic = "classic"
+	projectStyleNextGen = "next-gen"
+)
+
+// jiraProject is the part of a project of the REST API telling its style.
+// Jira Server has no team-managed projects and leaves both fields out.
+type jiraProject struct {
+	Key        string `json:"key"`
+	Style      string `json:"style"`
+	Simplified bool   `json:"simplified"`
+}
+
+// isTeamManagedProject tells whether the project is team-managed. The
+// simplified flag is only trusted without a known style.
+func isTeamManagedProject(project string) (bool, error) {
+	req, err := newJiraRequest(globalCtx, "GET", "rest/api/2/project/"+url.PathEscape(project), nil)
+	if err != nil {
+		return false, err
+	}
+
+	p := new(jiraProject)
+	if _, err = doWithRetry(req, p); err != nil {
+		return false, err
+	}
+	switch p.Style {
+	case projectStyleNextGen:
+		return true, nil
+	case projectStyleClassic:
+		return false, nil
+	default:
+		return p.Simplified, nil
+	}
+}
+
+// lookupTeamManagedBoardID returns the simple board of the project if it is
+// team-managed, ok is false if it isn't or its style is unknown. It is used
+// when the project has no board of the configured type.
+func lookupTeamManagedBoardID(project string) (id int, ok bool, err error) {
+	teamManaged, err := isTeamManagedProject(project)
+	if err != nil {
+		logger.Warn("can not get the project style", "project", project, "error", err)
+		return 0, false, nil
+	}
+	if !teamManaged {
+		return 0, false, nil
+	}
+	logger.Info("project is team-managed, using its board", "project", project, "board_type", boardTypeSimple)
+	id, err = lookupBoardID(project, boardTypeSimple)
+	return id, true, err
+}
+
+// explainSprintError adds to an error of the sprints of the board why a
+// team-managed board may have none. err is returned as is for the other
+// boards, or if the board can't be fetched.
+func explainSprintError(boardID int, err error) error {
+	board, berr := getBoard(boardID)
+	if berr != nil || board.Type != boardTypeSimple {
+		return err
+	}
+	return teamManagedSprintError(board, err)
+}
+
+func teamManagedSprintError(board jira.Board, err error) error {
+	return fmt.Errorf("%v: board %s (%d) is the board of a team-managed project, "+
+		"it only has sprints with the Sprints feature turned on in the project settings", err, board.Name, board.ID)
+}
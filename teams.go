@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+)
+
+// teamsHealthColors are the theme colors of the sprint health, the same as
+// the Slack ones.
+var teamsHealthColors = map[string]string{
+	"good":    "2EB886",
+	"warning": "DAA038",
+	"danger":  "A30200",
+}
+
+// teamsMessageCard is the message card of a Microsoft Teams incoming
+// webhook, see
+// https://docs.microsoft.com/outlook/actionable-messages/message-card-reference
+type teamsMessageCard struct {
+	Type       string             `json:"@type"`
+	Context    string             `json:"@context"`
+	Summary    string             `json:"summary"`
+	ThemeColor string             `json:"themeColor,omitempty"`
+	Title      string             `json:"title"`
+	Sections   []teamsCardSection `json:"sections"`
+}
+
+type teamsCardSection struct {
+	ActivityTitle    string          `json:"activityTitle"`
+	ActivitySubtitle string          `json:"activitySubtitle,omitempty"`
+	Facts            []teamsCardFact `json:"facts,omitempty"`
+	Text             string          `json:"text"`
+}
+
+type teamsCardFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// buildTeamsCard renders the report as a message card with a section per
+// project. The card is colored by the least healthy sprint.
+func buildTeamsCard(report SprintReport) teamsMessageCard {
+	card := teamsMessageCard{
+		Type:    "MessageCard",
+		Context: "https://schema.org/extensions",
+		Summary: report.Subject(),
+		Title:   report.Subject(),
+	}
+	worst := -1
+	for _, r := range report.Projects {
+		total, categories, donePercent := sprintHealth(r)
+		if worst < 0 || donePercent < worst {
+			worst = donePercent
+		}
+		section := teamsCardSection{
+			ActivityTitle:    r.title(),
+			ActivitySubtitle: fmt.Sprintf("%d%% of %d issues done", donePercent, total),
+			// Teams collapses the whitespace of the text, but keeps it
+			// in a pre block.
+			Text: "<pre>" + html.EscapeString(renderProjectText(r)) + "</pre>",
+		}
+		for _, category := range statusCategoryColumns(r.report) {
+			section.Facts = append(section.Facts, teamsCardFact{Name: category, Value: fmt.Sprint(categories[category])})
+		}
+		card.Sections = append(card.Sections, section)
+	}
+	if worst >= 0 {
+		card.ThemeColor = teamsHealthColors[sprintHealthColor(worst)]
+	}
+	return card
+}
+
+// teamsNotifier posts the report to a Microsoft Teams incoming webhook.
+type teamsNotifier struct {
+	webhook string
+}
+
+func (n teamsNotifier) Name() string {
+	return "teams"
+}
+
+func (n teamsNotifier) Send(ctx context.Context, report SprintReport) error {
+	payload, err := json.Marshal(buildTeamsCard(report))
+	if err != nil {
+		return err
+	}
+	if config.DryRun {
+		fmt.Printf("[dry-run] post to teams: %s\n", payload)
+		return nil
+	}
+
+	req, err := http.NewRequest("POST", n.webhook, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("can not post report to teams webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("can not post report to teams webhook: %s %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
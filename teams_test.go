@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func newTeamsTestReport() SprintReport {
+	return SprintReport{Projects: []projectReport{
+		{project: "TEST", sprint: jira.Sprint{Name: "TEST 1"}, report: buildAssigneeReport([]jira.Issue{
+			newReportIssue("TEST-1", "alice", "Done", 3),
+			newReportIssue("TEST-2", "bob", "To Do", 1),
+		})},
+		{project: "PD", sprint: jira.Sprint{Name: "PD 1"}, report: buildAssigneeReport([]jira.Issue{
+			newReportIssue("PD-1", "alice", "Done", 3),
+		})},
+	}}
+}
+
+func TestTeamsNotifier(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	var card teamsMessageCard
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&card); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte("1"))
+	}))
+	defer server.Close()
+
+	if err := (teamsNotifier{webhook: server.URL}).Send(context.Background(), newTeamsTestReport()); err != nil {
+		t.Fatal(err)
+	}
+	if card.Type != "MessageCard" || card.Title != "Sprint report: TEST, PD" || len(card.Sections) != 2 {
+		t.Fatalf("unexpected card %+v", card)
+	}
+	// TEST is only half done.
+	if card.ThemeColor != teamsHealthColors["warning"] {
+		t.Fatalf("expect the color of the least healthy sprint, got %s", card.ThemeColor)
+	}
+	section := card.Sections[0]
+	if section.ActivityTitle != "TEST: Sprint TEST 1" || section.ActivitySubtitle != "50% of 2 issues done" ||
+		!strings.Contains(section.Text, "<pre>") || !strings.Contains(section.Text, "alice") {
+		t.Fatalf("unexpected section %+v", section)
+	}
+	if len(section.Facts) != 2 || section.Facts[0].Name != "To Do" || section.Facts[1].Value != "1" {
+		t.Fatalf("expect the status categories as facts, got %+v", section.Facts)
+	}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Webhook message delivery failed", http.StatusBadRequest)
+	})
+	err := (teamsNotifier{webhook: server.URL}).Send(context.Background(), newTeamsTestReport())
+	if err == nil || !strings.Contains(err.Error(), "Webhook message delivery failed") {
+		t.Fatalf("expect the webhook error, got %v", err)
+	}
+}
+
+func TestTeamsNotifierDryRun(t *testing.T) {
+	config = &Config{DryRun: true}
+	defer func() { config = nil }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expect no request in dry-run")
+	}))
+	defer server.Close()
+
+	if err := (teamsNotifier{webhook: server.URL}).Send(context.Background(), newTeamsTestReport()); err != nil {
+		t.Fatal(err)
+	}
+}
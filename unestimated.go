@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// unestimatedIssues returns the issues of the sprint without story points,
+// so they can be estimated. Subtasks are left out unless report
+// unestimated-subtasks is set, they are usually estimated through their
+// parent.
+func unestimatedIssues(sprintID int) ([]jira.Issue, error) {
+	issues, err := issuesInSprint(sprintID)
+	if err != nil {
+		return nil, err
+	}
+	return filterUnestimated(issues), nil
+}
+
+// filterUnestimated returns the issues without story points, see
+// unestimatedIssues.
+func filterUnestimated(issues []jira.Issue) []jira.Issue {
+	var unestimated []jira.Issue
+	for _, issue := range issues {
+		if isSubtask(issue) && !config.Report.UnestimatedSubtasks {
+			continue
+		}
+		if _, ok := storyPoints(issue); !ok {
+			unestimated = append(unestimated, issue)
+		}
+	}
+	return unestimated
+}
+
+// renderUnestimated lists the keys and the assignees of the issues on one
+// line, or returns "" if there are none.
+func renderUnestimated(issues []jira.Issue) string {
+	if len(issues) == 0 {
+		return ""
+	}
+	items := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		items = append(items, fmt.Sprintf("%s (%s)", issue.Key, issueAssignee(issue)))
+	}
+	return fmt.Sprintf("%d unestimated: %s", len(issues), strings.Join(items, ", "))
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestUnestimatedIssues(t *testing.T) {
+	f, cleanup := newFakeJira(t)
+	defer cleanup()
+	config.Jira.StoryPointField = "customfield_10001"
+
+	subtask := newReportIssue("TEST-3", "bob", "To Do", -1)
+	subtask.Fields.Type.Subtask = true
+	f.addIssue(1, newReportIssue("TEST-1", "alice", "To Do", 3))
+	f.addIssue(1, newReportIssue("TEST-2", "", "To Do", -1))
+	f.addIssue(1, subtask)
+	f.addIssue(2, newReportIssue("TEST-4", "alice", "To Do", -1))
+
+	keys := func(issues []jira.Issue) []string {
+		var keys []string
+		for _, issue := range issues {
+			keys = append(keys, issue.Key)
+		}
+		return keys
+	}
+
+	issues, err := unestimatedIssues(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(issues); len(got) != 1 || got[0] != "TEST-2" {
+		t.Fatalf("expect TEST-2 without the subtask, got %v", got)
+	}
+	if expect := "1 unestimated: TEST-2 (Unassigned)"; renderUnestimated(issues) != expect {
+		t.Fatalf("expect %q, got %q", expect, renderUnestimated(issues))
+	}
+
+	config.Report.UnestimatedSubtasks = true
+	if issues, err = unestimatedIssues(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(issues); len(got) != 2 || got[1] != "TEST-3" {
+		t.Fatalf("expect TEST-2 and the subtask, got %v", got)
+	}
+
+	if renderUnestimated(nil) != "" {
+		t.Fatal("expect nothing without unestimated issues")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// The Jira Cloud bulk user endpoint returns at most this many users.
+const maxUserBatchSize = 50
+
+// userCache remembers the resolved users for the lifetime of the process, a
+// nil user is one Jira doesn't know anymore.
+var userCache = struct {
+	sync.Mutex
+	users map[string]*jira.User
+}{users: make(map[string]*jira.User)}
+
+func resetUserCache() {
+	userCache.Lock()
+	userCache.users = make(map[string]*jira.User)
+	userCache.Unlock()
+}
+
+// userID returns the ID Jira knows the user by. Jira Cloud only knows the
+// account ID, which the vendored jira.User lacks, it is taken from the user
+// URL.
+func userID(user *jira.User) string {
+	if !isJiraServer() {
+		if id := userAccountID(user); len(id) > 0 {
+			return id
+		}
+	}
+	if len(user.Key) > 0 {
+		return user.Key
+	}
+	return user.Name
+}
+
+// userAccountID returns the account ID of the user URL of Jira Cloud, like
+// https://example.atlassian.net/rest/api/2/user?accountId=5b10a2844c20165700ede21g.
+func userAccountID(user *jira.User) string {
+	u, err := url.Parse(user.Self)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("accountId")
+}
+
+// cloudUser is a user of Jira Cloud, found by its account ID.
+type cloudUser struct {
+	jira.User
+	AccountID string `json:"accountId"`
+}
+
+// resolveAssignees fills in the display names and avatars of the assignees
+// which the search returned without them, so the reports show human names.
+// The users are looked up in batches once per run. An assignee Jira doesn't
+// return, like a deactivated user, keeps its ID.
+func resolveAssignees(issues []jira.Issue) error {
+	var missing []string
+	seen := make(map[string]bool)
+	userCache.Lock()
+	for _, issue := range issues {
+		assignee := issueAssigneeUser(issue)
+		if assignee == nil || len(assignee.DisplayName) > 0 {
+			continue
+		}
+		id := userID(assignee)
+		if _, ok := userCache.users[id]; ok || seen[id] || len(id) == 0 {
+			continue
+		}
+		seen[id] = true
+		missing = append(missing, id)
+	}
+	userCache.Unlock()
+
+	users, err := lookupUsers(missing)
+	if err != nil {
+		return err
+	}
+
+	userCache.Lock()
+	defer userCache.Unlock()
+	for _, id := range missing {
+		userCache.users[id] = users[id]
+	}
+	for _, issue := range issues {
+		assignee := issueAssigneeUser(issue)
+		if assignee == nil || len(assignee.DisplayName) > 0 {
+			continue
+		}
+		if user := userCache.users[userID(assignee)]; user != nil {
+			assignee.DisplayName = user.DisplayName
+			assignee.AvatarUrls = user.AvatarUrls
+		}
+	}
+	return nil
+}
+
+func issueAssigneeUser(issue jira.Issue) *jira.User {
+	if issue.Fields == nil {
+		return nil
+	}
+	return issue.Fields.Assignee
+}
+
+// lookupUsers returns the users with the IDs by ID, the ones Jira doesn't
+// return are left out.
+func lookupUsers(ids []string) (map[string]*jira.User, error) {
+	users := make(map[string]*jira.User, len(ids))
+	// Jira Cloud finds the users by account ID, Jira Server by key.
+	if isJiraServer() {
+		// Jira Server/Data Center has no bulk user endpoint.
+		for _, id := range ids {
+			user, err := getUser(id)
+			if err != nil {
+				return nil, err
+			}
+			if user != nil {
+				users[id] = user
+			}
+		}
+		return users, nil
+	}
+
+	for start := 0; start < len(ids); start += maxUserBatchSize {
+		end := start + maxUserBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch, err := getUsersBulk(ids[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for i := range batch {
+			users[batch[i].AccountID] = &batch[i].User
+		}
+	}
+	return users, nil
+}
+
+// getUser returns the user with the key, or nil if there is none.
+func getUser(key string) (*jira.User, error) {
+	req, err := newJiraRequest(globalCtx, "GET", "rest/api/2/user?key="+url.QueryEscape(key), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	user := new(jira.User)
+	if _, err = doWithRetry(req, user); err != nil {
+		if apiErr, ok := err.(*jiraAPIError); ok && apiErr.StatusCode == http.StatusNotFound {
+			logger.Warn("assignee not found", "key", key)
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// getUsersBulk returns the users of Jira Cloud with the account IDs which
+// still exist. The endpoint doesn't take the keys of Jira Server.
+func getUsersBulk(accountIDs []string) ([]cloudUser, error) {
+	query := url.Values{"accountId": accountIDs}
+	query.Set("maxResults", fmt.Sprint(len(accountIDs)))
+	req, err := newJiraRequest(globalCtx, "GET", "rest/api/2/user/bulk?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	results := new(struct {
+		Values []cloudUser `json:"values"`
+	})
+	if _, err = doWithRetry(req, results); err != nil {
+		return nil, err
+	}
+	return results.Values, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func newAssignedIssue(key, assignee string) jira.Issue {
+	return jira.Issue{Key: key, Fields: &jira.IssueFields{Assignee: &jira.User{Key: assignee}}}
+}
+
+// newCloudAssignedIssue is assigned to the account ID, Jira Cloud has no
+// user keys.
+func newCloudAssignedIssue(key, accountID string) jira.Issue {
+	self := "https://example.atlassian.net/rest/api/2/user?accountId=" + accountID
+	return jira.Issue{Key: key, Fields: &jira.IssueFields{Assignee: &jira.User{Self: self}}}
+}
+
+func TestResolveAssigneesBulk(t *testing.T) {
+	var requests []string
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		if r.URL.Path != "/rest/api/2/user/bulk" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if ids := r.URL.Query()["accountId"]; len(ids) != 2 {
+			t.Errorf("expect 2 account IDs, got %v", ids)
+		}
+		// gone is deactivated and not returned.
+		writeJSON(t, w, map[string]interface{}{"values": []cloudUser{{
+			User: jira.User{
+				DisplayName: "Alice Liddell",
+				AvatarUrls:  jira.AvatarUrls{Four8X48: "http://avatar/alice"},
+			},
+			AccountID: "alice",
+		}}})
+	})()
+
+	named := newCloudAssignedIssue("TEST-4", "bob")
+	named.Fields.Assignee.DisplayName = "Bob"
+	issues := []jira.Issue{
+		newCloudAssignedIssue("TEST-1", "alice"),
+		newCloudAssignedIssue("TEST-2", "alice"),
+		newCloudAssignedIssue("TEST-3", "gone"),
+		named,
+		{Key: "TEST-5", Fields: &jira.IssueFields{}},
+	}
+	if err := resolveAssignees(issues); err != nil {
+		t.Fatal(err)
+	}
+
+	report := buildAssigneeReport(issues)
+	if summary := report["Alice Liddell"]; summary.Issues != 2 || summary.AvatarURL != "http://avatar/alice" {
+		t.Fatalf("expect 2 issues of Alice with her avatar, got %+v", summary)
+	}
+	if report["gone"].Issues != 1 || report["Bob"].Issues != 1 {
+		t.Fatalf("expect the ID of the deactivated user and Bob, got %v", report)
+	}
+
+	// Both users are cached, found or not.
+	if err := resolveAssignees([]jira.Issue{newCloudAssignedIssue("TEST-6", "alice"), newCloudAssignedIssue("TEST-7", "gone")}); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("expect 1 request, got %v", requests)
+	}
+}
+
+func TestResolveAssigneesServer(t *testing.T) {
+	defer newTestJiraServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/user" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if r.URL.Query().Get("key") != "alice" {
+			http.Error(w, `{"errorMessages": ["The user does not exist"]}`, http.StatusNotFound)
+			return
+		}
+		writeJSON(t, w, jira.User{Key: "alice", DisplayName: "Alice Liddell"})
+	})()
+	config.Jira.Deployment = deploymentServer
+
+	issues := []jira.Issue{newAssignedIssue("TEST-1", "alice"), newAssignedIssue("TEST-2", "gone")}
+	if err := resolveAssignees(issues); err != nil {
+		t.Fatal(err)
+	}
+	if name := issueAssignee(issues[0]); name != "Alice Liddell" {
+		t.Fatalf("expect Alice Liddell, got %s", name)
+	}
+	if name := issueAssignee(issues[1]); name != "gone" {
+		t.Fatalf("expect the ID, got %s", name)
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"testing"
 )
 
-func testEscaperValue(t testing.T) {
+func testEscaperValue(t *testing.T) {
 	if escaperValue("") != "" {
 		t.Error()
 	}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// SprintVelocity is the story points completed in a sprint.
+type SprintVelocity struct {
+	Sprint jira.Sprint
+	Points float64
+	Issues int
+}
+
+// velocity returns the completed story points of the last n closed sprints
+// of the project, oldest first. A sprint without completed issues has zero
+// points. An issue only counts for the sprint it was resolved in, see
+// resolvedInSprint.
+func velocity(project string, boardID int, n int) ([]SprintVelocity, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("the velocity needs at least 1 sprint, got %d", n)
+	}
+	sprints, err := getSprints(boardID, jira.GetAllSprintsOptions{State: "closed"})
+	if err != nil {
+		return nil, err
+	}
+
+	var closed []jira.Sprint
+	for _, sprint := range sprints {
+		if sprintBelongsToProject(sprint, project) && sprintFinishedAt(sprint) != nil {
+			closed = append(closed, sprint)
+		}
+	}
+	sort.SliceStable(closed, func(i, j int) bool {
+		return sprintFinishedAt(closed[i]).Before(*sprintFinishedAt(closed[j]))
+	})
+	if len(closed) > n {
+		closed = closed[len(closed)-n:]
+	}
+
+	velocities := make([]SprintVelocity, 0, len(closed))
+	for _, sprint := range closed {
+		issues, err := queryJiraIssues(NewJQL().Sprint(sprint.ID).Done(config.Jira.DoneStatuses...).String())
+		if err != nil {
+			return nil, err
+		}
+		issues = resolvedInSprint(issues, sprint)
+
+		v := SprintVelocity{Sprint: sprint, Issues: len(issues)}
+		for _, issue := range issues {
+			if points, ok := storyPoints(issue); ok {
+				v.Points += points
+			}
+		}
+		velocities = append(velocities, v)
+	}
+
+	return velocities, nil
+}
+
+// resolvedInSprint returns the issues resolved by the time the sprint was
+// completed. The sprint query also matches the issues carried over to a
+// later sprint and resolved there, they would count in every sprint they
+// passed through. The issues without a resolution date are kept.
+func resolvedInSprint(issues []jira.Issue, sprint jira.Sprint) []jira.Issue {
+	finished := sprintFinishedAt(sprint)
+	if finished == nil {
+		return issues
+	}
+	var resolved []jira.Issue
+	for _, issue := range issues {
+		if date := resolutionDate(issue); date.IsZero() || !date.After(*finished) {
+			resolved = append(resolved, issue)
+		}
+	}
+	return resolved
+}
+
+// sprintFinishedAt returns when the sprint was completed, or its planned
+// end if Jira doesn't tell.
+func sprintFinishedAt(sprint jira.Sprint) *time.Time {
+	if sprint.CompleteDate != nil {
+		return sprint.CompleteDate
+	}
+	return sprint.EndDate
+}
+
+// velocityTrend returns the average points per sprint and the slope of the
+// least squares line through them, in points per sprint.
+func velocityTrend(velocities []SprintVelocity) (average, slope float64) {
+	n := float64(len(velocities))
+	if n == 0 {
+		return 0, 0
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for i, v := range velocities {
+		x := float64(i)
+		sumX += x
+		sumY += v.Points
+		sumXY += x * v.Points
+		sumXX += x * x
+	}
+
+	average = sumY / n
+	if d := n*sumXX - sumX*sumX; d != 0 {
+		slope = (n*sumXY - sumX*sumY) / d
+	}
+	return average, slope
+}
+
+// renderVelocity renders the velocities as a Markdown table followed by the
+// average and trend.
+func renderVelocity(velocities []SprintVelocity) string {
+	var buf bytes.Buffer
+	buf.WriteString("| Sprint | Issues | Story Points |\n")
+	buf.WriteString("| --- | --- | --- |\n")
+	for _, v := range velocities {
+		fmt.Fprintf(&buf, "| %s | %d | %s |\n", escapeMarkdownCell(v.Sprint.Name), v.Issues, formatPoints(v.Points))
+	}
+
+	average, slope := velocityTrend(velocities)
+	fmt.Fprintf(&buf, "\nAverage: %.1f points per sprint, trend: %+.1f points per sprint\n", average, slope)
+	return buf.String()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestVelocity(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	config.Jira.StoryPointField = "customfield_10001"
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST 1", State: "closed", EndDate: day(9, 7)})
+	f.addSprint(jira.Sprint{ID: 3, Name: "TEST 3", State: "closed", EndDate: day(9, 21)})
+	f.addSprint(jira.Sprint{ID: 2, Name: "TEST 2", State: "closed", EndDate: day(9, 14)})
+	f.addSprint(jira.Sprint{ID: 4, Name: "OTHER 4", State: "closed", EndDate: day(9, 28)})
+	f.addSprint(jira.Sprint{ID: 5, Name: "TEST 5", State: "active", EndDate: day(10, 5)})
+
+	f.search = func(jql string) []jira.Issue {
+		if jql != `sprint = 3 AND statusCategory = "Done"` {
+			return nil
+		}
+		return []jira.Issue{
+			newReportIssue("TEST-1", "alice", "Done", 3),
+			newReportIssue("TEST-2", "bob", "Done", 5),
+		}
+	}
+
+	velocities, err := velocity("TEST", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range velocities {
+		got = append(got, v.Sprint.Name+"="+strconv.FormatFloat(v.Points, 'f', -1, 64))
+	}
+	if len(got) != 2 || got[0] != "TEST 2=0" || got[1] != "TEST 3=8" {
+		t.Fatalf("expect the last two sprints oldest first, got %v", got)
+	}
+
+	for _, n := range []int{0, -1} {
+		if _, err := velocity("TEST", 1, n); err == nil || !strings.Contains(err.Error(), "at least 1 sprint") {
+			t.Errorf("%d sprints: expect an error, got %v", n, err)
+		}
+	}
+}
+
+func TestResolvedInSprint(t *testing.T) {
+	sprint := jira.Sprint{ID: 1, Name: "TEST 1", EndDate: day(9, 7), CompleteDate: day(9, 8)}
+
+	// TEST-1 was carried over and resolved in the next sprint.
+	carried := newReportIssue("TEST-1", "alice", "Done", 3)
+	carried.Fields.Resolutiondate = jira.Time(*day(9, 12))
+	done := newReportIssue("TEST-2", "bob", "Done", 5)
+	done.Fields.Resolutiondate = jira.Time(*day(9, 8))
+	unknown := newReportIssue("TEST-3", "bob", "Done", 1)
+
+	resolved := resolvedInSprint([]jira.Issue{carried, done, unknown}, sprint)
+	if len(resolved) != 2 || resolved[0].Key != "TEST-2" || resolved[1].Key != "TEST-3" {
+		t.Fatalf("expect TEST-2 and TEST-3, got %v", resolved)
+	}
+}
+
+func TestVelocityTrend(t *testing.T) {
+	average, slope := velocityTrend([]SprintVelocity{{Points: 10}, {Points: 12}, {Points: 14}})
+	if average != 12 || math.Abs(slope-2) > 1e-9 {
+		t.Fatalf("expect average 12 and slope 2, got %v %v", average, slope)
+	}
+	if average, slope = velocityTrend(nil); average != 0 || slope != 0 {
+		t.Fatal("expect zero trend without sprints")
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"html"
+	"io/ioutil"
+	"os"
 	"strings"
+	"time"
 
 	jira "github.com/andygrunwald/go-jira"
 	"github.com/google/go-github/github"
@@ -35,6 +38,36 @@ func newRotateSprintCommand() *cobra.Command {
 	return m
 }
 
+var (
+	reportOutput           string
+	reportOutFile          string
+	reportComponents       []string
+	reportLabels           []string
+	reportGroupByComponent bool
+	reportGroupByEpic      bool
+	reportSprintID         int
+	reportSinceLastRun     bool
+	reportBoardIDs         []int
+)
+
+func newSprintReportCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "sprint-report",
+		Short: "Post Active Sprint Report To Slack",
+		Run:   runSprintReportCommandFunc,
+	}
+	m.Flags().StringVar(&reportOutput, "output", "", "Write the report as markdown or json instead of posting it, json has an array of the reports of several projects")
+	m.Flags().StringVar(&reportOutFile, "out-file", "", "Write the report to this file instead of posting it, - for stdout")
+	m.Flags().StringSliceVar(&reportComponents, "component", nil, "Only report the issues in these components, overrides report components")
+	m.Flags().StringSliceVar(&reportLabels, "label", nil, "Only report the issues with these labels, overrides report labels")
+	m.Flags().BoolVar(&reportGroupByComponent, "group-by-component", false, "Add a table grouped by component")
+	m.Flags().BoolVar(&reportGroupByEpic, "group-by-epic", false, "Add a table grouped by epic")
+	m.Flags().IntVar(&reportSprintID, "sprint-id", 0, "Report this sprint instead of the active one, like a closed sprint")
+	m.Flags().IntSliceVar(&reportBoardIDs, "board-id", nil, "Report the active sprints of these boards as one, overrides report board-ids")
+	m.Flags().BoolVar(&reportSinceLastRun, "since-last-run", false, "Only report the issues updated since the last run, recorded in the state file")
+	return m
+}
+
 func newWeeklyCommand() *cobra.Command {
 	m := &cobra.Command{
 		Use:   "weekly",
@@ -42,13 +75,292 @@ func newWeeklyCommand() *cobra.Command {
 	}
 	m.AddCommand(newWeeklyReportCommand())
 	m.AddCommand(newRotateSprintCommand())
+	m.AddCommand(newSprintReportCommand())
+	m.AddCommand(newPruneSprintsCommand())
+	m.AddCommand(newRealignSprintsCommand())
+	m.AddCommand(newCreateSprintCommand())
+	m.AddCommand(newSweepSprintCommand())
+	m.AddCommand(newWindowReportCommand())
+	m.AddCommand(newVelocityCommand())
+	m.AddCommand(newForecastCommand())
+	m.AddCommand(newCycleTimeCommand())
+	return m
+}
+
+var velocitySprints int
+
+func newVelocityCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "velocity",
+		Short: "Print The Velocity Of The Last Sprints",
+		Run:   runVelocityCommandFunc,
+	}
+	m.Flags().IntVar(&velocitySprints, "sprints", 6, "Number of closed sprints")
+	return m
+}
+
+func newForecastCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "forecast",
+		Short: "Forecast Whether The Active Sprint Completes",
+		Run:   runForecastCommandFunc,
+	}
+	return m
+}
+
+func newCycleTimeCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "cycle-time",
+		Short: "Print The Cycle Time Of The Issues Completed In The Active Sprint",
+		Run:   runCycleTimeCommandFunc,
+	}
+	return m
+}
+
+func runCycleTimeCommandFunc(cmd *cobra.Command, args []string) {
+	for _, project := range config.jiraProjects() {
+		boardID, err := getSprintBoardID(project)
+		perror(err)
+		sprint, err := getCurrentSprint(project, boardID)
+		perror(err)
+		times, average, err := sprintCycleTimes(sprint.ID)
+		perror(err)
+		fmt.Printf("[%s] %s: %s", project, sprint.Name, renderCycleTimes(times, average))
+	}
+}
+
+func runForecastCommandFunc(cmd *cobra.Command, args []string) {
+	for _, project := range config.jiraProjects() {
+		boardID, err := getSprintBoardID(project)
+		perror(err)
+		sprint, err := getCurrentSprint(project, boardID)
+		perror(err)
+		forecast, err := sprintForecast(sprint.ID)
+		perror(err)
+		fmt.Printf("[%s] %s\n", project, forecast)
+	}
+}
+
+func runVelocityCommandFunc(cmd *cobra.Command, args []string) {
+	for _, project := range config.jiraProjects() {
+		boardID, err := getSprintBoardID(project)
+		perror(err)
+		velocities, err := velocity(project, boardID, velocitySprints)
+		perror(err)
+		fmt.Printf("## %s\n\n%s\n", project, renderVelocity(velocities))
+	}
+}
+
+func newPruneSprintsCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "prune-sprints",
+		Short: "Delete Stale Empty Future Sprints",
+		Run:   runPruneSprintsCommandFunc,
+	}
 	return m
 }
 
+var (
+	realignAnchor        string
+	realignIncludeActive bool
+)
+
+func newRealignSprintsCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "realign-sprints",
+		Short: "Move The Sprint Dates Back On The Cadence",
+		Run:   runRealignSprintsCommandFunc,
+	}
+	m.Flags().StringVar(&realignAnchor, "anchor", "", "The start day of any sprint of the cadence, like 2018-10-01")
+	m.Flags().BoolVar(&realignIncludeActive, "include-active", false, "Realign the active sprint too, not only the future ones")
+	return m
+}
+
+func runRealignSprintsCommandFunc(cmd *cobra.Command, args []string) {
+	loc, err := sprintLocation(time.Local)
+	perror(err)
+	day, err := time.ParseInLocation(dayFormat, realignAnchor, loc)
+	if err != nil {
+		perrmsg(fmt.Sprintf("invalid --anchor %q, expect a day like 2018-10-01", realignAnchor))
+	}
+	anchor, err := alignSprintStart(day)
+	perror(err)
+
+	for _, project := range config.jiraProjects() {
+		boardID, err := getSprintBoardID(project)
+		perror(err)
+		realigned, err := realignSprints(project, boardID, anchor, realignIncludeActive)
+		for _, r := range realigned {
+			fmt.Printf("[%s] %s\n", project, r)
+		}
+		perror(err)
+		fmt.Printf("[%s] %d sprints realigned\n", project, len(realigned))
+	}
+}
+
+var (
+	createSprintName  string
+	createSprintGoal  string
+	createSprintStart string
+	createSprintEnd   string
+)
+
+func newCreateSprintCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "create-sprint",
+		Short: "Create A Future Sprint With The Name And Dates",
+		Run:   runCreateSprintCommandFunc,
+	}
+	m.Flags().StringVar(&createSprintName, "name", "", "The name of the sprint")
+	m.Flags().StringVar(&createSprintGoal, "goal", "", "The goal of the sprint")
+	m.Flags().StringVar(&createSprintStart, "start", "", "The start, a day like 2018-10-01 or a time like 2018-10-01T10:00:00+08:00")
+	m.Flags().StringVar(&createSprintEnd, "end", "", "The end like --start, one sprint-duration after the start by default")
+	return m
+}
+
+// parseSprintFlagTime parses the value of a date flag, a day starts at the
+// sprint-start-time-of-day in the sprint timezone.
+func parseSprintFlagTime(flag, value string) (time.Time, error) {
+	if t, err := time.Parse(dateFormat, value); err == nil {
+		return t, nil
+	}
+	loc, err := sprintLocation(time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	day, err := time.ParseInLocation(dayFormat, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --%s %q, expect a day like 2018-10-01 or a time like 2018-10-01T10:00:00+08:00", flag, value)
+	}
+	return alignSprintStart(day)
+}
+
+func runCreateSprintCommandFunc(cmd *cobra.Command, args []string) {
+	if len(strings.TrimSpace(createSprintName)) == 0 {
+		perrmsg("create-sprint needs a --name")
+	}
+	projects := config.jiraProjects()
+	if len(projects) != 1 {
+		perrmsg(fmt.Sprintf("create-sprint works on one project, got %s", strings.Join(projects, ", ")))
+	}
+
+	start, err := parseSprintFlagTime("start", createSprintStart)
+	perror(err)
+	end := sprintEnd(start)
+	if len(createSprintEnd) > 0 {
+		end, err = parseSprintFlagTime("end", createSprintEnd)
+		perror(err)
+	}
+	perror(checkSprintDates(start, end))
+
+	boardID, err := getSprintBoardID(projects[0])
+	perror(err)
+	sprint, err := createSprint(boardID, createSprintName, createSprintGoal, start.Format(dateFormat), end.Format(dateFormat))
+	perror(err)
+	start, end, err = createdSprintDates(sprint, start, end)
+	perror(err)
+	fmt.Printf("[%s] sprint %s (%d): %s - %s\n", projects[0], sprint.Name, sprint.ID, start.Format(dateFormat), end.Format(dateFormat))
+}
+
+// createdSprintDates returns the dates of a sprint from createSprint. An
+// existing sprint of the same name is returned as is, which is an error if
+// its dates aren't the requested ones. A sprint without dates, like one of a
+// dry run, has the requested dates.
+func createdSprintDates(sprint jira.Sprint, start, end time.Time) (time.Time, time.Time, error) {
+	if sprint.StartDate == nil || sprint.EndDate == nil {
+		return start, end, nil
+	}
+	if !sprint.StartDate.Equal(start) || !sprint.EndDate.Equal(end) {
+		return start, end, fmt.Errorf("sprint %s exists from %s to %s, not %s to %s", sprint.Name,
+			sprint.StartDate.Format(dateFormat), sprint.EndDate.Format(dateFormat),
+			start.Format(dateFormat), end.Format(dateFormat))
+	}
+	return *sprint.StartDate, *sprint.EndDate, nil
+}
+
+var (
+	windowFrom string
+	windowTo   string
+	windowDays int
+)
+
+func newWindowReportCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "window-report",
+		Short: "Report The Issues Updated In A Time Window, Regardless Of The Sprints",
+		Run:   runWindowReportCommandFunc,
+	}
+	m.Flags().StringVar(&windowFrom, "from", "", "The first day of the window, like 2018-10-01, --days before --to by default")
+	m.Flags().StringVar(&windowTo, "to", "", "The last day of the window, like 2018-10-05, up to now by default")
+	m.Flags().IntVar(&windowDays, "days", defaultWindowDays, "The length of the window in days without --from")
+	return m
+}
+
+func runWindowReportCommandFunc(cmd *cobra.Command, args []string) {
+	w, err := newReportWindow(windowFrom, windowTo, windowDays, nowFunc())
+	perror(err)
+	for _, project := range config.jiraProjects() {
+		report, err := buildWindowReport(project, w)
+		perror(err)
+		fmt.Print(renderWindowReport(project, w, report) + "\n")
+	}
+}
+
+func runPruneSprintsCommandFunc(cmd *cobra.Command, args []string) {
+	for _, project := range config.jiraProjects() {
+		boardID, err := getSprintBoardID(project)
+		perror(err)
+		deleted, err := pruneEmptyFutureSprints(project, boardID)
+		perror(err)
+		fmt.Printf("[%s] %d empty future sprints deleted %v\n", project, len(deleted), deleted)
+	}
+}
+
+var (
+	sweepJQL       string
+	sweepMaxIssues int
+)
+
+func newSweepSprintCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "sweep-sprint",
+		Short: "Move The Issues Matching A Query Into The Active Sprint",
+		Run:   runSweepSprintCommandFunc,
+	}
+	m.Flags().StringVar(&sweepJQL, "jql", "sprint is EMPTY AND resolution is EMPTY", "The issues to move, the query is limited to the project")
+	m.Flags().IntVar(&sweepMaxIssues, "max", 100, "Move nothing if more issues match, 0 means no limit")
+	return m
+}
+
+func runSweepSprintCommandFunc(cmd *cobra.Command, args []string) {
+	for _, project := range config.jiraProjects() {
+		boardID, err := getSprintBoardID(project)
+		perror(err)
+		moved, failures, err := addIssuesToActiveSprint(project, boardID, sweepJQL, sweepMaxIssues)
+		perror(err)
+		fmt.Printf("[%s] %d issues moved into the active sprint\n", project, moved)
+		if len(failures) > 0 {
+			perror(fmt.Errorf("[%s] %d batches failed to move, the first: %v", project, len(failures), failures[0]))
+		}
+	}
+}
+
 func runWeelyReportCommandFunc(cmd *cobra.Command, args []string) {
-	boardID := getBoardID(config.Jira.Project, "scrum")
-	sprints := getSprints(boardID, jira.GetAllSprintsOptions{})
-	lastSprint := getNearestFutureSprint(sprints)
+	// Every project gets its own page, titled by its sprint.
+	for _, project := range config.jiraProjects() {
+		genProjectWeeklyReport(project)
+	}
+}
+
+func genProjectWeeklyReport(project string) {
+	boardID, err := getSprintBoardID(project)
+	perror(err)
+	sprints, err := listSprints(boardID, upcomingSprintOptions())
+	perror(err)
+	lastSprint := getNearestFutureSprint(project, sprints)
+	if lastSprint == nil {
+		perrmsg(fmt.Sprintf("no upcoming sprint with dates found for project %s", project))
+	}
 
 	var body bytes.Buffer
 
@@ -63,23 +375,417 @@ func runWeelyReportCommandFunc(cmd *cobra.Command, args []string) {
 	genWeeklyReportToc(&body)
 	genWeeklyReportIssuesPRs(&body, githubStartDate, githubEndDate)
 	genWeeklyReportOnCall(&body, startDate, endDate)
-	genWeeklyReportProjects(&body, lastSprint)
+	genWeeklyReportProjects(&body, project, lastSprint)
 
 	formatPageEndForHtmlOutput(&body)
 
-	createWeeklyReport(lastSprint, body.String())
+	createWeeklyReport(project, lastSprint, body.String())
 }
 
 func runRotateSprintCommandFunc(cmd *cobra.Command, args []string) {
-	boardID := getBoardID(config.Jira.Project, "scrum")
-	activeSprint := getActiveSprint(boardID)
-	nextSprint := createNextSprint(boardID, *activeSprint.EndDate)
+	// A failed project doesn't keep the others from rotating.
+	for _, project := range config.jiraProjects() {
+		step := fmt.Sprintf("[%s] rotate sprint", project)
+		// A sprint not due for rotation is a skip, not a success, so the
+		// exit code and the summary file tell.
+		skipped, err := rotateProjectSprint(project)
+		if err == nil && len(skipped) > 0 {
+			runStatus.skip(step, skipped)
+			continue
+		}
+		runStatus.record(step, err)
+	}
+}
+
+// rotateProjectSprint rolls the sprint of the project over, it returns why
+// it was skipped if it was.
+func rotateProjectSprint(project string) (string, error) {
+	boardID, err := getSprintBoardID(project)
+	if err != nil {
+		return "", err
+	}
+	summary, err := rolloverSprint(project, boardID)
+	if err != nil {
+		return "", err
+	}
+
+	if summary.Skipped {
+		return fmt.Sprintf("sprint %s is not due for rotation yet", summary.ActiveSprint.Name), nil
+	}
+	msg := fmt.Sprintf("[%s] Current active Sprint %s is closed, %d incomplete issues are moved to Sprint %s",
+		project, summary.ClosedSprint.Name, summary.MovedIssues, summary.ActiveSprint.Name)
+	if summary.Resumed {
+		msg = fmt.Sprintf("[%s] The rotation of closed Sprint %s is resumed, Sprint %s is active",
+			project, summary.ClosedSprint.Name, summary.ActiveSprint.Name)
+	}
+	if len(summary.MissingIssues) > 0 {
+		keys := make([]string, 0, len(summary.MissingIssues))
+		for _, issue := range summary.MissingIssues {
+			if isSubtask(issue) {
+				keys = append(keys, issue.Key+" (subtask)")
+			} else {
+				keys = append(keys, issue.Key)
+			}
+		}
+		msg += fmt.Sprintf("\n%d issues were not moved: %s", len(keys), strings.Join(keys, ", "))
+	}
+	// The sprint is rotated, only the notification failed.
+	runStatus.record(fmt.Sprintf("[%s] post sprint rotation to slack", project), sendToSlack("%s", msg))
+	return "", nil
+}
+
+// projectReport is the sprint report of one project.
+type projectReport struct {
+	project string
+	sprint  jira.Sprint
+	report  map[string]AssigneeSummary
+	// components is only set when grouping by component.
+	components map[string]AssigneeSummary
+	// epics is only set when grouping by epic.
+	epics     map[string]AssigneeSummary
+	carryover CarryoverSummary
+	// since is set if only the issues updated since then are reported.
+	since time.Time
+	// warnings tell about the boards whose active sprints are not aligned.
+	warnings []string
+	// unestimated are the issues without story points.
+	unestimated []jira.Issue
+	// blocked are the flagged issues and the ones in a blocked status.
+	blocked []jira.Issue
+	// stale are the issues in progress without a recent update.
+	stale    []jira.Issue
+	progress sprintProgress
+	// chart is the PNG velocity chart, only with report velocity-chart.
+	chart []byte
+	// snapshot is set when the reports are stored in report history-dir,
+	// changes too if there is a previous report to compare with.
+	snapshot *reportSnapshot
+	changes  *reportChanges
+}
+
+// reportOptions selects what buildProjectReport reports.
+type reportOptions struct {
+	// sprintID is the sprint to report, 0 for the active sprint.
+	sprintID int
+	// since has the time of the last run of the projects, only the issues
+	// updated since are reported. A project without one gets the full
+	// sprint.
+	since map[string]time.Time
+}
+
+// buildProjectReport reports the sprint of the project selected by opts.
+// With report board-ids the active sprints of all the boards are reported
+// as one.
+func buildProjectReport(project string, opts reportOptions) (projectReport, error) {
+	sprints, err := reportSprints(project, opts.sprintID)
+	if err != nil {
+		return projectReport{}, err
+	}
+	sprint := &sprints[0].sprint
+
+	since := opts.since[project]
+	issues, err := querySprintIssues(sprints, since)
+	if err != nil {
+		return projectReport{}, err
+	}
+	// The progress is of the whole sprint, not of the issues updated since
+	// the last run.
+	progressIssues := issues
+	if !since.IsZero() {
+		if progressIssues, err = querySprintIssues(sprints, time.Time{}); err != nil {
+			return projectReport{}, err
+		}
+	}
+	if err = resolveAssignees(issues); err != nil {
+		return projectReport{}, err
+	}
+	if reportsLoggedTime() {
+		if err = resolveWorklogs(issues); err != nil {
+			return projectReport{}, err
+		}
+		keepSprintWorklogs(issues, *sprint, nowFunc())
+	}
+
+	r := projectReport{project: project, sprint: *sprint, report: buildAssigneeReport(issues), since: since}
+	r.warnings = checkSprintWindows(sprints)
+	r.unestimated = filterUnestimated(issues)
+	r.blocked = blockedIssues(issues)
+	r.stale = filterStale(issues, staleAfter(), nowFunc())
+	r.progress = newSprintProgress(*sprint, progressIssues)
+	if config.Report.GroupByComponent {
+		r.components = buildComponentReport(issues)
+	}
+	if config.Report.GroupByEpic {
+		if r.epics, err = buildEpicReport(issues); err != nil {
+			return projectReport{}, err
+		}
+	}
+	if n := config.Report.VelocityChart; n > 0 {
+		velocities, err := velocity(project, sprints[0].boardID, n)
+		if err != nil {
+			return projectReport{}, err
+		}
+		if r.chart, err = renderVelocityChart(velocities); err != nil {
+			return projectReport{}, err
+		}
+	}
+	// The sprint of the report comes first, the others are added to it.
+	var carryovers []CarryoverSummary
+	for _, s := range sprints {
+		carryover, err := sprintCarryover(project, s.boardID, s.sprint)
+		if err != nil {
+			return projectReport{}, err
+		}
+		if s.sprint.ID == sprint.ID {
+			carryovers = append([]CarryoverSummary{carryover}, carryovers...)
+			continue
+		}
+		carryovers = append(carryovers, carryover)
+	}
+	r.carryover = combineCarryover(carryovers)
+
+	// A report of the issues updated since the last run is not the whole
+	// sprint, so it has nothing to compare with.
+	if dir := config.Report.HistoryDir; len(dir) > 0 && since.IsZero() {
+		snapshot := newReportSnapshot(r, issues, nowFunc())
+		r.snapshot = &snapshot
+		previous, ok, err := loadLatestSnapshot(dir, project)
+		if err != nil {
+			return projectReport{}, err
+		}
+		if ok {
+			changes := diffSnapshots(previous, snapshot)
+			r.changes = &changes
+		}
+	}
+	return r, nil
+}
+
+// querySprintIssues returns the issues of the sprints in the components and
+// labels of the report, the ones updated since since unless it is zero.
+// Every section of the report leaves out the excluded types.
+func querySprintIssues(sprints []boardSprint, since time.Time) ([]jira.Issue, error) {
+	loc, err := sprintLocation(time.Local)
+	if err != nil {
+		return nil, err
+	}
+	var issues []jira.Issue
+	for _, s := range sprints {
+		query := NewJQL().Sprint(s.sprint.ID).Components(config.Report.Components...).Labels(config.Report.Labels...)
+		if !since.IsZero() {
+			query.UpdatedSince(since.In(loc).Format(jqlDateFormat))
+		}
+		sprintIssues, err := queryJiraIssues(query.String())
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, sprintIssues...)
+	}
+	return excludeIssueTypes(uniqueIssues(issues)), nil
+}
+
+// reportSprints returns the sprints to report for the project: the sprint
+// with the ID, or the active sprint of each of the report boards, or the
+// active sprint of the board of the project.
+func reportSprints(project string, sprintID int) ([]boardSprint, error) {
+	if boardIDs := config.Report.BoardIDs; len(boardIDs) > 0 && sprintID == 0 {
+		return activeBoardSprints(project, boardIDs)
+	}
+
+	boardID, err := getSprintBoardID(project)
+	if err != nil {
+		return nil, err
+	}
+	if sprintID > 0 {
+		sprint, err := getSprintByID(sprintID)
+		if err != nil {
+			return nil, err
+		}
+		return []boardSprint{{boardID: boardID, sprint: sprint}}, nil
+	}
+	sprint, err := getCurrentSprint(project, boardID)
+	if err != nil {
+		return nil, err
+	}
+	return []boardSprint{{boardID: boardID, sprint: *sprint}}, nil
+}
+
+// buildProjectReports builds the reports of the projects in parallel. The
+// reports of the failed projects are left out and their errors returned.
+func buildProjectReports(projects []string, opts reportOptions) ([]projectReport, []error) {
+	results := make([]projectReport, len(projects))
+	ok := make([]bool, len(projects))
+	errs := forEachProject(projects, config.Jira.Workers, func(i int, project string) error {
+		r, err := buildProjectReport(project, opts)
+		if err != nil {
+			return err
+		}
+		results[i], ok[i] = r, true
+		return nil
+	})
+
+	reports := make([]projectReport, 0, len(projects))
+	for i, r := range results {
+		if ok[i] {
+			reports = append(reports, r)
+		}
+	}
+	return reports, errs
+}
 
-	// Close the old sprint.
-	updateSprintState(activeSprint.ID, "closed")
-	// Active the next sprint.
-	updateSprintState(nextSprint.ID, "active")
-	sendToSlack("Current active Sprint %s is closed", activeSprint.Name)
+// title is the heading of the report, like "TIKV: Sprint 2018-10-01 - 2018-10-08".
+func (r projectReport) title() string {
+	title := fmt.Sprintf("%s: Sprint %s", r.project, sprintTitle(r.sprint))
+	if !r.since.IsZero() {
+		title += ", updated since " + r.since.Format(displayDateFormat())
+	}
+	return title
+}
+
+// chartName is the file name of the velocity chart of the report.
+func (r projectReport) chartName() string {
+	return r.project + "-velocity.png"
+}
+
+// renderProjectMarkdown renders the progress and the assignee table,
+// followed by the component and epic tables if there are, the blocked, the
+// stale and the unestimated issues, the assignees over capacity and the
+// changes since the last report.
+func renderProjectMarkdown(r projectReport) string {
+	return r.progress.String() + "\n\n" + renderProjectSections(r)
+}
+
+// renderProjectText is renderProjectMarkdown with the carryover below the
+// progress, for the notifications.
+func renderProjectText(r projectReport) string {
+	return r.progress.String() + "\n" + r.carryover.String() + "\n\n" + renderProjectSections(r)
+}
+
+// renderProjectSections renders the report below the progress, see
+// renderProjectMarkdown.
+func renderProjectSections(r projectReport) string {
+	markdown := renderMarkdown(r.report)
+	if r.components != nil {
+		markdown += "\n" + renderGroupedMarkdown(r.components, "Component")
+	}
+	if r.epics != nil {
+		markdown += "\n" + renderGroupedMarkdown(r.epics, "Epic")
+	}
+	if blocked := renderBlocked(r.blocked); len(blocked) > 0 {
+		markdown += "\n" + blocked
+	}
+	if stale := renderStale(r.stale); len(stale) > 0 {
+		markdown += "\n" + stale
+	}
+	if unestimated := renderUnestimated(r.unestimated); len(unestimated) > 0 {
+		markdown += "\n" + unestimated + "\n"
+	}
+	if overloaded := renderOverloaded(r.report); len(overloaded) > 0 {
+		markdown += "\n" + overloaded + "\n"
+	}
+	if r.changes != nil {
+		markdown += "\n" + r.changes.String()
+	}
+	return markdown
+}
+
+func runSprintReportCommandFunc(cmd *cobra.Command, args []string) {
+	if cmd.Flags().Changed("component") {
+		config.Report.Components = reportComponents
+	}
+	if cmd.Flags().Changed("label") {
+		config.Report.Labels = reportLabels
+	}
+	if reportGroupByComponent {
+		config.Report.GroupByComponent = true
+	}
+	if reportGroupByEpic {
+		config.Report.GroupByEpic = true
+	}
+	if cmd.Flags().Changed("board-id") {
+		config.Report.BoardIDs = reportBoardIDs
+	}
+
+	projects := config.jiraProjects()
+	if reportSprintID > 0 && len(projects) > 1 {
+		// A sprint is on one board, only report the projects it is for.
+		sprint, err := getSprintByID(reportSprintID)
+		perror(err)
+		var matched []string
+		for _, project := range projects {
+			if sprintBelongsToProject(sprint, project) {
+				matched = append(matched, project)
+			}
+		}
+		if len(matched) == 0 {
+			perrmsg(fmt.Sprintf("sprint %s does not belong to any of the projects %s", sprint.Name, strings.Join(projects, ", ")))
+		}
+		projects = matched
+	}
+	opts := reportOptions{sprintID: reportSprintID}
+	var state runState
+	started := nowFunc()
+	if reportSinceLastRun {
+		var err error
+		state, err = loadRunState(stateFilePath())
+		perror(err)
+		opts.since = state.LastRun
+	}
+
+	// The reports of the other projects are still delivered, the failed
+	// ones fail the run so that they are noticed.
+	reports, errs := buildProjectReports(projects, opts)
+	for _, err := range errs {
+		runStatus.record("sprint report", err)
+	}
+	for _, r := range reports {
+		runStatus.record(fmt.Sprintf("[%s] sprint report", r.project), nil)
+		for _, warning := range r.warnings {
+			fmt.Fprintf(os.Stderr, "[%s] warning: %s\n", r.project, warning)
+		}
+	}
+	if len(reports) == 0 {
+		return
+	}
+
+	delivered := deliverSprintReports(reports)
+	if !delivered || config.DryRun {
+		// Not stored, so the same changes are reported again next time.
+		return
+	}
+	if reportSinceLastRun {
+		for _, r := range reports {
+			state.LastRun[r.project] = started
+		}
+		runStatus.record("save run state", saveRunState(stateFilePath(), state))
+	}
+	if len(config.Report.HistoryDir) > 0 {
+		for _, r := range reports {
+			if r.snapshot != nil {
+				runStatus.record(fmt.Sprintf("[%s] save report history", r.project),
+					saveSnapshot(config.Report.HistoryDir, r.project, *r.snapshot))
+			}
+		}
+	}
+}
+
+// deliverSprintReports writes the reports to --out-file, or sends them to
+// every configured channel. It returns false if a delivery failed.
+func deliverSprintReports(reports []projectReport) bool {
+	if len(reportOutput) > 0 || len(reportOutFile) > 0 {
+		err := writeSprintReports(reports)
+		runStatus.record("write sprint reports", err)
+		return err == nil
+	}
+
+	report := SprintReport{Projects: reports}
+	delivered := true
+	for _, n := range configuredNotifiers() {
+		err := n.Send(globalCtx, report)
+		runStatus.record("send sprint report to "+n.Name(), err)
+		delivered = delivered && err == nil
+	}
+	return delivered
 }
 
 func formatPageBeginForHtmlOutput(buf *bytes.Buffer) {
@@ -169,7 +875,7 @@ func genPanelPlaceholder(buf *bytes.Buffer, desc string) {
 	buf.WriteString(fmt.Sprintf(panelTemplate, desc))
 }
 
-func genWeeklyUserPage(buf *bytes.Buffer, m Member, sprint *jira.Sprint) {
+func genWeeklyUserPage(buf *bytes.Buffer, m Member, project string, sprint *jira.Sprint) {
 	formatPageBeginForHtmlOutput(buf)
 
 	formatSectionBeginForHtmlOutput(buf)
@@ -190,7 +896,7 @@ func genWeeklyUserPage(buf *bytes.Buffer, m Member, sprint *jira.Sprint) {
   <ac:parameter ac:name="serverId">%s</ac:parameter>
   <ac:parameter ac:name="jqlQuery">project = %s AND Sprint = %d AND assignee = "%s"</ac:parameter>
 </ac:structured-macro>`
-	buf.WriteString(fmt.Sprintf(template, config.Jira.Server, config.Jira.ServerID, config.Jira.Project, sprint.ID, m.Email))
+	buf.WriteString(fmt.Sprintf(template, config.Jira.Server, config.Jira.ServerID, project, sprint.ID, m.Email))
 	formatSectionEndForHtmlOutput(buf)
 
 	formatPageEndForHtmlOutput(buf)
@@ -250,16 +956,16 @@ func genWeeklyReportIssuesPRs(buf *bytes.Buffer, start, end string) {
 	formatSectionEndForHtmlOutput(buf)
 }
 
-func genWeeklyReportProjects(buf *bytes.Buffer, sprint *jira.Sprint) {
-	epicQuery := `project = %s and "Epic Link" is not EMPTY and Sprint = %d`
-	epicIssues := queryJiraIssues(fmt.Sprintf(epicQuery, config.Jira.Project, sprint.ID))
+func genWeeklyReportProjects(buf *bytes.Buffer, project string, sprint *jira.Sprint) {
+	epicQuery := NewJQL().Project(project).Where(`"Epic Link" is not EMPTY`).Sprint(sprint.ID)
+	epicIssues, err := queryJiraIssues(epicQuery.String())
+	perror(err)
 	// An epic link set.
 	epics := make(map[string]struct{})
 	for _, is := range epicIssues {
-		// The magic name of epic link field.
-		const epicLinkField = "customfield_10100"
-		epicLink := is.Fields.Unknowns[epicLinkField].(string)
-		epics[epicLink] = struct{}{}
+		if epicLink := issueEpic(is); len(epicLink) > 0 {
+			epics[epicLink] = struct{}{}
+		}
 	}
 
 	projects := `
@@ -288,7 +994,7 @@ func genWeeklyReportProjects(buf *bytes.Buffer, sprint *jira.Sprint) {
       <ac:parameter ac:name="jqlQuery">project = %s and "Epic Link" = %s and Sprint = %d</ac:parameter>
     </ac:structured-macro>`
 		epIssues := fmt.Sprintf(epIssuesTemplate,
-			config.Jira.Server, config.Jira.ServerID, config.Jira.Project, ep, sprint.ID)
+			config.Jira.Server, config.Jira.ServerID, project, ep, sprint.ID)
 
 		projectTemplate := `
     <tr>
@@ -305,7 +1011,7 @@ func genWeeklyReportProjects(buf *bytes.Buffer, sprint *jira.Sprint) {
       </td>
     </tr>`
 
-		epic, _, err := jiraClient.Issue.Get(ep, nil)
+		epic, err := getIssue(ep)
 		perror(err)
 		// The magic name of epic name field.
 		const epicNameField = "customfield_10102"
@@ -351,7 +1057,7 @@ func genWeeklyReportToc(buf *bytes.Buffer) {
 	formatSectionEndForHtmlOutput(buf)
 }
 
-func createWeeklyReport(sprint *jira.Sprint, value string) {
+func createWeeklyReport(project string, sprint *jira.Sprint, value string) {
 	title := sprint.Name
 	space := config.Confluence.Space
 	c := getContentByTitle(space, title)
@@ -364,12 +1070,44 @@ func createWeeklyReport(sprint *jira.Sprint, value string) {
 		for _, team := range config.Teams {
 			for _, m := range team.Members {
 				body := bytes.Buffer{}
-				genWeeklyUserPage(&body, m, sprint)
+				genWeeklyUserPage(&body, m, project, sprint)
 				userTitle := fmt.Sprintf("%s - %s", m.Name, title)
 				createContent(space, c.Id, userTitle, body.String())
 			}
 		}
 	}
 
-	sendToSlack("Weekly report for sprint %s is generated: %s%s", title, config.Confluence.Endpoint, c.Links.WebUI)
+	runStatus.record("post weekly report to slack",
+		sendToSlack("Weekly report for sprint %s is generated: %s%s", title, config.Confluence.Endpoint, c.Links.WebUI))
+}
+
+// writeSprintReports writes the reports in the format of --output to
+// --out-file, stdout by default. With several projects the Markdown tables
+// get a heading each. The JSON is a single object for one project and an
+// array for several, see renderJSONReports.
+func writeSprintReports(reports []projectReport) error {
+	var out bytes.Buffer
+	switch reportOutput {
+	case "", "markdown":
+		for _, r := range reports {
+			if len(reports) > 1 {
+				fmt.Fprintf(&out, "## %s: Sprint %s\n\n", r.project, sprintTitle(r.sprint))
+			}
+			out.WriteString(renderProjectMarkdown(r))
+		}
+	case "json":
+		text, err := renderJSONReports(reports)
+		if err != nil {
+			return err
+		}
+		out.WriteString(text)
+	default:
+		return fmt.Errorf("unknown output format %q, expect markdown or json", reportOutput)
+	}
+
+	if len(reportOutFile) == 0 || reportOutFile == "-" {
+		_, err := os.Stdout.Write(out.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(reportOutFile, out.Bytes(), 0644)
 }
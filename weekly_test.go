@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestBuildProjectReportSprintID(t *testing.T) {
+	f, cleanup := newFakeJira(t)
+	defer cleanup()
+	f.boards = []jira.Board{{ID: 1, Name: "TEST board", Type: "scrum"}}
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST 1", State: "closed", StartDate: day(1, 1), EndDate: day(1, 8)})
+	f.addSprint(jira.Sprint{ID: 2, Name: "TEST 2", State: "active", StartDate: day(1, 8), EndDate: day(1, 15)})
+	f.addIssue(1, newReportIssue("TEST-1", "alice", "Done", 1))
+	f.addIssue(2, newReportIssue("TEST-2", "bob", "To Do", 1))
+
+	r, err := buildProjectReport("TEST", reportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.sprint.ID != 2 || r.report["bob"].Issues != 1 {
+		t.Fatalf("expect the active sprint report, got sprint %d %v", r.sprint.ID, r.report)
+	}
+
+	r, err = buildProjectReport("TEST", reportOptions{sprintID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.sprint.ID != 1 || r.report["alice"].Issues != 1 || len(r.report) != 1 {
+		t.Fatalf("expect the report of sprint 1, got sprint %d %v", r.sprint.ID, r.report)
+	}
+}
+
+func TestBuildProjectReportSince(t *testing.T) {
+	f, cleanup := newFakeJira(t)
+	defer cleanup()
+	config.Jira.Timezone = "UTC"
+	f.boards = []jira.Board{{ID: 1, Name: "TEST board", Type: "scrum"}}
+	f.addSprint(jira.Sprint{ID: 2, Name: "TEST 2", State: "active", StartDate: day(1, 8), EndDate: day(1, 15)})
+
+	var queries []string
+	f.search = func(jql string) []jira.Issue {
+		queries = append(queries, jql)
+		updated := []jira.Issue{newReportIssue("TEST-1", "alice", "Done", -1)}
+		if strings.Contains(jql, "updated") {
+			return updated
+		}
+		return append(updated, newReportIssue("TEST-2", "bob", "To Do", -1))
+	}
+
+	since := map[string]time.Time{"TEST": time.Date(2018, time.January, 9, 9, 30, 0, 0, time.UTC)}
+	r, err := buildProjectReport("TEST", reportOptions{since: since})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `sprint = 2 AND updated >= "2018-01-09 09:30"`; queries[0] != expect {
+		t.Fatalf("expect query %s, got %s", expect, queries[0])
+	}
+	// The table has the updated issues, the progress is of the whole sprint.
+	if r.report["alice"].Issues != 1 || len(r.report) != 1 || r.progress.Issues != 2 {
+		t.Fatalf("expect 1 updated issue of 2 in the sprint, got %v and %+v", r.report, r.progress)
+	}
+
+	queries = nil
+	if _, err := buildProjectReport("TEST", reportOptions{since: map[string]time.Time{}}); err != nil {
+		t.Fatal(err)
+	}
+	if expect := `sprint = 2`; queries[0] != expect {
+		t.Fatalf("expect the full sprint without a last run, got %s", queries[0])
+	}
+}
+
+func TestBuildProjectReportBoards(t *testing.T) {
+	f, cleanup := newFakeJira(t)
+	defer cleanup()
+	config.Report.BoardIDs = []int{1, 2}
+	config.Jira.StoryPointField = "customfield_10001"
+	f.addSprint(jira.Sprint{ID: 10, Name: "TEST 10", State: "active", OriginBoardID: 1, StartDate: day(1, 8), EndDate: day(1, 15)})
+	f.addSprint(jira.Sprint{ID: 20, Name: "TEST 20", State: "active", OriginBoardID: 2, StartDate: day(1, 8), EndDate: day(1, 15)})
+	f.addIssue(10, newReportIssue("TEST-1", "alice", "Done", 1))
+	f.addIssue(20, newReportIssue("TEST-2", "bob", "To Do", 2))
+	// Shown on both boards.
+	f.addIssue(10, newReportIssue("TEST-3", "bob", "To Do", 3))
+	f.addIssue(20, newReportIssue("TEST-3", "bob", "To Do", 3))
+
+	r, err := buildProjectReport("TEST", reportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.sprint.ID != 10 || r.report["alice"].Issues != 1 || r.report["bob"].Issues != 2 || r.report["bob"].StoryPoints != 5 {
+		t.Fatalf("expect the union of both sprints, got sprint %d %v", r.sprint.ID, r.report)
+	}
+	if len(r.warnings) != 0 {
+		t.Fatalf("expect aligned sprints, got %v", r.warnings)
+	}
+	if f.countRequests("GET /rest/agile/1.0/board ") != 0 {
+		t.Fatal("expect no board lookup with board IDs")
+	}
+
+	f.sprint(20).EndDate = day(1, 22)
+	if r, err = buildProjectReport("TEST", reportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.warnings) != 1 || !strings.Contains(r.warnings[0], "TEST 20 on board 2") {
+		t.Fatalf("expect a misaligned sprint warning, got %v", r.warnings)
+	}
+}
+
+func TestBuildProjectReportExcludeTypes(t *testing.T) {
+	f, cleanup := newFakeJira(t)
+	defer cleanup()
+	config.Jira.StoryPointField = "customfield_10001"
+	config.Jira.BlockedStatuses = []string{"Waiting"}
+	config.Report.ExcludeTypes = []string{"epic", "Tech Debt"}
+	f.boards = []jira.Board{{ID: 1, Name: "TEST board", Type: "scrum"}}
+	f.addSprint(jira.Sprint{ID: 1, Name: "TEST 1", State: "active", StartDate: day(1, 1), EndDate: day(1, 8)})
+
+	story := newReportIssue("TEST-1", "alice", "Done", 2)
+	story.Fields.Type.Name = "Story"
+	f.addIssue(1, story)
+	for i, name := range []string{"Epic", "Tech Debt"} {
+		issue := newReportIssue(fmt.Sprintf("TEST-%d", i+2), "bob", "In Progress", -1)
+		issue.Fields.Type.Name = name
+		issue.Fields.Status.Name = "Waiting"
+		f.addIssue(1, issue)
+	}
+
+	r, err := buildProjectReport("TEST", reportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.report) != 1 || r.report["alice"].Issues != 1 || r.report["alice"].StoryPoints != 2 {
+		t.Fatalf("expect only the story in the assignee report, got %v", r.report)
+	}
+	if r.progress.Issues != 1 || r.progress.Done != 1 || r.progress.Points != 2 {
+		t.Fatalf("expect only the story in the progress, got %+v", r.progress)
+	}
+	if len(r.blocked) != 0 || len(r.unestimated) != 0 || len(r.stale) != 0 {
+		t.Fatalf("expect no excluded issue listed, got blocked %v unestimated %v stale %v", r.blocked, r.unestimated, r.stale)
+	}
+	if markdown := renderProjectMarkdown(r); strings.Contains(markdown, "bob") || strings.Contains(markdown, "TEST-2") {
+		t.Fatalf("expect the excluded issues nowhere in the report, got\n%s", markdown)
+	}
+}
+
+func TestParseSprintFlagTime(t *testing.T) {
+	config = &Config{}
+	config.Jira.Timezone = "Asia/Shanghai"
+	config.Jira.SprintStartTimeOfDay = "10:00"
+	defer func() { config = nil }()
+
+	start, err := parseSprintFlagTime("start", "2018-10-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "2018-10-01T10:00:00+08:00"; start.Format(dateFormat) != expect {
+		t.Fatalf("expect %s, got %s", expect, start.Format(dateFormat))
+	}
+	end, err := parseSprintFlagTime("end", "2018-10-08T02:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !end.Equal(start.Add(7 * 24 * time.Hour)) {
+		t.Fatalf("expect the time as given, got %s", end)
+	}
+	if _, err = parseSprintFlagTime("start", "next monday"); err == nil || !strings.Contains(err.Error(), "invalid --start") {
+		t.Fatalf("expect an invalid --start, got %v", err)
+	}
+}
+
+func TestCheckSprintDates(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	start := *day(10, 1)
+	for _, c := range []struct {
+		end    time.Time
+		expect string
+	}{
+		{start.Add(7 * 24 * time.Hour), ""},
+		{start, "must be after its start"},
+		{start.Add(-time.Hour), "must be after its start"},
+		{start.Add(30 * 24 * time.Hour), "longer than 28d 0h"},
+	} {
+		err := checkSprintDates(start, c.end)
+		if len(c.expect) == 0 && err != nil || len(c.expect) > 0 && (err == nil || !strings.Contains(err.Error(), c.expect)) {
+			t.Errorf("end %s: expect %q, got %v", c.end, c.expect, err)
+		}
+	}
+
+	config.Jira.SprintDuration.Duration = 42 * 24 * time.Hour
+	if err := checkSprintDates(start, start.Add(42*24*time.Hour)); err != nil {
+		t.Fatalf("expect sprints of sprint-duration to be fine, got %v", err)
+	}
+}
+
+func TestCreatedSprintDates(t *testing.T) {
+	start, end := *day(10, 1), *day(10, 8)
+	sprint := jira.Sprint{Name: "TEST 1", StartDate: day(10, 1), EndDate: day(10, 8)}
+	if s, e, err := createdSprintDates(sprint, start, end); err != nil || !s.Equal(start) || !e.Equal(end) {
+		t.Fatalf("expect the sprint dates, got %s - %s, %v", s, e, err)
+	}
+
+	if _, _, err := createdSprintDates(sprint, start, *day(10, 15)); err == nil || !strings.Contains(err.Error(), "exists from 2018-10-01") {
+		t.Fatalf("expect the existing sprint dates to be reported, got %v", err)
+	}
+
+	if s, e, err := createdSprintDates(jira.Sprint{Name: "TEST 1"}, start, end); err != nil || !s.Equal(start) || !e.Equal(end) {
+		t.Fatalf("expect the requested dates without sprint dates, got %s - %s, %v", s, e, err)
+	}
+}
+
+func TestProjectReportTitleSince(t *testing.T) {
+	config = &Config{}
+	defer func() { config = nil }()
+
+	r := projectReport{project: "TEST", sprint: jira.Sprint{Name: "TEST 1"}, since: *day(10, 3)}
+	if got, expect := r.title(), "TEST: Sprint TEST 1, updated since Oct 3"; got != expect {
+		t.Fatalf("expect %q, got %q", expect, got)
+	}
+}
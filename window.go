@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Window reports cover this many days up to now unless --from is given.
+const defaultWindowDays = 7
+
+// reportWindow is the time span of a window report, from From up to but
+// not including To.
+type reportWindow struct {
+	From time.Time
+	To   time.Time
+}
+
+// String shows the days of the window, like "Oct 1 - Oct 5".
+func (w reportWindow) String() string {
+	return w.From.Format(displayDateFormat()) + " - " + w.To.Add(-time.Second).Format(displayDateFormat())
+}
+
+// newReportWindow returns the window of the days from and to, both
+// included, in the sprint timezone. Without from it is the last days up to
+// now, without to it ends now.
+func newReportWindow(from, to string, days int, now time.Time) (reportWindow, error) {
+	loc, err := sprintLocation(time.Local)
+	if err != nil {
+		return reportWindow{}, err
+	}
+	now = now.In(loc)
+	parseDay := func(flag, value string) (time.Time, error) {
+		day, err := time.ParseInLocation(dayFormat, value, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid --%s %q, expect a day like 2018-10-01", flag, value)
+		}
+		return day, nil
+	}
+
+	w := reportWindow{To: now}
+	if len(to) > 0 {
+		day, err := parseDay("to", to)
+		if err != nil {
+			return reportWindow{}, err
+		}
+		w.To = day.AddDate(0, 0, 1)
+	}
+	if len(from) > 0 {
+		if w.From, err = parseDay("from", from); err != nil {
+			return reportWindow{}, err
+		}
+	} else {
+		if days <= 0 {
+			return reportWindow{}, fmt.Errorf("invalid --days %d, expect a positive number", days)
+		}
+		w.From = w.To.AddDate(0, 0, -days)
+	}
+	if !w.From.Before(w.To) {
+		return reportWindow{}, fmt.Errorf("report window %s - %s is empty", w.From.Format(dateFormat), w.To.Format(dateFormat))
+	}
+	return w, nil
+}
+
+// buildWindowReport aggregates the issues of the project updated in the
+// window by assignee, whichever sprint they are in.
+func buildWindowReport(project string, w reportWindow) (map[string]AssigneeSummary, error) {
+	loc, err := sprintLocation(time.Local)
+	if err != nil {
+		return nil, err
+	}
+	query := NewJQL().Project(project).Components(config.Report.Components...).Labels(config.Report.Labels...).
+		UpdatedSince(w.From.In(loc).Format(jqlDateFormat)).UpdatedBefore(w.To.In(loc).Format(jqlDateFormat))
+	issues, err := queryJiraIssues(query.String())
+	if err != nil {
+		return nil, err
+	}
+	issues = excludeIssueTypes(issues)
+	if err = resolveAssignees(issues); err != nil {
+		return nil, err
+	}
+	return buildAssigneeReport(issues), nil
+}
+
+// renderWindowReport renders the report of the window under a header
+// telling the window.
+func renderWindowReport(project string, w reportWindow, report map[string]AssigneeSummary) string {
+	return fmt.Sprintf("## %s: issues updated %s\n\n%s", project, w, renderMarkdown(report))
+}
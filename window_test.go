@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestNewReportWindow(t *testing.T) {
+	config = &Config{}
+	config.Jira.Timezone = "UTC"
+	defer func() { config = nil }()
+
+	now := time.Date(2018, time.October, 10, 15, 0, 0, 0, time.UTC)
+	cases := []struct {
+		from, to string
+		days     int
+		expect   reportWindow
+		err      string
+	}{
+		{"", "", 7, reportWindow{From: now.AddDate(0, 0, -7), To: now}, ""},
+		{"2018-10-01", "2018-10-05", 0, reportWindow{
+			From: time.Date(2018, time.October, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2018, time.October, 6, 0, 0, 0, 0, time.UTC),
+		}, ""},
+		{"", "2018-10-05", 5, reportWindow{
+			From: time.Date(2018, time.October, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2018, time.October, 6, 0, 0, 0, 0, time.UTC),
+		}, ""},
+		{"2018-10-08", "", 0, reportWindow{From: time.Date(2018, time.October, 8, 0, 0, 0, 0, time.UTC), To: now}, ""},
+		{"monday", "", 0, reportWindow{}, "invalid --from"},
+		{"", "", 0, reportWindow{}, "invalid --days"},
+		{"2018-10-05", "2018-10-01", 0, reportWindow{}, "is empty"},
+	}
+	for _, c := range cases {
+		w, err := newReportWindow(c.from, c.to, c.days, now)
+		if len(c.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%q - %q: expect %q, got %v", c.from, c.to, c.err, err)
+			}
+			continue
+		}
+		if err != nil || !w.From.Equal(c.expect.From) || !w.To.Equal(c.expect.To) {
+			t.Errorf("%q - %q: expect %v, got %v %v", c.from, c.to, c.expect, w, err)
+		}
+	}
+}
+
+func TestBuildWindowReport(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+	config.Jira.Timezone = "UTC"
+
+	var queries []string
+	f.search = func(jql string) []jira.Issue {
+		queries = append(queries, jql)
+		return []jira.Issue{
+			newReportIssue("TEST-1", "alice", "Done", -1),
+			newReportIssue("TEST-2", "alice", "In Progress", -1),
+		}
+	}
+
+	w := reportWindow{
+		From: time.Date(2018, time.October, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2018, time.October, 6, 0, 0, 0, 0, time.UTC),
+	}
+	report, err := buildWindowReport("TEST", w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `project = "TEST" AND updated >= "2018-10-01 00:00" AND updated < "2018-10-06 00:00"`
+	if len(queries) != 1 || queries[0] != expect {
+		t.Fatalf("expect query %s, got %v", expect, queries)
+	}
+	if report["alice"].Issues != 2 {
+		t.Fatalf("expect the issues of alice, got %v", report)
+	}
+	if out := renderWindowReport("TEST", w, report); !strings.HasPrefix(out, "## TEST: issues updated Oct 1 - Oct 5\n") {
+		t.Fatalf("expect the window in the header, got %q", out)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// The report logged-time modes: the hours logged in the worklogs are shown
+// next to the story points, or instead of them.
+const (
+	loggedTimeAlongside = "alongside"
+	loggedTimeInstead   = "instead"
+)
+
+func reportsLoggedTime() bool {
+	return len(config.Report.LoggedTime) > 0
+}
+
+func reportsStoryPoints() bool {
+	return config.Report.LoggedTime != loggedTimeInstead
+}
+
+// loggedHours sums the time logged on the issue, see resolveWorklogs.
+func loggedHours(issue jira.Issue) float64 {
+	if issue.Fields == nil || issue.Fields.Worklog == nil {
+		return 0
+	}
+	seconds := 0
+	for _, record := range issue.Fields.Worklog.Worklogs {
+		seconds += record.TimeSpentSeconds
+	}
+	return float64(seconds) / 3600
+}
+
+// formatHours formats the hours to a tenth, like "7.5".
+func formatHours(hours float64) string {
+	return strconv.FormatFloat(hours, 'f', 1, 64)
+}
+
+// resolveWorklogs fetches the worklogs of the issues which the search
+// returned without them, or with only the first page of them: Jira embeds
+// at most 20. The issues are fetched on jira workers goroutines.
+func resolveWorklogs(issues []jira.Issue) error {
+	var keys []string
+	var fetched []*jira.IssueFields
+	for i := range issues {
+		fields := issues[i].Fields
+		if fields == nil {
+			continue
+		}
+		if w := fields.Worklog; w != nil && len(w.Worklogs) >= w.Total {
+			continue
+		}
+		keys = append(keys, issues[i].Key)
+		fetched = append(fetched, fields)
+	}
+
+	errs := forEachProject(keys, config.Jira.Workers, func(i int, key string) error {
+		worklog, err := getIssueWorklog(key)
+		if err != nil {
+			return err
+		}
+		fetched[i].Worklog = worklog
+		return nil
+	})
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// keepSprintWorklogs drops the worklogs of the issues which were not
+// started within the sprint, up to now for a sprint still running, so that
+// time logged on a carried over issue counts in the sprint it was spent.
+// A sprint without a start date keeps them all.
+func keepSprintWorklogs(issues []jira.Issue, sprint jira.Sprint, now time.Time) {
+	if sprint.StartDate == nil {
+		return
+	}
+	start, end := *sprint.StartDate, now
+	if finished := sprintFinishedAt(sprint); finished != nil && finished.Before(now) {
+		end = *finished
+	}
+
+	for _, issue := range issues {
+		if issue.Fields == nil || issue.Fields.Worklog == nil {
+			continue
+		}
+		w := issue.Fields.Worklog
+		var kept []jira.WorklogRecord
+		for _, record := range w.Worklogs {
+			if record.Started == nil {
+				continue
+			}
+			started := time.Time(*record.Started)
+			if !started.Before(start) && started.Before(end) {
+				kept = append(kept, record)
+			}
+		}
+		w.Worklogs, w.Total = kept, len(kept)
+	}
+}
+
+// getIssueWorklog returns the whole worklog of the issue.
+func getIssueWorklog(key string) (*jira.Worklog, error) {
+	req, err := newJiraRequest(globalCtx, "GET", "rest/api/2/issue/"+url.PathEscape(key)+"/worklog", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	worklog := new(jira.Worklog)
+	if _, err = doWithRetry(req, worklog); err != nil {
+		return nil, err
+	}
+	return worklog, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func newWorklog(seconds ...int) *jira.Worklog {
+	w := &jira.Worklog{Total: len(seconds)}
+	for _, s := range seconds {
+		w.Worklogs = append(w.Worklogs, jira.WorklogRecord{TimeSpentSeconds: s})
+	}
+	return w
+}
+
+func TestResolveWorklogs(t *testing.T) {
+	f, closer := newFakeJira(t)
+	defer closer()
+
+	complete := newReportIssue("TEST-1", "alice", "Done", 1)
+	complete.Fields.Worklog = newWorklog(3600, 1800)
+	// The search only embeds the first page of the worklog.
+	truncated := newReportIssue("TEST-2", "alice", "Done", 1)
+	truncated.Fields.Worklog = newWorklog(3600)
+	truncated.Fields.Worklog.Total = 3
+	missing := newReportIssue("TEST-3", "bob", "To Do", 1)
+
+	full := newReportIssue("TEST-2", "alice", "Done", 1)
+	full.Fields.Worklog = newWorklog(3600, 3600, 3600)
+	f.addIssue(1, full)
+	withoutWorklog := newReportIssue("TEST-3", "bob", "To Do", 1)
+	f.addIssue(1, withoutWorklog)
+
+	issues := []jira.Issue{complete, truncated, missing}
+	if err := resolveWorklogs(issues); err != nil {
+		t.Fatal(err)
+	}
+	for i, expect := range []float64{1.5, 3, 0} {
+		if hours := loggedHours(issues[i]); hours != expect {
+			t.Errorf("%s: expect %v hours, got %v", issues[i].Key, expect, hours)
+		}
+	}
+	if n := f.countRequests("GET /rest/api/2/issue/"); n != 2 {
+		t.Fatalf("expect the worklogs of the 2 incomplete issues fetched, got %d", n)
+	}
+}
+
+func TestKeepSprintWorklogs(t *testing.T) {
+	started := func(d *time.Time) *jira.Time {
+		s := jira.Time(*d)
+		return &s
+	}
+	issue := newReportIssue("TEST-1", "alice", "In Progress", 1)
+	issue.Fields.Worklog = newWorklog(3600, 1800, 900, 600)
+	// Logged in the sprint before, in this sprint, after the given now and
+	// without a start.
+	issue.Fields.Worklog.Worklogs[0].Started = started(day(9, 30))
+	issue.Fields.Worklog.Worklogs[1].Started = started(day(10, 2))
+	issue.Fields.Worklog.Worklogs[2].Started = started(day(10, 6))
+
+	sprint := jira.Sprint{Name: "TEST 1", StartDate: day(10, 1), EndDate: day(10, 8)}
+	keepSprintWorklogs([]jira.Issue{issue}, sprint, *day(10, 5))
+	if hours := loggedHours(issue); hours != 0.5 {
+		t.Fatalf("expect only the 0.5 hours logged in the sprint, got %v", hours)
+	}
+
+	all := newReportIssue("TEST-2", "alice", "In Progress", 1)
+	all.Fields.Worklog = newWorklog(3600)
+	keepSprintWorklogs([]jira.Issue{all}, jira.Sprint{Name: "TEST 2"}, *day(10, 5))
+	if hours := loggedHours(all); hours != 1 {
+		t.Fatalf("expect the worklogs kept without sprint dates, got %v", hours)
+	}
+}
+
+func TestRenderMarkdownLoggedTime(t *testing.T) {
+	config = &Config{}
+	config.Jira.StoryPointField = "customfield_10001"
+	defer func() { config = nil }()
+
+	alice := newReportIssue("TEST-1", "alice", "Done", 3)
+	alice.Fields.Worklog = newWorklog(5400)
+	bob := newReportIssue("TEST-2", "bob", "To Do", 2)
+	bob.Fields.Worklog = newWorklog(3600, 3600)
+	report := buildAssigneeReport([]jira.Issue{alice, bob})
+
+	config.Report.LoggedTime = loggedTimeAlongside
+	out := renderMarkdown(report)
+	for _, expect := range []string{"| Issues | Story Points | Hours |", "| alice | 0 | 1 | 1 | 3 | 1.5 |", "| **5** | **3.5** |"} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("expect %q in\n%s", expect, out)
+		}
+	}
+
+	config.Report.LoggedTime = loggedTimeInstead
+	out = renderMarkdown(report)
+	if !strings.Contains(out, "| Issues | Hours |") || strings.Contains(out, "Story Points") || !strings.Contains(out, "| bob | 1 | 0 | 1 | 2.0 |") {
+		t.Errorf("expect the hours instead of the story points in\n%s", out)
+	}
+}